@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
@@ -9,7 +10,13 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/canyouhearthemusic/todo-list/internal/calendar"
+	"github.com/canyouhearthemusic/todo-list/internal/config"
+	"github.com/canyouhearthemusic/todo-list/internal/i18n"
+	"github.com/canyouhearthemusic/todo-list/internal/idempotency"
+	"github.com/canyouhearthemusic/todo-list/internal/repositories"
 	"github.com/canyouhearthemusic/todo-list/internal/routes"
+	"github.com/canyouhearthemusic/todo-list/internal/services"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -21,9 +28,68 @@ func main() {
 		port = "8080"
 	}
 
+	configPath := os.Getenv("CONFIG_PATH")
+	if configPath == "" {
+		configPath = "config/config.yaml"
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("Could not load config: %v", err)
+	}
+
+	store, closeRepo, err := newTaskRepo(cfg.Storage)
+	if err != nil {
+		log.Fatalf("Could not open %s storage: %v", cfg.Storage.Driver, err)
+	}
+
+	repo, err := repositories.NewIndexedTaskRepo(context.Background(), store)
+	if err != nil {
+		log.Fatalf("Could not build the search index: %v", err)
+	}
+
+	messages, err := i18n.Load(cfg.I18n.DefaultLanguage)
+	if err != nil {
+		log.Fatalf("Could not load message catalogs: %v", err)
+	}
+
+	cal, err := newCalendar(cfg.Calendar)
+	if err != nil {
+		log.Fatalf("Could not load calendar: %v", err)
+	}
+
+	flow, err := cfg.Workflow.Build()
+	if err != nil {
+		log.Fatalf("Invalid workflow: %v", err)
+	}
+
+	service := services.New(repo,
+		services.WithCalendar(cal),
+		services.WithMessages(messages),
+		services.WithWorkflow(flow),
+		services.WithMaxDepth(cfg.Subtasks.MaxDepth),
+		services.WithParentAutoComplete(cfg.Subtasks.AutoCompleteParent),
+		services.WithBlockerPolicy(cfg.Blockers.Unfinished),
+		services.WithNonWorkingDays(cfg.Recurrence.NonWorkingDays),
+	)
+
+	migrated, err := service.MigrateStatuses(context.Background(), cfg.Workflow.Migrate)
+	if err != nil {
+		log.Fatalf("Could not start with the configured workflow: %v", err)
+	}
+	if migrated > 0 {
+		log.Infof("Moved %d tasks to statuses of the configured workflow", migrated)
+	}
+
 	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: routes.New(),
+		Addr: ":" + port,
+		Handler: routes.New(routes.Deps{
+			Tasks:       service,
+			Projects:    services.NewProjectService(repo),
+			Holidays:    services.NewHolidayService(cal),
+			Messages:    messages,
+			Idempotency: idempotency.NewStore(cfg.Idempotency.TTL),
+		}),
 	}
 
 	wg.Add(1)
@@ -54,5 +120,67 @@ func main() {
 
 	wg.Wait()
 
+	if err := closeRepo(); err != nil {
+		log.Errorf("Storage close error: %s", err)
+	}
+
 	log.Infoln("Shutdown complete.")
 }
+
+func newTaskRepo(cfg config.Storage) (repositories.TaskRepo, func() error, error) {
+	switch cfg.Driver {
+	case config.DriverSQLite:
+		repo, err := repositories.NewSQLiteTaskRepo(cfg.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Infof("Using SQLite storage at %s", cfg.Path)
+		return repo, repo.Close, nil
+	case config.DriverJournal:
+		repo, err := repositories.NewJournaledTaskRepo(repositories.JournalOptions{
+			Dir:           cfg.Journal.Dir,
+			Fsync:         repositories.FsyncPolicy(cfg.Journal.Fsync),
+			FsyncInterval: cfg.Journal.FsyncInterval,
+			CompactEvery:  cfg.Journal.CompactEvery,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Infof("Using journaled in-memory storage at %s (fsync: %s)", cfg.Journal.Dir, cfg.Journal.Fsync)
+		return repo, repo.Close, nil
+	default:
+		log.Warnln("Using in-memory storage; tasks will not survive a restart")
+		return repositories.NewSyncMapTaskRepo(), func() error { return nil }, nil
+	}
+}
+
+func newCalendar(cfg config.Calendar) (*calendar.Calendar, error) {
+	weekendDays, err := calendar.ParseWeekdays(cfg.WeekendDays)
+	if err != nil {
+		return nil, fmt.Errorf("weekendDays: %w", err)
+	}
+
+	opts := []calendar.Option{
+		calendar.WithWeekendDays(weekendDays...),
+		calendar.WithDefaultRegion(cfg.Region),
+	}
+
+	for _, src := range cfg.Holidays {
+		holidays, err := calendar.LoadFile(src.Path, src.Region)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("Loaded %d holidays from %s", len(holidays), src.Path)
+		opts = append(opts, calendar.WithHolidays(holidays...))
+	}
+
+	cal := calendar.New(opts...)
+
+	if cfg.CustomHolidaysPath != "" {
+		if err := cal.LoadCustom(cfg.CustomHolidaysPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return cal, nil
+}
@@ -24,24 +24,28 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/tasks": {
+        "/api/holidays": {
             "get": {
-                "description": "Get all tasks by status",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "List public and custom holidays of a region",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/problem+json"
                 ],
                 "tags": [
-                    "tasks"
+                    "holidays"
                 ],
-                "summary": "Get all tasks",
+                "summary": "List holidays",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Status Filter",
-                        "name": "status",
+                        "description": "Region, e.g. KZ (defaults to the configured region)",
+                        "name": "region",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Expand annual holidays into this year",
+                        "name": "year",
                         "in": "query"
                     }
                 ],
@@ -51,38 +55,39 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Task"
+                                "$ref": "#/definitions/calendar.PublicHoliday"
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers.Problem"
                         }
                     }
                 }
             },
             "post": {
-                "description": "Create a new task",
+                "description": "Add a custom holiday; without a region it applies everywhere",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/problem+json"
                 ],
                 "tags": [
-                    "tasks"
+                    "holidays"
                 ],
-                "summary": "Create a new task",
+                "summary": "Add a company holiday",
                 "parameters": [
                     {
-                        "description": "Task",
-                        "name": "task",
+                        "description": "Holiday",
+                        "name": "holiday",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.TaskRequest"
+                            "$ref": "#/definitions/calendar.HolidayRequest"
                         }
                     }
                 ],
@@ -90,131 +95,346 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Task"
+                            "$ref": "#/definitions/calendar.PublicHoliday"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/holidays/{id}": {
+            "delete": {
+                "description": "Delete a custom holiday by ID; holidays loaded from files cannot be deleted",
+                "produces": [
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "holidays"
+                ],
+                "summary": "Delete a company holiday",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Holiday ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
                     }
                 }
             }
         },
-        "/api/tasks/{id}": {
+        "/api/projects": {
             "get": {
-                "description": "Get a task by ID",
+                "description": "List every project, oldest first. Tasks created without a projectId go to the \"default\" project.",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "List projects",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Project"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a project; names are unique",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/problem+json"
                 ],
                 "tags": [
-                    "tasks"
+                    "projects"
                 ],
-                "summary": "Get task by ID",
+                "summary": "Create a project",
+                "parameters": [
+                    {
+                        "description": "Project",
+                        "name": "project",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ProjectRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Project"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the project"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/projects/{id}": {
+            "get": {
+                "description": "Get a project by ID; list its tasks with GET /api/tasks?project={id}",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Get project by ID",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Task ID",
+                        "description": "Project ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Answer 304 if the project still has one of these ETags",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Task"
+                            "$ref": "#/definitions/models.Project"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the project"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
                         }
                     }
                 }
             },
             "put": {
-                "description": "Update a task by ID",
+                "description": "Rename a project or change its description",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/problem+json"
                 ],
                 "tags": [
-                    "tasks"
+                    "projects"
                 ],
-                "summary": "Update a task",
+                "summary": "Update a project",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Task ID",
+                        "description": "Project ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Task",
-                        "name": "task",
+                        "description": "Project",
+                        "name": "project",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.TaskRequest"
+                            "$ref": "#/definitions/models.ProjectRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Update only if the project has one of these ETags",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Update only if the project has none of these ETags",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Project"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the project"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers.Problem"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
                         }
                     }
                 }
             },
             "delete": {
-                "description": "Delete a task by ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Delete a project without tasks. The default project cannot be deleted.",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/problem+json"
                 ],
                 "tags": [
-                    "tasks"
+                    "projects"
                 ],
-                "summary": "Delete a task",
+                "summary": "Delete a project",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Task ID",
+                        "description": "Project ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delete only if the project has one of these ETags",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delete only if the project has none of these ETags",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -227,64 +447,2407 @@ const docTemplate = `{
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict: the project has tasks or is the default one",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/tasks/{id}/done": {
+        "/api/tags": {
+            "get": {
+                "description": "List the tags in use with the number of tasks carrying each, most used first",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "List tags",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Tag"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tags/{name}": {
             "put": {
-                "description": "Update a task status by ID",
+                "description": "Rename a tag on every task carrying it. The new name must not be in use yet; merge the tags instead.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
                 "tags": [
-                    "tasks"
+                    "tags"
                 ],
-                "summary": "Mark task as done",
+                "summary": "Rename a tag",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Task ID",
-                        "name": "id",
+                        "description": "Tag",
+                        "name": "name",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "New name",
+                        "name": "tag",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TagRenameRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Tag"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers.Problem"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict: the new name is in use",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
+        },
+        "/api/tags/{name}/merge": {
+            "post": {
+                "description": "Replace a tag with another on every task carrying it; tasks carrying both keep one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Merge a tag into another",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tag to merge away",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tag to merge into",
+                        "name": "tag",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TagMergeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Tag"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tasks": {
+            "get": {
+                "description": "Get all tasks by status",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get all tasks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only tasks of this project",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated workflow statuses, e.g. in_progress,review (defaults to the open ones unless filter is given)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter expression over id, projectId, title, description, activeAt, createdAt, status, priority and dueAt, e.g. activeAt\u003e=2024-05-01 AND title~report AND priority IN (high,urgent)",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Holiday region, e.g. KZ (defaults to the configured region)",
+                        "name": "region",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": true,
+                        "description": "Prefix weekend and holiday display titles with a label",
+                        "name": "decorate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Language of display labels (ru, en, kk)",
+                        "name": "Accept-Language",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only subtasks of this task",
+                        "name": "parent",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only tasks past their dueAt that are not closed",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated tags; only tasks with at least one of them",
+                        "name": "tagsAny",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated tags; only tasks with all of them",
+                        "name": "tagsAll",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated tags; only tasks with none of them",
+                        "name": "tagsNone",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "html"
+                        ],
+                        "type": "string",
+                        "description": "Add descriptionHtml, the description rendered as sanitized HTML",
+                        "name": "render",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "activeAt",
+                        "description": "Comma-separated sort fields (activeAt, title, createdAt, status, priority, dueAt); prefix with - for descending",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Page size (max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of tasks to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from X-Next-Cursor; cannot be combined with offset",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.TaskView"
+                            }
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "RFC 8288 first/prev/next/last links"
+                            },
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "Cursor of the next page, absent on the last page"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Number of tasks matching the filters"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found: no such project",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new task, in the default project unless projectId names another. A parentId makes it a subtask of that task, within the configured nesting depth. A recurrence rule (RFC 5545 RRULE of dates, e.g. FREQ=WEEKLY;BYDAY=MO) makes it repeat, starting at activeAt. Retries sent with the same Idempotency-Key and body get the first response back instead of creating the task again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Create a new task",
+                "parameters": [
+                    {
+                        "description": "Task",
+                        "name": "task",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TaskRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique key of this request, e.g. a UUID, kept for the configured TTL",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the task"
+                            },
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true if this is the stored response of an earlier request"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found: no such project",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict (also while a request with the same Idempotency-Key is in progress)",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity (also when the Idempotency-Key was used with a different body)",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tasks/order": {
+            "get": {
+                "description": "List open tasks so that every task comes after the open tasks it is blocked by. Among tasks that could come next, the most urgent goes first, then the soonest due, then the earliest active. With project, blockers in other projects are not waited for.",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "blockers"
+                ],
+                "summary": "Get the order to work on tasks in",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only tasks of this project",
+                        "name": "project",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Task"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found: no such project",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict: the blockers of the named tasks form a cycle",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tasks/search": {
+            "get": {
+                "description": "Full-text search over task titles and descriptions. Every word of the query must match a word in the task, exactly or as its prefix; results are ranked by relevance, with matches in the title counting more.",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Search tasks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search query, e.g. годов отч",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Maximum number of results (max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.TaskSearchResult"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented: the storage has no full-text index",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tasks/{id}": {
+            "get": {
+                "description": "Get a task by ID along with its progress over subtasks and checklist items. With render=html the task also carries descriptionHtml, its Markdown description rendered as sanitized HTML. The ETag versions the task itself, so changes to its subtasks do not change it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get task by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "html"
+                        ],
+                        "type": "string",
+                        "description": "Also render the description",
+                        "name": "render",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Answer 304 if the task still has one of these ETags",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TaskDetail"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the task"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update a task by ID. Without projectId the task stays in its project, and without parentId under its parent; its checklist and blockers are left as they are. Keeping the recurrence rule keeps the task in its series, a new rule starts a series at activeAt, and leaving it out stops the task from repeating.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Update a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Task",
+                        "name": "task",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TaskRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Update only if the task has one of these ETags",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Update only if the task has none of these ETags",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the task"
+                            },
+                            "Warning": {
+                                "type": "string",
+                                "description": "Set when the task was marked done while blocked by unfinished tasks"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a task by ID; a task with subtasks cannot be deleted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Delete a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delete only if the task has one of these ETags",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delete only if the task has none of these ETags",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict: the task has subtasks",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Change some of a task's title, activeAt, projectId, parentId, status, description, tags, priority, dueAt and recurrence with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902), e.g. {\"status\":\"active\"} or [{\"op\":\"replace\",\"path\":\"/title\",\"value\":\"New title\"}]. The patched task is validated like a PUT.",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Patch a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Merge patch over these fields, or a JSON Patch array",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TaskPatch"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Patch only if the task has one of these ETags",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Patch only if the task has none of these ETags",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the task"
+                            },
+                            "Warning": {
+                                "type": "string",
+                                "description": "Set when the task was marked done while blocked by unfinished tasks"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict, including a failed JSON Patch test",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tasks/{id}/blockers": {
+            "get": {
+                "description": "List the tasks a task is blocked by, in the order they were added",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "blockers"
+                ],
+                "summary": "Get the blockers of a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Task"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the task"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tasks/{id}/blockers/{blockerId}": {
+            "put": {
+                "description": "Make a task blocked by another one, which then has to be finished first. Blockers that would make a cycle are refused.",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "blockers"
+                ],
+                "summary": "Block a task by another",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the blocking task",
+                        "name": "blockerId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Change only if the task has one of these ETags",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Change only if the task has none of these ETags",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the task"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed (also when the task changed meanwhile)",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity: no such blocking task, or a cycle",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Stop a task from being blocked by another one",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "blockers"
+                ],
+                "summary": "Unblock a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the blocking task",
+                        "name": "blockerId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Change only if the task has one of these ETags",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Change only if the task has none of these ETags",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the task"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found: no such task, or not blocked by that one",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed (also when the task changed meanwhile)",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tasks/{id}/checklist": {
+            "get": {
+                "description": "List the checklist items of a task in their order",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "checklist"
+                ],
+                "summary": "Get the checklist of a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ChecklistItem"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the task"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Append an item to the checklist of a task; a checklist holds at most 100 items",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "checklist"
+                ],
+                "summary": "Add a checklist item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Checklist item",
+                        "name": "item",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ChecklistItemRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Change only if the task has one of these ETags",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Change only if the task has none of these ETags",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChecklistItem"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the task"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed (also when the task changed meanwhile)",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tasks/{id}/checklist/{itemId}": {
+            "put": {
+                "description": "Replace the text of a checklist item and whether it is done",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "checklist"
+                ],
+                "summary": "Update a checklist item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Checklist item ID",
+                        "name": "itemId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Checklist item",
+                        "name": "item",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ChecklistItemRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Change only if the task has one of these ETags",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Change only if the task has none of these ETags",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChecklistItem"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the task"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found: no such task or item",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed (also when the task changed meanwhile)",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove an item from the checklist of a task",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "checklist"
+                ],
+                "summary": "Delete a checklist item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Checklist item ID",
+                        "name": "itemId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Change only if the task has one of these ETags",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Change only if the task has none of these ETags",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the task"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found: no such task or item",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed (also when the task changed meanwhile)",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tasks/{id}/done": {
+            "put": {
+                "description": "Move a task to the done status, if the workflow allows it from its current one. A recurring task gets its activeAt appended to its title and stops repeating, and its next occurrence is created under its title, moved off weekends and holidays as configured. If parent auto-completion is configured, the parent follows once all of its subtasks are closed.",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Mark task as done",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Mark done only if the task has one of these ETags",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Mark done only if the task has none of these ETags",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, recorded as statusChangedBy",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the task"
+                            },
+                            "Warning": {
+                                "type": "string",
+                                "description": "Set when the task was marked done while blocked by unfinished tasks"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict: transition not allowed, or unfinished blockers",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tasks/{id}/project": {
+            "put": {
+                "description": "Move a task to another project, where its title must not be taken yet",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Move a task to another project",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Target project",
+                        "name": "project",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MoveRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Move only if the task has one of these ETags",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Move only if the task has none of these ETags",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the task"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found: no such task or project",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict: the title is taken in the target project",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tasks/{id}/reopen": {
+            "put": {
+                "description": "Move a task back to the initial status, if the workflow allows it from its current one",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Reopen a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reopen only if the task has one of these ETags",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reopen only if the task has none of these ETags",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, recorded as statusChangedBy",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the task"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict: transition not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tasks/{id}/status": {
+            "put": {
+                "description": "Move a task to another status, if the workflow allows the transition from its current one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Change the status of a task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New status",
+                        "name": "status",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.StatusRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Change only if the task has one of these ETags",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Change only if the task has none of these ETags",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, recorded as statusChangedBy",
+                        "name": "X-Actor",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the task"
+                            },
+                            "Warning": {
+                                "type": "string",
+                                "description": "Set when the task was marked done while blocked by unfinished tasks"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict: transition not allowed, or unfinished blockers",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tasks:batch": {
+            "post": {
+                "description": "Apply up to 100 operations in order. Each does what the single-task request would, against the tasks as the operations before it left them, and gets its own result with the status that request would have got. With atomic set, either every operation is applied or none is: the failing one reports its error and the rest 424 Failed Dependency.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Create, update, complete and delete tasks in one request",
+                "parameters": [
+                    {
+                        "description": "Operations",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "calendar.HolidayRequest": {
+            "type": "object",
+            "properties": {
+                "annual": {
+                    "type": "boolean"
+                },
+                "date": {
+                    "type": "string",
+                    "example": "2024-12-31"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Company day off"
+                },
+                "region": {
+                    "type": "string",
+                    "example": "KZ"
+                }
+            }
+        },
+        "calendar.PublicHoliday": {
+            "type": "object",
+            "properties": {
+                "annual": {
+                    "type": "boolean"
+                },
+                "custom": {
+                    "description": "Custom holidays are managed through the API; the rest come from files.",
+                    "type": "boolean"
+                },
+                "date": {
+                    "type": "string",
+                    "example": "2024-03-22"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Nauryz"
+                },
+                "region": {
+                    "type": "string",
+                    "example": "KZ"
+                }
+            }
+        },
+        "handlers.BatchItemResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/handlers.Problem"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 201
+                },
+                "task": {
+                    "$ref": "#/definitions/models.Task"
+                }
+            }
+        },
+        "handlers.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.BatchItemResult"
+                    }
+                }
+            }
+        },
+        "handlers.Problem": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "example": "task not found"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/api/tasks/0b7c7f4e-4a7e-4bd1-9d0f-3f6f1f0c2d11"
+                },
+                "requestId": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Not Found"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "/problems/not-found"
+                }
+            }
+        },
+        "models.BatchOperation": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "op": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "done",
+                        "delete"
+                    ]
+                },
+                "task": {
+                    "$ref": "#/definitions/models.Task"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.BatchRequest": {
+            "type": "object",
+            "properties": {
+                "atomic": {
+                    "description": "Atomic applies either every operation or none of them.",
+                    "type": "boolean"
+                },
+                "operations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BatchOperation"
+                    }
+                }
+            }
+        },
+        "models.ChecklistItem": {
+            "type": "object",
+            "properties": {
+                "done": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "3f1c2a"
+                },
+                "text": {
+                    "type": "string",
+                    "example": "Book the room"
+                }
+            }
+        },
+        "models.ChecklistItemRequest": {
+            "type": "object",
+            "properties": {
+                "done": {
+                    "type": "boolean"
+                },
+                "text": {
+                    "type": "string",
+                    "example": "Book the room"
+                }
+            }
+        },
+        "models.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MoveRequest": {
+            "type": "object",
+            "properties": {
+                "projectId": {
+                    "type": "string",
+                    "example": "default"
+                }
+            }
+        },
+        "models.Progress": {
+            "type": "object",
+            "properties": {
+                "checklistDone": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "checklistTotal": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "percent": {
+                    "description": "Percent is the share of subtasks and items done, rounded down.",
+                    "type": "integer",
+                    "example": 66
+                },
+                "subtasksDone": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "subtasksTotal": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "models.Project": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "description": "CreatedAt is set by the repository when the project is first stored.",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Tasks not filed anywhere else"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "default"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Inbox"
+                },
+                "version": {
+                    "description": "Version starts at 1 and is incremented by the repository on every\nchange; it is the project's ETag.",
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.ProjectRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Tasks not filed anywhere else"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Inbox"
+                }
+            }
+        },
+        "models.Recurrence": {
+            "type": "object",
+            "properties": {
+                "occurrence": {
+                    "description": "Occurrence numbers this task in the series, starting at 1.",
+                    "type": "integer",
+                    "example": 1
+                },
+                "rule": {
+                    "description": "Rule is an RRULE of dates, e.g. FREQ=WEEKLY;BYDAY=MO,WE; times of day\nare not supported.",
+                    "type": "string",
+                    "example": "FREQ=WEEKLY;BYDAY=MO"
+                },
+                "scheduled": {
+                    "description": "Scheduled is the date the rule gives this occurrence, which differs\nfrom activeAt if that was moved off a weekend or holiday.",
+                    "type": "string",
+                    "example": "2024-05-06"
+                },
+                "start": {
+                    "description": "Start is the activeAt of the first occurrence, the DTSTART of the\nrule.",
+                    "type": "string",
+                    "example": "2024-05-06"
+                }
+            }
+        },
+        "models.RecurrenceRequest": {
+            "type": "object",
+            "properties": {
+                "rule": {
+                    "type": "string",
+                    "example": "FREQ=WEEKLY;BYDAY=MO"
+                }
+            }
+        },
+        "models.StatusRequest": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string",
+                    "example": "done"
+                }
+            }
+        },
+        "models.Tag": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "name": {
+                    "type": "string",
+                    "example": "backend"
+                }
+            }
+        },
+        "models.TagMergeRequest": {
+            "type": "object",
+            "properties": {
+                "into": {
+                    "type": "string",
+                    "example": "backend"
+                }
+            }
+        },
+        "models.TagRenameRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "customer-y"
+                }
+            }
+        },
         "models.Task": {
             "type": "object",
             "properties": {
                 "activeAt": {
                     "type": "string"
                 },
+                "blockedBy": {
+                    "description": "BlockedBy names the tasks to finish before this one. Like the\nchecklist, it is changed through its own endpoints only.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "checklist": {
+                    "description": "Checklist is changed through its own endpoints only; updates of the\ntask keep it as it is.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChecklistItem"
+                    }
+                },
+                "createdAt": {
+                    "description": "CreatedAt is set by the repository when the task is first stored.",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Description holds details in Markdown, stored as given.",
+                    "type": "string",
+                    "example": "Collect the **Q2** numbers first"
+                },
+                "dueAt": {
+                    "description": "DueAt is an optional RFC 3339 timestamp, kept with the offset it was\ngiven in.",
+                    "type": "string",
+                    "example": "2024-05-10T18:00:00+05:00"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "parentId": {
+                    "description": "ParentID names the task this one is a subtask of, if any.",
+                    "type": "string"
+                },
+                "priority": {
+                    "description": "Priority is low, normal, high or urgent; the repository stores\nnormal if it is empty.",
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "normal",
+                        "high",
+                        "urgent"
+                    ],
+                    "example": "normal"
+                },
+                "projectId": {
+                    "description": "ProjectID names the project of the task, DefaultProjectID if empty.",
+                    "type": "string",
+                    "example": "default"
+                },
+                "recurrence": {
+                    "description": "Recurrence makes the task repeat: marking it done creates the next\noccurrence.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Recurrence"
+                        }
+                    ]
+                },
+                "status": {
+                    "type": "string"
+                },
+                "statusChangedAt": {
+                    "description": "StatusChangedAt and StatusChangedBy record the last status change.\nThe repository sets the time; the caller names who made the change.",
+                    "type": "string"
+                },
+                "statusChangedBy": {
+                    "type": "string",
+                    "example": "alice"
+                },
+                "tags": {
+                    "description": "Tags label the task, normalized by NormalizeTags.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "backend",
+                        "customer-x"
+                    ]
+                },
+                "title": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version starts at 1 and is incremented by the repository on every\nchange; it is the task's ETag.",
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.TaskDetail": {
+            "type": "object",
+            "properties": {
+                "activeAt": {
+                    "type": "string"
+                },
+                "blockedBy": {
+                    "description": "BlockedBy names the tasks to finish before this one. Like the\nchecklist, it is changed through its own endpoints only.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "checklist": {
+                    "description": "Checklist is changed through its own endpoints only; updates of the\ntask keep it as it is.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChecklistItem"
+                    }
+                },
+                "createdAt": {
+                    "description": "CreatedAt is set by the repository when the task is first stored.",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Description holds details in Markdown, stored as given.",
+                    "type": "string",
+                    "example": "Collect the **Q2** numbers first"
+                },
+                "descriptionHtml": {
+                    "type": "string"
+                },
+                "dueAt": {
+                    "description": "DueAt is an optional RFC 3339 timestamp, kept with the offset it was\ngiven in.",
+                    "type": "string",
+                    "example": "2024-05-10T18:00:00+05:00"
+                },
                 "id": {
                     "type": "string"
                 },
+                "parentId": {
+                    "description": "ParentID names the task this one is a subtask of, if any.",
+                    "type": "string"
+                },
+                "priority": {
+                    "description": "Priority is low, normal, high or urgent; the repository stores\nnormal if it is empty.",
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "normal",
+                        "high",
+                        "urgent"
+                    ],
+                    "example": "normal"
+                },
+                "progress": {
+                    "$ref": "#/definitions/models.Progress"
+                },
+                "projectId": {
+                    "description": "ProjectID names the project of the task, DefaultProjectID if empty.",
+                    "type": "string",
+                    "example": "default"
+                },
+                "recurrence": {
+                    "description": "Recurrence makes the task repeat: marking it done creates the next\noccurrence.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Recurrence"
+                        }
+                    ]
+                },
                 "status": {
                     "type": "string"
                 },
+                "statusChangedAt": {
+                    "description": "StatusChangedAt and StatusChangedBy record the last status change.\nThe repository sets the time; the caller names who made the change.",
+                    "type": "string"
+                },
+                "statusChangedBy": {
+                    "type": "string",
+                    "example": "alice"
+                },
+                "tags": {
+                    "description": "Tags label the task, normalized by NormalizeTags.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "backend",
+                        "customer-x"
+                    ]
+                },
+                "title": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version starts at 1 and is incremented by the repository on every\nchange; it is the task's ETag.",
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.TaskPatch": {
+            "type": "object",
+            "properties": {
+                "activeAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "dueAt": {
+                    "type": "string",
+                    "example": "2024-05-10T18:00:00+05:00"
+                },
+                "parentId": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "string",
+                    "example": "high"
+                },
+                "projectId": {
+                    "type": "string",
+                    "example": "default"
+                },
+                "recurrence": {
+                    "description": "Recurrence is null for tasks that do not repeat.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.RecurrenceRequest"
+                        }
+                    ]
+                },
+                "status": {
+                    "type": "string",
+                    "example": "done"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "title": {
                     "type": "string"
                 }
@@ -296,8 +2859,303 @@ const docTemplate = `{
                 "activeAt": {
                     "type": "string"
                 },
+                "blockedBy": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "checklist": {
+                    "description": "Checklist and BlockedBy are only taken on create.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChecklistItemRequest"
+                    }
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Collect the **Q2** numbers first"
+                },
+                "dueAt": {
+                    "type": "string",
+                    "example": "2024-05-10T18:00:00+05:00"
+                },
+                "parentId": {
+                    "description": "ParentID makes the task a subtask; on update, an empty one keeps the\ncurrent parent.",
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "normal",
+                        "high",
+                        "urgent"
+                    ],
+                    "example": "normal"
+                },
+                "projectId": {
+                    "description": "ProjectID defaults to the default project on create and to the\ncurrent project on update.",
+                    "type": "string",
+                    "example": "default"
+                },
+                "recurrence": {
+                    "description": "Recurrence makes the task repeat; on update, leaving it out stops it\nfrom repeating.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.RecurrenceRequest"
+                        }
+                    ]
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "backend",
+                        "customer-x"
+                    ]
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TaskSearchResult": {
+            "type": "object",
+            "properties": {
+                "activeAt": {
+                    "type": "string"
+                },
+                "blockedBy": {
+                    "description": "BlockedBy names the tasks to finish before this one. Like the\nchecklist, it is changed through its own endpoints only.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "checklist": {
+                    "description": "Checklist is changed through its own endpoints only; updates of the\ntask keep it as it is.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChecklistItem"
+                    }
+                },
+                "createdAt": {
+                    "description": "CreatedAt is set by the repository when the task is first stored.",
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Description holds details in Markdown, stored as given.",
+                    "type": "string",
+                    "example": "Collect the **Q2** numbers first"
+                },
+                "dueAt": {
+                    "description": "DueAt is an optional RFC 3339 timestamp, kept with the offset it was\ngiven in.",
+                    "type": "string",
+                    "example": "2024-05-10T18:00:00+05:00"
+                },
+                "highlights": {
+                    "description": "Highlights maps each matching field to an HTML-escaped snippet with\nthe matched words wrapped in \u003cmark\u003e tags.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "parentId": {
+                    "description": "ParentID names the task this one is a subtask of, if any.",
+                    "type": "string"
+                },
+                "priority": {
+                    "description": "Priority is low, normal, high or urgent; the repository stores\nnormal if it is empty.",
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "normal",
+                        "high",
+                        "urgent"
+                    ],
+                    "example": "normal"
+                },
+                "projectId": {
+                    "description": "ProjectID names the project of the task, DefaultProjectID if empty.",
+                    "type": "string",
+                    "example": "default"
+                },
+                "recurrence": {
+                    "description": "Recurrence makes the task repeat: marking it done creates the next\noccurrence.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Recurrence"
+                        }
+                    ]
+                },
+                "score": {
+                    "description": "Score ranks results; higher is more relevant.",
+                    "type": "number",
+                    "example": 1.42
+                },
+                "status": {
+                    "type": "string"
+                },
+                "statusChangedAt": {
+                    "description": "StatusChangedAt and StatusChangedBy record the last status change.\nThe repository sets the time; the caller names who made the change.",
+                    "type": "string"
+                },
+                "statusChangedBy": {
+                    "type": "string",
+                    "example": "alice"
+                },
+                "tags": {
+                    "description": "Tags label the task, normalized by NormalizeTags.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "backend",
+                        "customer-x"
+                    ]
+                },
+                "title": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version starts at 1 and is incremented by the repository on every\nchange; it is the task's ETag.",
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.TaskView": {
+            "type": "object",
+            "properties": {
+                "activeAt": {
+                    "type": "string"
+                },
+                "blockedBy": {
+                    "description": "BlockedBy names the tasks to finish before this one. Like the\nchecklist, it is changed through its own endpoints only.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "checklist": {
+                    "description": "Checklist is changed through its own endpoints only; updates of the\ntask keep it as it is.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChecklistItem"
+                    }
+                },
+                "createdAt": {
+                    "description": "CreatedAt is set by the repository when the task is first stored.",
+                    "type": "string"
+                },
+                "dayType": {
+                    "description": "DayType is workday, weekend or holiday.",
+                    "type": "string",
+                    "example": "workday"
+                },
+                "description": {
+                    "description": "Description holds details in Markdown, stored as given.",
+                    "type": "string",
+                    "example": "Collect the **Q2** numbers first"
+                },
+                "descriptionHtml": {
+                    "description": "DescriptionHTML is the description rendered as sanitized HTML, if\nrequested with render=html.",
+                    "type": "string"
+                },
+                "displayTitle": {
+                    "type": "string"
+                },
+                "dueAt": {
+                    "description": "DueAt is an optional RFC 3339 timestamp, kept with the offset it was\ngiven in.",
+                    "type": "string",
+                    "example": "2024-05-10T18:00:00+05:00"
+                },
+                "holiday": {
+                    "type": "string",
+                    "example": "Nauryz"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "isWeekend": {
+                    "type": "boolean"
+                },
+                "overdue": {
+                    "description": "Overdue is set for tasks past their dueAt that are not closed yet.",
+                    "type": "boolean"
+                },
+                "parentId": {
+                    "description": "ParentID names the task this one is a subtask of, if any.",
+                    "type": "string"
+                },
+                "priority": {
+                    "description": "Priority is low, normal, high or urgent; the repository stores\nnormal if it is empty.",
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "normal",
+                        "high",
+                        "urgent"
+                    ],
+                    "example": "normal"
+                },
+                "progress": {
+                    "description": "Progress is set for tasks with subtasks or a checklist.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Progress"
+                        }
+                    ]
+                },
+                "projectId": {
+                    "description": "ProjectID names the project of the task, DefaultProjectID if empty.",
+                    "type": "string",
+                    "example": "default"
+                },
+                "recurrence": {
+                    "description": "Recurrence makes the task repeat: marking it done creates the next\noccurrence.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Recurrence"
+                        }
+                    ]
+                },
+                "status": {
+                    "type": "string"
+                },
+                "statusChangedAt": {
+                    "description": "StatusChangedAt and StatusChangedBy record the last status change.\nThe repository sets the time; the caller names who made the change.",
+                    "type": "string"
+                },
+                "statusChangedBy": {
+                    "type": "string",
+                    "example": "alice"
+                },
+                "tags": {
+                    "description": "Tags label the task, normalized by NormalizeTags.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "backend",
+                        "customer-x"
+                    ]
+                },
                 "title": {
                     "type": "string"
+                },
+                "version": {
+                    "description": "Version starts at 1 and is incremented by the repository on every\nchange; it is the task's ETag.",
+                    "type": "integer",
+                    "example": 1
                 }
             }
         }
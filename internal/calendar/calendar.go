@@ -0,0 +1,240 @@
+// Package calendar classifies dates for task listings as workdays,
+// weekends or public holidays.
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/google/uuid"
+)
+
+type DayType string
+
+const (
+	Workday DayType = "workday"
+	Weekend DayType = "weekend"
+	Holiday DayType = "holiday"
+)
+
+var ErrHolidayNotFound = fmt.Errorf("holiday %w", models.ErrNotFound)
+
+// Day is the classification of a single date. Holidays take precedence
+// over weekends.
+type Day struct {
+	Type      DayType
+	IsWeekend bool
+	Holiday   *PublicHoliday
+}
+
+type Calendar struct {
+	weekend       map[time.Weekday]bool
+	defaultRegion string
+
+	mu       sync.RWMutex
+	holidays []*PublicHoliday
+	// customPath, if set, is where custom holidays are persisted.
+	customPath string
+}
+
+type Option func(*Calendar)
+
+// WithWeekendDays replaces the default Saturday/Sunday weekend.
+func WithWeekendDays(days ...time.Weekday) Option {
+	return func(c *Calendar) {
+		c.weekend = make(map[time.Weekday]bool)
+		for _, day := range days {
+			c.weekend[day] = true
+		}
+	}
+}
+
+// WithDefaultRegion sets the region used when a lookup names none.
+func WithDefaultRegion(region string) Option {
+	return func(c *Calendar) {
+		c.defaultRegion = normalizeRegion(region)
+	}
+}
+
+// WithHolidays adds public holidays, e.g. from LoadFile.
+func WithHolidays(holidays ...*PublicHoliday) Option {
+	return func(c *Calendar) {
+		c.holidays = append(c.holidays, holidays...)
+	}
+}
+
+func New(opts ...Option) *Calendar {
+	c := &Calendar{
+		weekend: map[time.Weekday]bool{
+			time.Saturday: true,
+			time.Sunday:   true,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *Calendar) DefaultRegion() string {
+	return c.defaultRegion
+}
+
+func (c *Calendar) IsWeekend(date time.Time) bool {
+	return c.weekend[date.Weekday()]
+}
+
+// Classify returns the day type of date in region ("" for the default).
+func (c *Calendar) Classify(date time.Time, region string) Day {
+	day := Day{Type: Workday, IsWeekend: c.IsWeekend(date)}
+	if day.IsWeekend {
+		day.Type = Weekend
+	}
+
+	if h := c.holidayOn(date, c.region(region)); h != nil {
+		day.Type = Holiday
+		day.Holiday = h
+	}
+
+	return day
+}
+
+// Holidays lists the holidays observed in region during year, sorted by
+// date. Annual holidays are expanded into year. A zero year lists every
+// holiday as stored; an empty region lists all regions.
+func (c *Calendar) Holidays(region string, year int) []*PublicHoliday {
+	region = normalizeRegion(region)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var out []*PublicHoliday
+	for _, h := range c.holidays {
+		if region != "" && !h.observedIn(region) {
+			continue
+		}
+
+		if year != 0 {
+			date, ok := h.dateIn(year)
+			if !ok {
+				continue
+			}
+
+			copied := *h
+			copied.Date = date.Format(models.DateLayout)
+			out = append(out, &copied)
+			continue
+		}
+
+		copied := *h
+		out = append(out, &copied)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Date < out[j].Date
+	})
+
+	return out
+}
+
+// AddCustom registers a company holiday and persists the custom set.
+func (c *Calendar) AddCustom(h *PublicHoliday) error {
+	h.ID = uuid.New().String()
+	h.Region = normalizeRegion(h.Region)
+	h.Custom = true
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.holidays = append(c.holidays, h)
+
+	if err := c.saveCustom(); err != nil {
+		c.holidays = c.holidays[:len(c.holidays)-1]
+		return err
+	}
+
+	return nil
+}
+
+// DeleteCustom removes a company holiday. Holidays loaded from files
+// cannot be deleted.
+func (c *Calendar) DeleteCustom(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, h := range c.holidays {
+		if h.Custom && h.ID == id {
+			previous := c.holidays
+			c.holidays = append(c.holidays[:i:i], c.holidays[i+1:]...)
+
+			if err := c.saveCustom(); err != nil {
+				c.holidays = previous
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	return ErrHolidayNotFound
+}
+
+func (c *Calendar) holidayOn(date time.Time, region string) *PublicHoliday {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, h := range c.holidays {
+		if h.observedIn(region) && h.falls(date) {
+			copied := *h
+			return &copied
+		}
+	}
+
+	return nil
+}
+
+func (c *Calendar) region(region string) string {
+	if region == "" {
+		return c.defaultRegion
+	}
+
+	return normalizeRegion(region)
+}
+
+// ParseWeekdays parses English weekday names ("saturday", "Sun", ...).
+func ParseWeekdays(names []string) ([]time.Weekday, error) {
+	days := make([]time.Weekday, 0, len(names))
+
+	for _, name := range names {
+		day, ok := parseWeekday(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday %q", name)
+		}
+		days = append(days, day)
+	}
+
+	return days, nil
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || name == full[:3] {
+			return day, true
+		}
+	}
+
+	return 0, false
+}
+
+func normalizeRegion(region string) string {
+	return strings.ToUpper(strings.TrimSpace(region))
+}
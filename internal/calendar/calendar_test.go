@@ -0,0 +1,126 @@
+package calendar_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/calendar"
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+)
+
+const ics = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20240321\r\n" +
+	"DTEND;VALUE=DATE:20240324\r\n" +
+	"SUMMARY:Nau\r\n" +
+	" ryz\r\n" +
+	"RRULE:FREQ=YEARLY\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20240610\r\n" +
+	"SUMMARY:Kurban Ait\\, day 1\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func date(s string) time.Time {
+	d, _ := time.Parse(models.DateLayout, s)
+	return d
+}
+
+func TestParseICS(t *testing.T) {
+	holidays, err := calendar.ParseICS(strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, h := range holidays {
+		got = append(got, h.Date+" "+h.Name)
+	}
+
+	want := []string{
+		"2024-03-21 Nauryz",
+		"2024-03-22 Nauryz",
+		"2024-03-23 Nauryz",
+		"2024-06-10 Kurban Ait, day 1",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("ParseICS = %q, want %q", got, want)
+	}
+
+	if !holidays[0].Annual || holidays[3].Annual {
+		t.Error("only events with RRULE:FREQ=YEARLY should be annual")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	holidays, err := calendar.ParseICS(strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range holidays {
+		h.Region = "KZ"
+	}
+
+	cal := calendar.New(
+		calendar.WithDefaultRegion("kz"),
+		calendar.WithHolidays(holidays...),
+	)
+
+	tests := []struct {
+		date   string
+		region string
+		want   calendar.DayType
+	}{
+		{"2024-05-06", "", calendar.Workday},
+		{"2024-05-04", "", calendar.Weekend},
+		{"2025-03-21", "", calendar.Holiday}, // annual
+		{"2024-03-23", "", calendar.Holiday}, // Saturday and holiday
+		{"2024-06-10", "", calendar.Holiday},
+		{"2025-06-10", "", calendar.Workday}, // not annual
+		{"2024-03-21", "RU", calendar.Workday},
+	}
+
+	for _, tt := range tests {
+		if got := cal.Classify(date(tt.date), tt.region).Type; got != tt.want {
+			t.Errorf("Classify(%s, %q) = %s, want %s", tt.date, tt.region, got, tt.want)
+		}
+	}
+
+	if day := cal.Classify(date("2024-03-23"), ""); !day.IsWeekend {
+		t.Error("a holiday on a Saturday should still report IsWeekend")
+	}
+}
+
+func TestCustomHolidaysPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "holidays.yaml")
+
+	cal := calendar.New()
+	if err := cal.LoadCustom(path); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &calendar.PublicHoliday{Date: "2024-12-31", Name: "Company day off"}
+	if err := cal.AddCustom(h); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := calendar.New()
+	if err := reloaded.LoadCustom(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if day := reloaded.Classify(date("2024-12-31"), "ANY"); day.Type != calendar.Holiday {
+		t.Fatalf("custom holiday not reloaded, got %s", day.Type)
+	}
+
+	if err := reloaded.DeleteCustom(h.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloaded.DeleteCustom(h.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("second DeleteCustom: got %v, want ErrNotFound", err)
+	}
+}
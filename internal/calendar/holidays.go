@@ -0,0 +1,304 @@
+package calendar
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"gopkg.in/yaml.v2"
+)
+
+// PublicHoliday is a non-working date. An empty Region means the holiday
+// is observed everywhere; Annual holidays recur on the same month and day.
+type PublicHoliday struct {
+	ID     string `json:"id,omitempty" yaml:"id,omitempty"`
+	Date   string `json:"date" yaml:"date" example:"2024-03-22"`
+	Name   string `json:"name" yaml:"name" example:"Nauryz"`
+	Region string `json:"region,omitempty" yaml:"region,omitempty" example:"KZ"`
+	Annual bool   `json:"annual" yaml:"annual,omitempty"`
+	// Custom holidays are managed through the API; the rest come from files.
+	Custom bool `json:"custom" yaml:"-"`
+}
+
+type HolidayRequest struct {
+	Date   string `json:"date" example:"2024-12-31"`
+	Name   string `json:"name" example:"Company day off"`
+	Region string `json:"region,omitempty" example:"KZ"`
+	Annual bool   `json:"annual"`
+}
+
+func (h *PublicHoliday) Validate() error {
+	verr := &models.ValidationError{}
+
+	if strings.TrimSpace(h.Name) == "" {
+		verr.Add("name", "name is required")
+	}
+
+	if _, err := time.Parse(models.DateLayout, h.Date); err != nil {
+		verr.Add("date", "invalid date format")
+	}
+
+	return verr.Err()
+}
+
+func (h *PublicHoliday) observedIn(region string) bool {
+	return h.Region == "" || h.Region == region
+}
+
+func (h *PublicHoliday) falls(date time.Time) bool {
+	if h.Annual {
+		return len(h.Date) == len(models.DateLayout) && h.Date[4:] == date.Format(models.DateLayout)[4:]
+	}
+
+	return h.Date == date.Format(models.DateLayout)
+}
+
+func (h *PublicHoliday) dateIn(year int) (time.Time, bool) {
+	date, err := time.Parse(models.DateLayout, h.Date)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	if h.Annual {
+		moved := time.Date(year, date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		// Feb 29 does not exist every year.
+		return moved, moved.Month() == date.Month()
+	}
+
+	return date, date.Year() == year
+}
+
+type holidayFile struct {
+	Region   string           `yaml:"region,omitempty"`
+	Holidays []*PublicHoliday `yaml:"holidays"`
+}
+
+// LoadFile reads holidays from a YAML (.yaml, .yml) or iCalendar (.ics)
+// file. region, when set, overrides any region named in the file.
+func LoadFile(path, region string) ([]*PublicHoliday, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var holidays []*PublicHoliday
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		holidays, err = ParseYAML(f)
+	case ".ics", ".ical":
+		holidays, err = ParseICS(f)
+	default:
+		return nil, fmt.Errorf("%s: unsupported holiday file format", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, h := range holidays {
+		if region != "" {
+			h.Region = region
+		}
+		h.Region = normalizeRegion(h.Region)
+	}
+
+	return holidays, nil
+}
+
+// ParseYAML reads a file of the form
+//
+//	region: KZ
+//	holidays:
+//	  - date: 2024-03-21
+//	    name: Nauryz
+//	    annual: true
+//
+// Entries without a region inherit the file's.
+func ParseYAML(r io.Reader) ([]*PublicHoliday, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var file holidayFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	for i, h := range file.Holidays {
+		if err := h.Validate(); err != nil {
+			return nil, fmt.Errorf("holiday %d: %w", i+1, err)
+		}
+		if h.Region == "" {
+			h.Region = file.Region
+		}
+	}
+
+	return file.Holidays, nil
+}
+
+// ParseICS reads the all-day VEVENTs of an iCalendar file. Multi-day events
+// yield one holiday per day (DTEND is exclusive, per RFC 5545), and events
+// with RRULE:FREQ=YEARLY become annual holidays. Other recurrence rules are
+// not expanded.
+func ParseICS(r io.Reader) ([]*PublicHoliday, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		holidays []*PublicHoliday
+		event    map[string]string
+	)
+
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			event = make(map[string]string)
+		case line == "END:VEVENT":
+			expanded, err := icsEvent(event)
+			if err != nil {
+				return nil, err
+			}
+			holidays = append(holidays, expanded...)
+			event = nil
+		case event != nil:
+			name, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			// Drop parameters such as DTSTART;VALUE=DATE.
+			name, _, _ = strings.Cut(name, ";")
+			event[strings.ToUpper(name)] = value
+		}
+	}
+
+	return holidays, nil
+}
+
+func icsEvent(event map[string]string) ([]*PublicHoliday, error) {
+	start, err := parseICSDate(event["DTSTART"])
+	if err != nil {
+		return nil, fmt.Errorf("event %q: DTSTART: %w", event["SUMMARY"], err)
+	}
+
+	end := start.AddDate(0, 0, 1)
+	if raw, ok := event["DTEND"]; ok {
+		if end, err = parseICSDate(raw); err != nil {
+			return nil, fmt.Errorf("event %q: DTEND: %w", event["SUMMARY"], err)
+		}
+	}
+
+	annual := strings.Contains(strings.ToUpper(event["RRULE"]), "FREQ=YEARLY")
+	name := unescapeICS(event["SUMMARY"])
+
+	var holidays []*PublicHoliday
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		holidays = append(holidays, &PublicHoliday{
+			Date:   day.Format(models.DateLayout),
+			Name:   name,
+			Annual: annual,
+		})
+	}
+
+	return holidays, nil
+}
+
+func parseICSDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+
+	return time.Parse("20060102", value[:8])
+}
+
+// unfoldICS joins continuation lines (RFC 5545 section 3.1).
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+func unescapeICS(s string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
+
+// LoadCustom reads the custom holidays persisted at path, which is then
+// used to persist later changes. A missing file is an empty set.
+func (c *Calendar) LoadCustom(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.customPath = path
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	holidays, err := ParseYAML(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, h := range holidays {
+		h.Custom = true
+		h.Region = normalizeRegion(h.Region)
+	}
+	c.holidays = append(c.holidays, holidays...)
+
+	return nil
+}
+
+// saveCustom must be called with c.mu held.
+func (c *Calendar) saveCustom() error {
+	if c.customPath == "" {
+		return nil
+	}
+
+	var file holidayFile
+	for _, h := range c.holidays {
+		if h.Custom {
+			file.Holidays = append(file.Holidays, h)
+		}
+	}
+
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.customPath), 0o755); err != nil {
+		return err
+	}
+
+	tmp := c.customPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.customPath)
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/idempotency"
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/canyouhearthemusic/todo-list/internal/repositories"
+	"github.com/canyouhearthemusic/todo-list/internal/services"
+	"github.com/canyouhearthemusic/todo-list/internal/workflow"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	DriverMemory  = "memory"
+	DriverSQLite  = "sqlite"
+	DriverJournal = "journal"
+)
+
+type Config struct {
+	Storage     Storage     `yaml:"storage"`
+	I18n        I18n        `yaml:"i18n"`
+	Calendar    Calendar    `yaml:"calendar"`
+	Idempotency Idempotency `yaml:"idempotency"`
+	Workflow    Workflow    `yaml:"workflow"`
+	Subtasks    Subtasks    `yaml:"subtasks"`
+	Blockers    Blockers    `yaml:"blockers"`
+	Recurrence  Recurrence  `yaml:"recurrence"`
+}
+
+// Recurrence configures recurring tasks.
+type Recurrence struct {
+	// NonWorkingDays is where the next occurrence goes if its rule puts it
+	// on a weekend or holiday: next or previous moves it to that workday,
+	// skip drops it for the next one on a workday, keep leaves it there.
+	NonWorkingDays string `yaml:"nonWorkingDays"`
+}
+
+// Blockers configures task dependencies.
+type Blockers struct {
+	// Unfinished is what marking a task done does while tasks it is blocked
+	// by are not finished: block refuses it, warn allows it with a warning.
+	Unfinished string `yaml:"unfinished"`
+}
+
+// Subtasks configures task nesting.
+type Subtasks struct {
+	// MaxDepth is how deep tasks nest, top-level tasks being at depth 1.
+	MaxDepth int `yaml:"maxDepth"`
+	// AutoCompleteParent marks a parent done once all of its subtasks are
+	// closed.
+	AutoCompleteParent bool `yaml:"autoCompleteParent"`
+}
+
+// Workflow defines the task statuses and the transitions allowed between
+// them. Left empty, tasks are active or done and move freely between the two.
+type Workflow struct {
+	// Initial is the status of new and reopened tasks.
+	Initial string `yaml:"initial"`
+	// Done is the status PUT /api/tasks/{id}/done moves a task to.
+	Done string `yaml:"done"`
+	// Closed lists statuses, besides Done, that task listings leave out
+	// unless asked for.
+	Closed []string `yaml:"closed"`
+	// Transitions maps every status to the statuses it may move to.
+	Transitions map[string][]string `yaml:"transitions"`
+	// Migrate maps statuses stored tasks may still have from an earlier
+	// workflow to statuses of this one; tasks are moved on startup. Any
+	// other status unknown to the workflow stops the service from starting.
+	Migrate map[string]string `yaml:"migrate"`
+}
+
+// Build returns the workflow w describes: workflow.Default if it has no
+// transitions, which must then leave the statuses unset too.
+func (w Workflow) Build() (*workflow.Workflow, error) {
+	var flow *workflow.Workflow
+	if len(w.Transitions) == 0 {
+		if w.Initial != "" || w.Done != "" || len(w.Closed) > 0 {
+			return nil, errors.New("initial, done and closed need transitions")
+		}
+		flow = workflow.Default()
+	} else {
+		var err error
+		flow, err = workflow.New(workflow.Definition{
+			Initial:     w.Initial,
+			Done:        w.Done,
+			Closed:      w.Closed,
+			Transitions: w.Transitions,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for from, to := range w.Migrate {
+		if flow.Valid(from) {
+			return nil, fmt.Errorf("cannot migrate %q, a status of the workflow", from)
+		}
+		if !flow.Valid(to) {
+			return nil, fmt.Errorf("cannot migrate %q to unknown status %q", from, to)
+		}
+	}
+
+	return flow, nil
+}
+
+// Idempotency configures Idempotency-Key handling: responses are replayed
+// for retries within TTL of the first request.
+type Idempotency struct {
+	TTL time.Duration `yaml:"ttl"`
+}
+
+type I18n struct {
+	// DefaultLanguage is used when Accept-Language names nothing supported.
+	DefaultLanguage string `yaml:"defaultLanguage"`
+}
+
+type Calendar struct {
+	// WeekendDays are English weekday names, e.g. [saturday, sunday].
+	WeekendDays []string `yaml:"weekendDays"`
+	// Region is the holiday region used when a request names none.
+	Region   string          `yaml:"region"`
+	Holidays []HolidaySource `yaml:"holidays"`
+	// CustomHolidaysPath is where holidays added through the API are kept.
+	CustomHolidaysPath string `yaml:"customHolidaysPath"`
+}
+
+// HolidaySource is a YAML or iCalendar file of public holidays. Region,
+// if set, overrides the region named in the file.
+type HolidaySource struct {
+	Region string `yaml:"region"`
+	Path   string `yaml:"path"`
+}
+
+type Storage struct {
+	Driver  string  `yaml:"driver"`
+	Path    string  `yaml:"path"`
+	Journal Journal `yaml:"journal"`
+}
+
+// Journal configures the journal driver: an in-memory store persisted
+// through an append-only log under Dir, compacted into a snapshot every
+// CompactEvery records.
+type Journal struct {
+	Dir           string        `yaml:"dir"`
+	Fsync         string        `yaml:"fsync"`
+	FsyncInterval time.Duration `yaml:"fsyncInterval"`
+	CompactEvery  int           `yaml:"compactEvery"`
+}
+
+// Load reads the YAML config at path. A missing file is not an error:
+// the defaults (in-memory storage) are returned instead.
+func Load(path string) (*Config, error) {
+	cfg := &Config{
+		Storage: Storage{
+			Driver: DriverMemory,
+			Path:   "data/tasks.db",
+			Journal: Journal{
+				Dir:           "data/journal",
+				Fsync:         string(repositories.FsyncAlways),
+				FsyncInterval: time.Second,
+				CompactEvery:  1000,
+			},
+		},
+		I18n: I18n{
+			DefaultLanguage: "ru",
+		},
+		Calendar: Calendar{
+			WeekendDays: []string{"saturday", "sunday"},
+		},
+		Idempotency: Idempotency{
+			TTL: idempotency.DefaultTTL,
+		},
+		Subtasks: Subtasks{
+			MaxDepth: models.DefaultMaxTaskDepth,
+		},
+		Blockers: Blockers{
+			Unfinished: services.BlockersBlock,
+		},
+		Recurrence: Recurrence{
+			NonWorkingDays: services.NonWorkingNext,
+		},
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) Validate() error {
+	switch c.Storage.Driver {
+	case DriverMemory:
+	case DriverSQLite:
+		if c.Storage.Path == "" {
+			return errors.New("storage.path is required for the sqlite driver")
+		}
+	case DriverJournal:
+		if c.Storage.Journal.Dir == "" {
+			return errors.New("storage.journal.dir is required for the journal driver")
+		}
+		switch repositories.FsyncPolicy(c.Storage.Journal.Fsync) {
+		case repositories.FsyncAlways, repositories.FsyncInterval, repositories.FsyncNever:
+		default:
+			return fmt.Errorf("unknown storage.journal.fsync policy %q", c.Storage.Journal.Fsync)
+		}
+	default:
+		return fmt.Errorf("unknown storage driver %q", c.Storage.Driver)
+	}
+
+	if c.Idempotency.TTL <= 0 {
+		return errors.New("idempotency.ttl must be positive")
+	}
+
+	if _, err := c.Workflow.Build(); err != nil {
+		return fmt.Errorf("workflow: %w", err)
+	}
+
+	if c.Subtasks.MaxDepth < 1 {
+		return errors.New("subtasks.maxDepth must be at least 1")
+	}
+
+	switch c.Blockers.Unfinished {
+	case services.BlockersBlock, services.BlockersWarn:
+	default:
+		return fmt.Errorf("unknown blockers.unfinished policy %q", c.Blockers.Unfinished)
+	}
+
+	switch c.Recurrence.NonWorkingDays {
+	case services.NonWorkingKeep, services.NonWorkingNext, services.NonWorkingPrevious, services.NonWorkingSkip:
+	default:
+		return fmt.Errorf("unknown recurrence.nonWorkingDays policy %q", c.Recurrence.NonWorkingDays)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Match evaluates e against a record whose field values value returns, in
+// the form the schema describes. A nil Expr matches everything.
+//
+// value reports false for a field the record has no value for. As with
+// NULL in SQL, a comparison on it is neither true nor false, and neither is
+// its negation, so it matches only where the rest of an OR decides.
+func Match(e Expr, value func(field string) (string, bool)) bool {
+	return eval(e, value) == isTrue
+}
+
+// truth is a value of SQL's three-valued logic.
+type truth int
+
+const (
+	isFalse truth = iota
+	isUnknown
+	isTrue
+)
+
+func eval(e Expr, value func(field string) (string, bool)) truth {
+	switch e := e.(type) {
+	case nil:
+		return isTrue
+	case *And:
+		return min(eval(e.Left, value), eval(e.Right, value))
+	case *Or:
+		return max(eval(e.Left, value), eval(e.Right, value))
+	case *Not:
+		return isTrue - eval(e.Expr, value)
+	case *Comparison:
+		v, ok := value(e.Field)
+		switch {
+		case !ok:
+			return isUnknown
+		case e.match(v):
+			return isTrue
+		}
+		return isFalse
+	}
+
+	panic(fmt.Sprintf("filter: unexpected node %T", e))
+}
+
+func (c *Comparison) match(v string) bool {
+	switch c.Op {
+	case Eq:
+		return v == c.Values[0]
+	case Ne:
+		return v != c.Values[0]
+	case Lt:
+		return v < c.Values[0]
+	case Le:
+		return v <= c.Values[0]
+	case Gt:
+		return v > c.Values[0]
+	case Ge:
+		return v >= c.Values[0]
+	case Contains:
+		return strings.Contains(strings.ToLower(v), strings.ToLower(c.Values[0]))
+	case In, NotIn:
+		found := false
+		for _, want := range c.Values {
+			if v == want {
+				found = true
+				break
+			}
+		}
+		return found == (c.Op == In)
+	}
+
+	return false
+}
@@ -0,0 +1,312 @@
+// Package filter parses filter expressions such as
+//
+//	activeAt>=2024-05-01 AND title~"report" AND status IN (active,done)
+//
+// into a tree that can be evaluated in memory (Match) or translated to a SQL
+// condition (SQL).
+//
+// Comparisons are field OP value with OP one of = != < <= > >= and ~
+// (case-insensitive substring), or field [NOT] IN (value, ...). They combine
+// with AND, OR, NOT and parentheses; AND binds tighter than OR. Keywords are
+// case-insensitive. Values are bare words or double-quoted strings with
+// backslash escapes.
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+)
+
+// Kind is the type of a field; it decides which values and operators the
+// field accepts.
+type Kind int
+
+const (
+	String Kind = iota
+	// Date fields hold models.DateLayout dates.
+	Date
+	// Time fields hold UTC timestamps formatted with TimeLayout. Filter
+	// values may be RFC 3339 timestamps or dates, meaning midnight UTC.
+	Time
+)
+
+// TimeLayout has a fixed width so that timestamps compare correctly as
+// strings.
+const TimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// Schema lists the fields an expression may refer to.
+type Schema map[string]Kind
+
+func (s Schema) fields() string {
+	fields := make([]string, 0, len(s))
+	for field := range s {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return strings.Join(fields, ", ")
+}
+
+type Op string
+
+const (
+	Eq       Op = "="
+	Ne       Op = "!="
+	Lt       Op = "<"
+	Le       Op = "<="
+	Gt       Op = ">"
+	Ge       Op = ">="
+	Contains Op = "~"
+	In       Op = "IN"
+	NotIn    Op = "NOT IN"
+)
+
+// Expr is a node of a parsed expression: *And, *Or, *Not or *Comparison.
+type Expr interface {
+	expr()
+}
+
+type And struct {
+	Left, Right Expr
+}
+
+type Or struct {
+	Left, Right Expr
+}
+
+type Not struct {
+	Expr Expr
+}
+
+// Comparison tests one field. Values holds a single value except for In
+// and NotIn; Time values are normalized to TimeLayout.
+type Comparison struct {
+	Field  string
+	Op     Op
+	Values []string
+}
+
+func (*And) expr()        {}
+func (*Or) expr()         {}
+func (*Not) expr()        {}
+func (*Comparison) expr() {}
+
+// Error describes a malformed or invalid expression.
+type Error struct {
+	// Pos is the byte offset in the input the error refers to.
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("at position %d: %s", e.Pos, e.Msg)
+}
+
+// Parse parses input and checks it against schema. An empty input yields a
+// nil Expr, which matches everything.
+func Parse(input string, schema Schema) (Expr, error) {
+	p := &parser{lex: lexer{input: input}, schema: schema}
+	p.next()
+
+	if p.tok.kind == tokEOF {
+		return nil, nil
+	}
+
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+
+	return e, nil
+}
+
+type parser struct {
+	lex    lexer
+	tok    token
+	schema Schema
+	err    error
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lex.next()
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	return &Error{Pos: p.tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// keyword reports whether the current token is the unquoted word kw.
+func (p *parser) keyword(kw string) bool {
+	return p.tok.kind == tokWord && strings.EqualFold(p.tok.text, kw)
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("AND") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	switch {
+	case p.keyword("NOT"):
+		p.next()
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: e}, nil
+
+	case p.tok.kind == tokLParen:
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("expected ) but found %s", p.tok)
+		}
+		p.next()
+		return e, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	if p.tok.kind != tokWord {
+		return nil, p.errorf("expected a field name but found %s", p.tok)
+	}
+
+	fieldTok := p.tok
+	kind, ok := p.schema[fieldTok.text]
+	if !ok {
+		return nil, &Error{Pos: fieldTok.pos, Msg: fmt.Sprintf("unknown field %q; allowed fields are %s", fieldTok.text, p.schema.fields())}
+	}
+
+	c := &Comparison{Field: fieldTok.text}
+	p.next()
+
+	switch {
+	case p.tok.kind == tokOp:
+		c.Op = Op(p.tok.text)
+		if c.Op == Contains && kind != String {
+			return nil, p.errorf("operator ~ applies only to text fields, not %s", c.Field)
+		}
+		p.next()
+
+		value, err := p.parseValue(kind)
+		if err != nil {
+			return nil, err
+		}
+		c.Values = []string{value}
+
+	case p.keyword("IN"), p.keyword("NOT"):
+		c.Op = In
+		if p.keyword("NOT") {
+			c.Op = NotIn
+			p.next()
+			if !p.keyword("IN") {
+				return nil, p.errorf("expected IN but found %s", p.tok)
+			}
+		}
+		p.next()
+
+		if p.tok.kind != tokLParen {
+			return nil, p.errorf("expected ( but found %s", p.tok)
+		}
+		p.next()
+
+		for {
+			value, err := p.parseValue(kind)
+			if err != nil {
+				return nil, err
+			}
+			c.Values = append(c.Values, value)
+
+			if p.tok.kind != tokComma {
+				break
+			}
+			p.next()
+		}
+
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("expected , or ) but found %s", p.tok)
+		}
+		p.next()
+
+	default:
+		return nil, p.errorf("expected an operator after %s but found %s", c.Field, p.tok)
+	}
+
+	return c, nil
+}
+
+func (p *parser) parseValue(kind Kind) (string, error) {
+	if p.tok.kind != tokWord && p.tok.kind != tokString {
+		return "", p.errorf("expected a value but found %s", p.tok)
+	}
+
+	value := p.tok.text
+	switch kind {
+	case Date:
+		if _, err := time.Parse(models.DateLayout, value); err != nil {
+			return "", p.errorf("invalid date %q, want YYYY-MM-DD", value)
+		}
+	case Time:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			if t, err = time.Parse(models.DateLayout, value); err != nil {
+				return "", p.errorf("invalid timestamp %q, want RFC 3339 or YYYY-MM-DD", value)
+			}
+		}
+		value = t.UTC().Format(TimeLayout)
+	}
+	p.next()
+
+	return value, nil
+}
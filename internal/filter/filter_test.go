@@ -0,0 +1,160 @@
+package filter_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/canyouhearthemusic/todo-list/internal/filter"
+)
+
+var schema = filter.Schema{
+	"title":     filter.String,
+	"status":    filter.String,
+	"activeAt":  filter.Date,
+	"createdAt": filter.Time,
+	"dueAt":     filter.Time,
+}
+
+var columns = map[string]string{
+	"title":     "title",
+	"status":    "status",
+	"activeAt":  "active_at",
+	"createdAt": "created_at",
+	"dueAt":     "due_at",
+}
+
+func TestSQL(t *testing.T) {
+	tests := []struct {
+		input string
+		sql   string
+		args  []any
+	}{
+		{"", "1 = 1", nil},
+		{
+			`activeAt>=2024-05-01 AND title~"Report" AND status IN (active,done)`,
+			"((active_at >= ? AND instr(unicode_lower(title), ?) > 0) AND status IN (?, ?))",
+			[]any{"2024-05-01", "report", "active", "done"},
+		},
+		{
+			"status = done or not (activeAt < 2024-01-01 and status != active)",
+			"(status = ? OR NOT ((active_at < ? AND status != ?)))",
+			[]any{"done", "2024-01-01", "active"},
+		},
+		{
+			"status NOT IN (done)",
+			"status NOT IN (?)",
+			[]any{"done"},
+		},
+		{
+			"createdAt > 2024-05-01T12:00:00+05:00",
+			"created_at > ?",
+			[]any{"2024-05-01T07:00:00.000000000Z"},
+		},
+		{
+			`title = "say \"hi\", (then) AND leave"`,
+			"title = ?",
+			[]any{`say "hi", (then) AND leave`},
+		},
+	}
+
+	for _, tt := range tests {
+		e, err := filter.Parse(tt.input, schema)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.input, err)
+			continue
+		}
+
+		sql, args := filter.SQL(e, columns)
+		if sql != tt.sql || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("SQL(%q) = %q %v, want %q %v", tt.input, sql, args, tt.sql, tt.args)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	task := map[string]string{
+		"title":     "Годовой ОТЧЁТ",
+		"status":    "active",
+		"activeAt":  "2024-05-06",
+		"createdAt": "2024-05-01T09:30:00.000000000Z",
+	}
+	// dueAt is missing, like a NULL column.
+	value := func(field string) (string, bool) {
+		v, ok := task[field]
+		return v, ok
+	}
+
+	tests := map[string]bool{
+		"":                  true,
+		"title~отчёт":       true,
+		`title~"годовой о"`: true,
+		"title~report":      false,
+		"activeAt>=2024-05-01 AND activeAt<2024-06-01": true,
+		"activeAt>2024-05-06":                          false,
+		"status IN (active, done)":                     true,
+		"status NOT IN (active)":                       false,
+		"NOT status = done":                            true,
+		"status = done OR activeAt = 2024-05-06":       true,
+		"status = done OR activeAt = 2024-05-07":       false,
+		"createdAt < 2024-05-02":                       true,
+		"createdAt >= 2024-05-01T10:00:00+01:00":       true,
+		"createdAt >= 2024-05-01T10:00:00Z":            false,
+		"dueAt > 2024-01-01":                           false,
+		"dueAt <= 2024-01-01":                          false,
+		"NOT dueAt > 2024-01-01":                       false,
+		"dueAt NOT IN (2024-01-01)":                    false,
+		"dueAt > 2024-01-01 OR status = active":        true,
+		"NOT (dueAt > 2024-01-01 AND status = done)":   true,
+		"NOT (dueAt > 2024-01-01 OR status = active)":  false,
+	}
+
+	for input, want := range tests {
+		e, err := filter.Parse(input, schema)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", input, err)
+			continue
+		}
+
+		if got := filter.Match(e, value); got != want {
+			t.Errorf("Match(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int
+		msg   string
+	}{
+		{"priority = high", 0, `unknown field "priority"`},
+		{"status = active AND owner = me", 20, `unknown field "owner"`},
+		{"activeAt >= May", 12, "invalid date"},
+		{"createdAt < yesterday", 12, "invalid timestamp"},
+		{"activeAt ~ 2024", 9, "operator ~ applies only to text fields"},
+		{"status", 6, "expected an operator"},
+		{"status = ", 9, "expected a value"},
+		{"status IN active", 10, "expected ("},
+		{"status IN (active", 17, "expected , or )"},
+		{"(status = done", 14, "expected )"},
+		{"status = done done", 14, "unexpected"},
+		{`title = "open`, 8, "unterminated string"},
+		{"status ! done", 7, "expected !="},
+	}
+
+	for _, tt := range tests {
+		_, err := filter.Parse(tt.input, schema)
+
+		var ferr *filter.Error
+		if !errors.As(err, &ferr) {
+			t.Errorf("Parse(%q): got %v, want a *filter.Error", tt.input, err)
+			continue
+		}
+
+		if ferr.Pos != tt.pos || !strings.Contains(ferr.Msg, tt.msg) {
+			t.Errorf("Parse(%q): got %q at %d, want %q at %d", tt.input, ferr.Msg, ferr.Pos, tt.msg, tt.pos)
+		}
+	}
+}
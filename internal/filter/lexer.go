@@ -0,0 +1,120 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of input"
+	}
+
+	return fmt.Sprintf("%q", t.text)
+}
+
+type lexer struct {
+	input string
+	pos   int
+}
+
+// special are the characters that end a bare word.
+const special = `()=!<>~,"`
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		l.pos += size
+	}
+
+	start := l.pos
+	if start == len(l.input) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	switch c := l.input[start]; c {
+	case '(':
+		l.pos++
+		return token{kind: tokLParen, text: "(", pos: start}, nil
+	case ')':
+		l.pos++
+		return token{kind: tokRParen, text: ")", pos: start}, nil
+	case ',':
+		l.pos++
+		return token{kind: tokComma, text: ",", pos: start}, nil
+	case '=', '~':
+		l.pos++
+		return token{kind: tokOp, text: string(c), pos: start}, nil
+	case '<', '>', '!':
+		l.pos++
+		if l.pos < len(l.input) && l.input[l.pos] == '=' {
+			l.pos++
+			return token{kind: tokOp, text: l.input[start:l.pos], pos: start}, nil
+		}
+		if c == '!' {
+			return token{}, &Error{Pos: start, Msg: `expected != but found "!"`}
+		}
+		return token{kind: tokOp, text: string(c), pos: start}, nil
+	case '"':
+		return l.quoted()
+	}
+
+	for l.pos < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+		if unicode.IsSpace(r) || strings.ContainsRune(special, r) {
+			break
+		}
+		l.pos += size
+	}
+
+	return token{kind: tokWord, text: l.input[start:l.pos], pos: start}, nil
+}
+
+func (l *lexer) quoted() (token, error) {
+	start := l.pos
+	l.pos++
+
+	var b strings.Builder
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		switch c {
+		case '"':
+			l.pos++
+			return token{kind: tokString, text: b.String(), pos: start}, nil
+		case '\\':
+			if l.pos+1 == len(l.input) {
+				return token{}, &Error{Pos: l.pos, Msg: "unterminated escape"}
+			}
+			b.WriteByte(l.input[l.pos+1])
+			l.pos += 2
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+
+	return token{}, &Error{Pos: start, Msg: "unterminated string"}
+}
@@ -0,0 +1,67 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LowerFunc names the SQL function ~ is rendered with. The database must
+// provide it, folding case the way strings.ToLower does, for the backends to
+// agree on non-ASCII text; SQLite's own lower() folds ASCII only.
+const LowerFunc = "unicode_lower"
+
+// SQL translates e into a condition for a WHERE clause with ? placeholders.
+// columns maps every schema field to its column; stored values must use the
+// same representation as Match expects. A nil Expr yields "1 = 1".
+func SQL(e Expr, columns map[string]string) (string, []any) {
+	var b strings.Builder
+	var args []any
+
+	writeSQL(&b, &args, e, columns)
+
+	return b.String(), args
+}
+
+func writeSQL(b *strings.Builder, args *[]any, e Expr, columns map[string]string) {
+	switch e := e.(type) {
+	case nil:
+		b.WriteString("1 = 1")
+	case *And:
+		writeBinarySQL(b, args, "AND", e.Left, e.Right, columns)
+	case *Or:
+		writeBinarySQL(b, args, "OR", e.Left, e.Right, columns)
+	case *Not:
+		b.WriteString("NOT (")
+		writeSQL(b, args, e.Expr, columns)
+		b.WriteString(")")
+	case *Comparison:
+		column, ok := columns[e.Field]
+		if !ok {
+			panic(fmt.Sprintf("filter: no column for field %q", e.Field))
+		}
+
+		switch e.Op {
+		case Contains:
+			fmt.Fprintf(b, "instr(%s(%s), ?) > 0", LowerFunc, column)
+			*args = append(*args, strings.ToLower(e.Values[0]))
+		case In, NotIn:
+			fmt.Fprintf(b, "%s %s (%s)", column, e.Op, strings.TrimSuffix(strings.Repeat("?, ", len(e.Values)), ", "))
+			for _, v := range e.Values {
+				*args = append(*args, v)
+			}
+		default:
+			fmt.Fprintf(b, "%s %s ?", column, e.Op)
+			*args = append(*args, e.Values[0])
+		}
+	default:
+		panic(fmt.Sprintf("filter: unexpected node %T", e))
+	}
+}
+
+func writeBinarySQL(b *strings.Builder, args *[]any, op string, left, right Expr, columns map[string]string) {
+	b.WriteString("(")
+	writeSQL(b, args, left, columns)
+	b.WriteString(" " + op + " ")
+	writeSQL(b, args, right, columns)
+	b.WriteString(")")
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/canyouhearthemusic/todo-list/internal/services"
+)
+
+// maxActorLength bounds the X-Actor header.
+const maxActorLength = 100
+
+// Actor stores the X-Actor header, naming who makes the request, in the
+// request context. Status changes record it as statusChangedBy.
+func Actor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := strings.TrimSpace(r.Header.Get("X-Actor"))
+		if len(actor) > maxActorLength {
+			respondWithBadRequest(w, r, fmt.Errorf("X-Actor must be at most %d characters", maxActorLength))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(services.WithActor(r.Context(), actor)))
+	})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+)
+
+// maxBatchBodySize bounds the body of a batch request.
+const maxBatchBodySize = 4 << 20
+
+// BatchResponse lists the outcome of every operation of a batch, in order.
+type BatchResponse struct {
+	Results []BatchItemResult `json:"results"`
+}
+
+// BatchItemResult is the outcome of one operation: the status it would have
+// got as a request of its own, and either the task or the problem.
+type BatchItemResult struct {
+	Status int          `json:"status" example:"201"`
+	Task   *models.Task `json:"task,omitempty"`
+	Error  *Problem     `json:"error,omitempty"`
+}
+
+// BatchTasks godoc
+// @Summary Create, update, complete and delete tasks in one request
+// @Description Apply up to 100 operations in order. Each does what the single-task request would, against the tasks as the operations before it left them, and gets its own result with the status that request would have got. With atomic set, either every operation is applied or none is: the failing one reports its error and the rest 424 Failed Dependency.
+// @Tags tasks
+// @Accept  json
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   batch  body  models.BatchRequest  true  "Operations"
+// @Success 200 {object} handlers.BatchResponse
+// @Failure 400 {object} handlers.Problem "Bad Request"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/tasks:batch [post]
+func BatchTasks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req models.BatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodySize)).Decode(&req); err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+
+	results, err := service.BatchTasks(ctx, req)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	resp := BatchResponse{Results: make([]BatchItemResult, len(results))}
+	for i, result := range results {
+		if result.Err != nil {
+			p := problemFor(r, result.Err)
+			resp.Results[i] = BatchItemResult{Status: p.Status, Error: p}
+			continue
+		}
+
+		resp.Results[i] = BatchItemResult{
+			Status: batchSuccessStatus(req.Operations[i].Op),
+			Task:   result.Task,
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// batchSuccessStatus mirrors the status of the single-task endpoints: 201
+// for POST /api/tasks, 204 for PUT and DELETE /api/tasks/{id} and 200 for
+// PUT /api/tasks/{id}/done. An updated task is still returned in its result.
+func batchSuccessStatus(op string) int {
+	switch op {
+	case "create":
+		return http.StatusCreated
+	case "update", "delete":
+		return http.StatusNoContent
+	case "done":
+		return http.StatusOK
+	}
+
+	// The service refuses any other op before it succeeds.
+	panic("handlers: unexpected batch op " + op)
+}
@@ -0,0 +1,94 @@
+package handlers_test
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/canyouhearthemusic/todo-list/internal/handlers"
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/canyouhearthemusic/todo-list/internal/repositories"
+)
+
+func TestBatchTasks(t *testing.T) {
+	repo := repositories.NewSyncMapTaskRepo()
+	server := newServer(t, repo)
+
+	var existing models.Task
+	if resp := do(t, server, http.MethodPost, "/api/tasks", models.Task{Title: "existing", ActiveAt: "2024-05-06"}, &existing); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /api/tasks: %d", resp.StatusCode)
+	}
+
+	// statuses lists the status of every result, and checks that failed
+	// operations carry a problem of that status.
+	statuses := func(batch handlers.BatchResponse) []int {
+		t.Helper()
+		var got []int
+		for i, result := range batch.Results {
+			got = append(got, result.Status)
+			if result.Status >= 400 && (result.Error == nil || result.Error.Status != result.Status) {
+				t.Errorf("result %d: status %d with problem %+v", i, result.Status, result.Error)
+			}
+		}
+		return got
+	}
+
+	// The :batch suffix is routed outside /api/tasks/{id}.
+	var batch handlers.BatchResponse
+	resp := do(t, server, http.MethodPost, "/api/tasks:batch", models.BatchRequest{Operations: []models.BatchOperation{
+		{Op: "create", Task: &models.Task{Title: "created", ActiveAt: "2024-05-06"}},
+		{Op: "update", ID: existing.ID, Task: &models.Task{Title: "renamed", ActiveAt: "2024-05-06"}},
+		{Op: "done", ID: existing.ID},
+		{Op: "delete", ID: "missing"},
+		{Op: "create", Task: &models.Task{Title: "created", ActiveAt: "2024-05-07"}},
+		{Op: "update", ID: existing.ID, Version: existing.Version},
+		{Op: "delete", ID: existing.ID},
+	}}, &batch)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /api/tasks:batch: %d", resp.StatusCode)
+	}
+	want := []int{201, 204, 200, 404, 409, 422, 204}
+	if got := statuses(batch); !slices.Equal(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+	if task := batch.Results[1].Task; task == nil || task.Title != "renamed" {
+		t.Errorf("updated task %+v, want it renamed", task)
+	}
+	if task := batch.Results[2].Task; task == nil || task.Status != "done" {
+		t.Errorf("done task %+v, want it done", task)
+	}
+	if batch.Results[6].Task != nil {
+		t.Errorf("deleted task %+v, want none", batch.Results[6].Task)
+	}
+
+	// In atomic mode the failing operation reports its own problem, the
+	// rest 424, and nothing is stored.
+	batch = handlers.BatchResponse{}
+	do(t, server, http.MethodPost, "/api/tasks:batch", models.BatchRequest{Atomic: true, Operations: []models.BatchOperation{
+		{Op: "create", Task: &models.Task{Title: "atomic", ActiveAt: "2024-05-06"}},
+		{Op: "done", ID: "missing"},
+		{Op: "create", Task: &models.Task{Title: "after", ActiveAt: "2024-05-06"}},
+	}}, &batch)
+	want = []int{424, 404, 424}
+	if got := statuses(batch); !slices.Equal(got, want) {
+		t.Errorf("atomic statuses = %v, want %v", got, want)
+	}
+	if batch.Results[0].Error.Type != handlers.ProblemBatchAborted {
+		t.Errorf("aborted operation problem type %q, want %q", batch.Results[0].Error.Type, handlers.ProblemBatchAborted)
+	}
+
+	var tasks []models.Task
+	do(t, server, http.MethodGet, "/api/tasks?status=active,done", nil, &tasks)
+	if len(tasks) != 1 || tasks[0].Title != "created" {
+		t.Errorf("tasks after the batches: %+v, want only created", tasks)
+	}
+
+	for body, want := range map[string]int{
+		`{"operations":[]}`: http.StatusUnprocessableEntity,
+		`{"operations":`:    http.StatusBadRequest,
+	} {
+		if resp := do(t, server, http.MethodPost, "/api/tasks:batch", body, nil); resp.StatusCode != want {
+			t.Errorf("batch %s: %d, want %d", body, resp.StatusCode, want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetBlockers godoc
+// @Summary Get the blockers of a task
+// @Description List the tasks a task is blocked by, in the order they were added
+// @Tags blockers
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   id  path  string  true  "Task ID"
+// @Success 200 {array} models.Task
+// @Header  200 {string} ETag "Version of the task"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/tasks/{id}/blockers [get]
+func GetBlockers(w http.ResponseWriter, r *http.Request) {
+	blockers, task, err := service.GetBlockers(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", task.ETag())
+	respondWithJSON(w, http.StatusOK, blockers)
+}
+
+// PutBlocker godoc
+// @Summary Block a task by another
+// @Description Make a task blocked by another one, which then has to be finished first. Blockers that would make a cycle are refused.
+// @Tags blockers
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   id         path  string  true  "Task ID"
+// @Param   blockerId  path  string  true  "ID of the blocking task"
+// @Param   If-Match       header  string  false  "Change only if the task has one of these ETags"
+// @Param   If-None-Match  header  string  false  "Change only if the task has none of these ETags"
+// @Success 200 {object} models.Task
+// @Header  200 {string} ETag "New version of the task"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 412 {object} handlers.Problem "Precondition Failed (also when the task changed meanwhile)"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity: no such blocking task, or a cycle"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/tasks/{id}/blockers/{blockerId} [put]
+func PutBlocker(w http.ResponseWriter, r *http.Request) {
+	task, err := service.AddBlocker(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "blockerId"), preconditionFrom(r))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", task.ETag())
+	respondWithJSON(w, http.StatusOK, task)
+}
+
+// DeleteBlocker godoc
+// @Summary Unblock a task
+// @Description Stop a task from being blocked by another one
+// @Tags blockers
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   id         path  string  true  "Task ID"
+// @Param   blockerId  path  string  true  "ID of the blocking task"
+// @Param   If-Match       header  string  false  "Change only if the task has one of these ETags"
+// @Param   If-None-Match  header  string  false  "Change only if the task has none of these ETags"
+// @Success 204 {string} string "No Content"
+// @Header  204 {string} ETag "New version of the task"
+// @Failure 404 {object} handlers.Problem "Not Found: no such task, or not blocked by that one"
+// @Failure 412 {object} handlers.Problem "Precondition Failed (also when the task changed meanwhile)"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/tasks/{id}/blockers/{blockerId} [delete]
+func DeleteBlocker(w http.ResponseWriter, r *http.Request) {
+	task, err := service.RemoveBlocker(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "blockerId"), preconditionFrom(r))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", task.ETag())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTaskOrder godoc
+// @Summary Get the order to work on tasks in
+// @Description List open tasks so that every task comes after the open tasks it is blocked by. Among tasks that could come next, the most urgent goes first, then the soonest due, then the earliest active. With project, blockers in other projects are not waited for.
+// @Tags blockers
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   project  query  string  false  "Only tasks of this project"
+// @Success 200 {array} models.Task
+// @Failure 404 {object} handlers.Problem "Not Found: no such project"
+// @Failure 409 {object} handlers.Problem "Conflict: the blockers of the named tasks form a cycle"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/tasks/order [get]
+func GetTaskOrder(w http.ResponseWriter, r *http.Request) {
+	tasks, err := service.TaskOrder(r.Context(), r.URL.Query().Get("project"))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, tasks)
+}
+
+// warnUnfinishedBlockers adds a Warning header naming the unfinished
+// blockers of task if it was marked done despite them, which the service
+// allows only when configured to warn.
+func warnUnfinishedBlockers(w http.ResponseWriter, r *http.Request, task *models.Task) {
+	blockers, err := service.UnfinishedBlockers(r.Context(), task)
+	if err != nil {
+		// The change is made already; only the warning is lost.
+		log.WithField("requestId", middleware.GetReqID(r.Context())).Errorf("unfinished blockers of %s: %v", task.ID, err)
+		return
+	}
+	if len(blockers) == 0 {
+		return
+	}
+
+	ids := make([]string, len(blockers))
+	for i, blocker := range blockers {
+		ids[i] = blocker.ID
+	}
+
+	w.Header().Set("Warning", "199 - "+strconv.Quote(fmt.Sprintf("done while blocked by unfinished tasks %s", strings.Join(ids, ", "))))
+}
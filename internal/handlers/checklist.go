@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetChecklist godoc
+// @Summary Get the checklist of a task
+// @Description List the checklist items of a task in their order
+// @Tags checklist
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   id  path  string  true  "Task ID"
+// @Success 200 {array} models.ChecklistItem
+// @Header  200 {string} ETag "Version of the task"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/tasks/{id}/checklist [get]
+func GetChecklist(w http.ResponseWriter, r *http.Request) {
+	checklist, task, err := service.GetChecklist(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", task.ETag())
+	respondWithJSON(w, http.StatusOK, checklist)
+}
+
+// PostChecklistItem godoc
+// @Summary Add a checklist item
+// @Description Append an item to the checklist of a task; a checklist holds at most 100 items
+// @Tags checklist
+// @Accept  json
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   id    path  string                       true  "Task ID"
+// @Param   item  body  models.ChecklistItemRequest  true  "Checklist item"
+// @Param   If-Match       header  string  false  "Change only if the task has one of these ETags"
+// @Param   If-None-Match  header  string  false  "Change only if the task has none of these ETags"
+// @Success 201 {object} models.ChecklistItem
+// @Header  201 {string} ETag "New version of the task"
+// @Failure 400 {object} handlers.Problem "Bad Request"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 412 {object} handlers.Problem "Precondition Failed (also when the task changed meanwhile)"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/tasks/{id}/checklist [post]
+func PostChecklistItem(w http.ResponseWriter, r *http.Request) {
+	var req models.ChecklistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+
+	item, task, err := service.AddChecklistItem(r.Context(), chi.URLParam(r, "id"), req, preconditionFrom(r))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", task.ETag())
+	respondWithJSON(w, http.StatusCreated, item)
+}
+
+// PutChecklistItem godoc
+// @Summary Update a checklist item
+// @Description Replace the text of a checklist item and whether it is done
+// @Tags checklist
+// @Accept  json
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   id      path  string                       true  "Task ID"
+// @Param   itemId  path  string                       true  "Checklist item ID"
+// @Param   item    body  models.ChecklistItemRequest  true  "Checklist item"
+// @Param   If-Match       header  string  false  "Change only if the task has one of these ETags"
+// @Param   If-None-Match  header  string  false  "Change only if the task has none of these ETags"
+// @Success 200 {object} models.ChecklistItem
+// @Header  200 {string} ETag "New version of the task"
+// @Failure 400 {object} handlers.Problem "Bad Request"
+// @Failure 404 {object} handlers.Problem "Not Found: no such task or item"
+// @Failure 412 {object} handlers.Problem "Precondition Failed (also when the task changed meanwhile)"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/tasks/{id}/checklist/{itemId} [put]
+func PutChecklistItem(w http.ResponseWriter, r *http.Request) {
+	var req models.ChecklistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+
+	item, task, err := service.PutChecklistItem(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "itemId"), req, preconditionFrom(r))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", task.ETag())
+	respondWithJSON(w, http.StatusOK, item)
+}
+
+// DeleteChecklistItem godoc
+// @Summary Delete a checklist item
+// @Description Remove an item from the checklist of a task
+// @Tags checklist
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   id      path  string  true  "Task ID"
+// @Param   itemId  path  string  true  "Checklist item ID"
+// @Param   If-Match       header  string  false  "Change only if the task has one of these ETags"
+// @Param   If-None-Match  header  string  false  "Change only if the task has none of these ETags"
+// @Success 204 {string} string "No Content"
+// @Header  204 {string} ETag "New version of the task"
+// @Failure 404 {object} handlers.Problem "Not Found: no such task or item"
+// @Failure 412 {object} handlers.Problem "Precondition Failed (also when the task changed meanwhile)"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/tasks/{id}/checklist/{itemId} [delete]
+func DeleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	task, err := service.DeleteChecklistItem(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "itemId"), preconditionFrom(r))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", task.ETag())
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/canyouhearthemusic/todo-list/internal/services"
+)
+
+// preconditionFrom reads the If-Match and If-None-Match headers of r.
+func preconditionFrom(r *http.Request) services.Precondition {
+	return services.Precondition{
+		IfMatch:     entityTags(r.Header.Values("If-Match")),
+		IfNoneMatch: entityTags(r.Header.Values("If-None-Match")),
+	}
+}
+
+// entityTags splits the comma-separated lists in values. It returns nil if
+// the header was absent.
+func entityTags(values []string) []string {
+	var tags []string
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	return tags
+}
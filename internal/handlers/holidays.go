@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/canyouhearthemusic/todo-list/internal/calendar"
+	"github.com/canyouhearthemusic/todo-list/internal/services"
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	holidayService *services.HolidayService
+)
+
+// SetHolidayService sets the HolidayService used by the holiday handlers.
+func SetHolidayService(s *services.HolidayService) {
+	holidayService = s
+}
+
+// GetHolidays godoc
+// @Summary List holidays
+// @Description List public and custom holidays of a region
+// @Tags holidays
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   region  query  string  false  "Region, e.g. KZ (defaults to the configured region)"
+// @Param   year    query  int     false  "Expand annual holidays into this year"
+// @Success 200 {array} calendar.PublicHoliday
+// @Failure 400 {object} handlers.Problem "Bad Request"
+// @Router /api/holidays [get]
+func GetHolidays(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	region := r.URL.Query().Get("region")
+
+	var year int
+	if raw := r.URL.Query().Get("year"); raw != "" {
+		var err error
+		if year, err = strconv.Atoi(raw); err != nil {
+			respondWithBadRequest(w, r, fmt.Errorf("invalid year %q", raw))
+			return
+		}
+	}
+
+	holidays := holidayService.GetHolidays(ctx, region, year)
+	if holidays == nil {
+		holidays = []*calendar.PublicHoliday{}
+	}
+
+	respondWithJSON(w, http.StatusOK, holidays)
+}
+
+// PostHoliday godoc
+// @Summary Add a company holiday
+// @Description Add a custom holiday; without a region it applies everywhere
+// @Tags holidays
+// @Accept  json
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   holiday  body  calendar.HolidayRequest  true  "Holiday"
+// @Success 201 {object} calendar.PublicHoliday
+// @Failure 400 {object} handlers.Problem "Bad Request"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/holidays [post]
+func PostHoliday(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req calendar.HolidayRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+
+	holiday := calendar.PublicHoliday{
+		Date:   req.Date,
+		Name:   req.Name,
+		Region: req.Region,
+		Annual: req.Annual,
+	}
+
+	if err := holidayService.PostHoliday(ctx, &holiday); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, holiday)
+}
+
+// DeleteHoliday godoc
+// @Summary Delete a company holiday
+// @Description Delete a custom holiday by ID; holidays loaded from files cannot be deleted
+// @Tags holidays
+// @Produce  application/problem+json
+// @Param   id  path  string  true  "Holiday ID"
+// @Success 204 {string} string "No Content"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/holidays/{id} [delete]
+func DeleteHoliday(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	if err := holidayService.DeleteHoliday(ctx, id); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/canyouhearthemusic/todo-list/internal/idempotency"
+)
+
+const (
+	// maxIdempotencyKeyLength bounds the Idempotency-Key header.
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodySize bounds the body hashed into the fingerprint.
+	maxIdempotentBodySize = 1 << 20
+)
+
+// Idempotent makes a handler honour the Idempotency-Key header: the first
+// response for a key is stored and replayed, marked with Idempotent-Replayed,
+// for requests repeating the key with the same body. Reusing a key with a
+// different body is rejected with 422, and retrying while the first request
+// still runs with 409. Server errors are not stored, so they can be retried.
+// Requests without the header pass through untouched.
+func Idempotent(store *idempotency.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				respondWithBadRequest(w, r, fmt.Errorf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+			if err != nil {
+				respondWithBadRequest(w, r, err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			stored, err := store.Begin(key, idempotency.NewFingerprint(r.Method, r.URL.Path, body))
+			switch {
+			case errors.Is(err, idempotency.ErrKeyReused):
+				writeProblem(w, newProblem(r, http.StatusUnprocessableEntity, ProblemIdempotencyKeyReused, err.Error()))
+				return
+			case errors.Is(err, idempotency.ErrInProgress):
+				writeProblem(w, newProblem(r, http.StatusConflict, ProblemConflict, err.Error()))
+				return
+			case err != nil:
+				respondWithError(w, r, err)
+				return
+			case stored != nil:
+				replay(w, stored)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				if p := recover(); p != nil {
+					store.Release(key)
+					panic(p)
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError {
+				store.Release(key)
+				return
+			}
+			store.Complete(key, &idempotency.Response{
+				Status: rec.status,
+				Header: w.Header().Clone(),
+				Body:   rec.body.Bytes(),
+			})
+		})
+	}
+}
+
+// replay writes resp, copying its header values so that changes to the
+// response never reach the stored ones.
+func replay(w http.ResponseWriter, resp *idempotency.Response) {
+	for name, values := range resp.Header {
+		w.Header()[name] = slices.Clone(values)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+// responseRecorder passes a response through while keeping a copy of its
+// status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+
+	return rec.ResponseWriter.Write(b)
+}
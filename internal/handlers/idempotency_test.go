@@ -0,0 +1,129 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/handlers"
+	"github.com/canyouhearthemusic/todo-list/internal/idempotency"
+)
+
+// postWithKey sends a POST with an Idempotency-Key through h.
+func postWithKey(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/tasks", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", key)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	var calls atomic.Int32
+	h := handlers.Idempotent(idempotency.NewStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Location", "/api/tasks/first")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "created %d", n)
+	}))
+
+	first := postWithKey(h, "k1", `{"title":"a"}`)
+	if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("first request: %d replayed %q", first.Code, first.Header().Get("Idempotent-Replayed"))
+	}
+
+	for i := 0; i < 2; i++ {
+		again := postWithKey(h, "k1", `{"title":"a"}`)
+		if again.Code != http.StatusCreated || again.Body.String() != "created 1" || again.Header().Get("Idempotent-Replayed") != "true" {
+			t.Errorf("retry %d: %d %q replayed %q, want the first response replayed", i, again.Code, again.Body, again.Header().Get("Idempotent-Replayed"))
+		}
+		if got := again.Header().Get("Location"); got != "/api/tasks/first" {
+			t.Errorf("retry %d: Location %q, want the stored one", i, got)
+		}
+
+		// Changing the headers of a replayed response leaves the stored
+		// one alone.
+		again.Header()["Location"][0] = "/changed"
+		again.Header().Add("Location", "/added")
+	}
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want once", n)
+	}
+
+	// Requests without a key are not stored.
+	req := httptest.NewRequest(http.MethodPost, "/api/tasks", strings.NewReader(`{"title":"a"}`))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times after a request without a key, want twice", n)
+	}
+}
+
+func TestIdempotentKeyReused(t *testing.T) {
+	h := handlers.Idempotent(idempotency.NewStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	postWithKey(h, "k1", `{"title":"a"}`)
+
+	rec := postWithKey(h, "k1", `{"title":"b"}`)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), handlers.ProblemIdempotencyKeyReused) {
+		t.Errorf("key reused with another body: %d %s, want 422 %s", rec.Code, rec.Body, handlers.ProblemIdempotencyKeyReused)
+	}
+}
+
+func TestIdempotentInProgress(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	h := handlers.Idempotent(idempotency.NewStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postWithKey(h, "k1", `{"title":"a"}`) }()
+	<-started
+
+	rec := postWithKey(h, "k1", `{"title":"a"}`)
+	if rec.Code != http.StatusConflict || rec.Header().Get("Content-Type") != "application/problem+json" {
+		t.Errorf("retry while the first request runs: %d %s, want a 409 problem", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	close(release)
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Errorf("first request: %d, want 201", first.Code)
+	}
+}
+
+func TestIdempotentServerError(t *testing.T) {
+	var calls atomic.Int32
+	h := handlers.Idempotent(idempotency.NewStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	if rec := postWithKey(h, "k1", `{"title":"a"}`); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("first request: %d, want 503", rec.Code)
+	}
+
+	// The key is free again, so the retry runs and its response is kept.
+	retry := postWithKey(h, "k1", `{"title":"a"}`)
+	if retry.Code != http.StatusCreated || retry.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry after a server error: %d replayed %q, want it run", retry.Code, retry.Header().Get("Idempotent-Replayed"))
+	}
+	if replayed := postWithKey(h, "k1", `{"title":"a"}`); replayed.Code != http.StatusCreated || replayed.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("second retry: %d replayed %q, want the kept response", replayed.Code, replayed.Header().Get("Idempotent-Replayed"))
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want twice", n)
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/canyouhearthemusic/todo-list/internal/services"
+)
+
+// queryInt reads an optional integer query parameter.
+func queryInt(r *http.Request, name string) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q", name, raw)
+	}
+
+	return n, nil
+}
+
+// setPaginationHeaders sets X-Total-Count, X-Next-Cursor and an RFC 8288
+// Link header. Cursor requests get a cursor "next" link; offset requests
+// get first/prev/next/last links by offset.
+func setPaginationHeaders(w http.ResponseWriter, r *http.Request, opts services.ListOptions, list *services.TaskList) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(list.Total))
+	if list.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", list.NextCursor)
+	}
+
+	limit := opts.Limit
+	if limit == 0 {
+		limit = services.DefaultPageSize
+	}
+
+	var links []string
+	link := func(rel string, set map[string]string) {
+		u := *r.URL
+		q := u.Query()
+		q.Del("offset")
+		q.Del("cursor")
+		q.Set("limit", strconv.Itoa(limit))
+		for k, v := range set {
+			q.Set(k, v)
+		}
+		u.RawQuery = q.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, (&url.URL{Path: u.Path, RawQuery: u.RawQuery}).String(), rel))
+	}
+
+	if opts.Cursor != "" {
+		link("first", nil)
+		if list.NextCursor != "" {
+			link("next", map[string]string{"cursor": list.NextCursor})
+		}
+	} else {
+		link("first", nil)
+		if opts.Offset > 0 {
+			prev := opts.Offset - limit
+			if prev < 0 {
+				prev = 0
+			}
+			link("prev", map[string]string{"offset": strconv.Itoa(prev)})
+		}
+		if opts.Offset+limit < list.Total {
+			link("next", map[string]string{"offset": strconv.Itoa(opts.Offset + limit)})
+		}
+		if list.Total > 0 {
+			last := (list.Total - 1) / limit * limit
+			link("last", map[string]string{"offset": strconv.Itoa(last)})
+		}
+	}
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
@@ -0,0 +1,157 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/canyouhearthemusic/todo-list/internal/repositories"
+)
+
+func TestPaginationOffset(t *testing.T) {
+	server := newServer(t, repositories.NewSyncMapTaskRepo())
+	postNumbered(t, server, 5)
+
+	tests := []struct {
+		query  string
+		titles string
+		link   string
+	}{
+		{"limit=2", "task 1,task 2",
+			`</api/tasks?limit=2>; rel="first", </api/tasks?limit=2&offset=2>; rel="next", </api/tasks?limit=2&offset=4>; rel="last"`},
+		{"limit=2&offset=2", "task 3,task 4",
+			`</api/tasks?limit=2>; rel="first", </api/tasks?limit=2&offset=0>; rel="prev", </api/tasks?limit=2&offset=4>; rel="next", </api/tasks?limit=2&offset=4>; rel="last"`},
+		{"limit=2&offset=4", "task 5",
+			`</api/tasks?limit=2>; rel="first", </api/tasks?limit=2&offset=2>; rel="prev", </api/tasks?limit=2&offset=4>; rel="last"`},
+		// An offset off the page grid steps back by a whole page, but not
+		// past the start.
+		{"limit=2&offset=1", "task 2,task 3",
+			`</api/tasks?limit=2>; rel="first", </api/tasks?limit=2&offset=0>; rel="prev", </api/tasks?limit=2&offset=3>; rel="next", </api/tasks?limit=2&offset=4>; rel="last"`},
+		// Past the end the page is empty but the links still lead back.
+		{"limit=2&offset=10", "",
+			`</api/tasks?limit=2>; rel="first", </api/tasks?limit=2&offset=8>; rel="prev", </api/tasks?limit=2&offset=4>; rel="last"`},
+		// A single page links to itself as both first and last.
+		{"", "task 1,task 2,task 3,task 4,task 5",
+			`</api/tasks?limit=100>; rel="first", </api/tasks?limit=100&offset=0>; rel="last"`},
+		// Other parameters are kept.
+		{"limit=2&sort=-activeAt&offset=2", "task 3,task 2",
+			`</api/tasks?limit=2&sort=-activeAt>; rel="first", </api/tasks?limit=2&offset=0&sort=-activeAt>; rel="prev", </api/tasks?limit=2&offset=4&sort=-activeAt>; rel="next", </api/tasks?limit=2&offset=4&sort=-activeAt>; rel="last"`},
+	}
+
+	for _, tt := range tests {
+		var tasks []models.Task
+		resp := do(t, server, http.MethodGet, "/api/tasks?"+tt.query, nil, &tasks)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: %d", tt.query, resp.StatusCode)
+			continue
+		}
+
+		if got := titlesOf(tasks); got != tt.titles {
+			t.Errorf("%s: tasks [%s], want [%s]", tt.query, got, tt.titles)
+		}
+		if got := resp.Header.Get("X-Total-Count"); got != "5" {
+			t.Errorf("%s: X-Total-Count %s, want 5", tt.query, got)
+		}
+		if got := resp.Header.Get("Link"); got != tt.link {
+			t.Errorf("%s: Link\n%s\nwant\n%s", tt.query, got, tt.link)
+		}
+	}
+}
+
+func TestPaginationCursor(t *testing.T) {
+	server := newServer(t, repositories.NewSyncMapTaskRepo())
+	postNumbered(t, server, 5)
+
+	// Follow the next links from the first page to the last.
+	var pages []string
+	path := "/api/tasks?limit=2"
+	for i := 0; path != ""; i++ {
+		if i == 5 {
+			t.Fatal("next links do not end")
+		}
+
+		var tasks []models.Task
+		resp := do(t, server, http.MethodGet, path, nil, &tasks)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: %d", path, resp.StatusCode)
+		}
+		pages = append(pages, titlesOf(tasks))
+
+		if got := resp.Header.Get("X-Total-Count"); got != "5" {
+			t.Errorf("GET %s: X-Total-Count %s, want 5", path, got)
+		}
+
+		cursor := resp.Header.Get("X-Next-Cursor")
+		path = ""
+		if cursor != "" {
+			path = "/api/tasks?" + url.Values{"cursor": {cursor}, "limit": {"2"}}.Encode()
+		}
+
+		// Cursor pages link to the first page and to the next one only.
+		if i == 0 {
+			continue
+		}
+		want := `</api/tasks?limit=2>; rel="first"`
+		if path != "" {
+			want += fmt.Sprintf(`, <%s>; rel="next"`, path)
+		}
+		if got := resp.Header.Get("Link"); got != want {
+			t.Errorf("page %d: Link\n%s\nwant\n%s", i, got, want)
+		}
+	}
+
+	if got := strings.Join(pages, " | "); got != "task 1,task 2 | task 3,task 4 | task 5" {
+		t.Errorf("pages %s", got)
+	}
+}
+
+func TestPaginationInvalid(t *testing.T) {
+	server := newServer(t, repositories.NewSyncMapTaskRepo())
+
+	tests := map[string]int{
+		"limit=ten":           http.StatusBadRequest,
+		"offset=1.5":          http.StatusBadRequest,
+		"limit=-1":            http.StatusUnprocessableEntity,
+		"limit=1001":          http.StatusUnprocessableEntity,
+		"offset=-2":           http.StatusUnprocessableEntity,
+		"cursor=garbage":      http.StatusUnprocessableEntity,
+		"cursor=abc&offset=2": http.StatusBadRequest,
+		"limit=1000&offset=0": http.StatusOK,
+	}
+
+	for query, want := range tests {
+		resp := do(t, server, http.MethodGet, "/api/tasks?"+query, nil, nil)
+		if resp.StatusCode != want {
+			t.Errorf("%s: %d, want %d", query, resp.StatusCode, want)
+		}
+		if want != http.StatusOK && resp.Header.Get("Content-Type") != "application/problem+json" {
+			t.Errorf("%s: Content-Type %q, want a problem", query, resp.Header.Get("Content-Type"))
+		}
+	}
+}
+
+// postNumbered posts tasks "task 1" to "task n", active on consecutive days
+// from 2024-05-01.
+func postNumbered(t *testing.T, server *httptest.Server, n int) {
+	t.Helper()
+
+	for i := 1; i <= n; i++ {
+		task := models.Task{Title: fmt.Sprintf("task %d", i), ActiveAt: fmt.Sprintf("2024-05-%02d", i)}
+		if resp := do(t, server, http.MethodPost, "/api/tasks", task, nil); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("POST %s: %d", task.Title, resp.StatusCode)
+		}
+	}
+}
+
+func titlesOf(tasks []models.Task) string {
+	titles := make([]string, len(tasks))
+	for i, task := range tasks {
+		titles[i] = task.Title
+	}
+
+	return strings.Join(titles, ",")
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/go-chi/chi/v5/middleware"
+	log "github.com/sirupsen/logrus"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem type URIs, relative to the API root.
+const (
+	ProblemBadRequest           = "/problems/bad-request"
+	ProblemValidation           = "/problems/validation"
+	ProblemNotFound             = "/problems/not-found"
+	ProblemConflict             = "/problems/conflict"
+	ProblemPrecondition         = "/problems/precondition-failed"
+	ProblemMethodNotAllowed     = "/problems/method-not-allowed"
+	ProblemUnsupportedMedia     = "/problems/unsupported-media-type"
+	ProblemIdempotencyKeyReused = "/problems/idempotency-key-reused"
+	ProblemBatchAborted         = "/problems/batch-aborted"
+	ProblemNotImplemented       = "/problems/not-implemented"
+	ProblemInternal             = "/problems/internal"
+)
+
+// Problem is an RFC 7807 problem details document.
+type Problem struct {
+	Type      string              `json:"type" example:"/problems/not-found"`
+	Title     string              `json:"title" example:"Not Found"`
+	Status    int                 `json:"status" example:"404"`
+	Detail    string              `json:"detail,omitempty" example:"task not found"`
+	Instance  string              `json:"instance,omitempty" example:"/api/tasks/0b7c7f4e-4a7e-4bd1-9d0f-3f6f1f0c2d11"`
+	RequestID string              `json:"requestId,omitempty"`
+	Errors    []models.FieldError `json:"errors,omitempty"`
+}
+
+// respondWithError writes the problem document for err.
+func respondWithError(w http.ResponseWriter, r *http.Request, err error) {
+	writeProblem(w, problemFor(r, err))
+}
+
+// problemFor maps the typed errors from models to a problem document.
+// Anything unrecognised is a server fault and its details are only logged.
+func problemFor(r *http.Request, err error) *Problem {
+	var verr *models.ValidationError
+
+	switch {
+	case errors.As(err, &verr):
+		p := newProblem(r, http.StatusUnprocessableEntity, ProblemValidation, err.Error())
+		p.Errors = verr.Fields
+		return p
+	case errors.Is(err, models.ErrValidation):
+		return newProblem(r, http.StatusUnprocessableEntity, ProblemValidation, err.Error())
+	case errors.Is(err, models.ErrNotFound):
+		return newProblem(r, http.StatusNotFound, ProblemNotFound, err.Error())
+	case errors.Is(err, models.ErrConflict):
+		return newProblem(r, http.StatusConflict, ProblemConflict, err.Error())
+	case errors.Is(err, models.ErrPreconditionFailed):
+		return newProblem(r, http.StatusPreconditionFailed, ProblemPrecondition, err.Error())
+	case errors.Is(err, models.ErrBatchAborted):
+		return newProblem(r, http.StatusFailedDependency, ProblemBatchAborted, err.Error())
+	case errors.Is(err, models.ErrNotImplemented):
+		return newProblem(r, http.StatusNotImplemented, ProblemNotImplemented, err.Error())
+	default:
+		log.WithField("requestId", middleware.GetReqID(r.Context())).Errorf("internal error: %v", err)
+		return newProblem(r, http.StatusInternalServerError, ProblemInternal, "")
+	}
+}
+
+// respondWithBadRequest reports a request that could not be parsed at all.
+func respondWithBadRequest(w http.ResponseWriter, r *http.Request, err error) {
+	writeProblem(w, newProblem(r, http.StatusBadRequest, ProblemBadRequest, err.Error()))
+}
+
+// NotFound replaces the router's plain-text 404.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, newProblem(r, http.StatusNotFound, ProblemNotFound, "no route for "+r.URL.Path))
+}
+
+// MethodNotAllowed replaces the router's plain-text 405.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, newProblem(r, http.StatusMethodNotAllowed, ProblemMethodNotAllowed,
+		r.Method+" is not supported on "+r.URL.Path))
+}
+
+func newProblem(r *http.Request, status int, typ, detail string) *Problem {
+	return &Problem{
+		Type:      typ,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.RequestURI(),
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+}
+
+func writeProblem(w http.ResponseWriter, p *Problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		log.Errorf("write problem: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+)
+
+func TestRespondWithError(t *testing.T) {
+	verr := &models.ValidationError{}
+	verr.Add("title", "title exceeds 200 characters")
+
+	tests := []struct {
+		err    error
+		status int
+		typ    string
+	}{
+		{verr, http.StatusUnprocessableEntity, ProblemValidation},
+		{fmt.Errorf("%w: bad rule", models.ErrValidation), http.StatusUnprocessableEntity, ProblemValidation},
+		{models.ErrTaskNotFound, http.StatusNotFound, ProblemNotFound},
+		{models.ErrDuplicateTitle, http.StatusConflict, ProblemConflict},
+		{&models.BlockerCycleError{IDs: []string{"a", "b"}}, http.StatusConflict, ProblemConflict},
+		{models.ErrVersionMismatch, http.StatusPreconditionFailed, ProblemPrecondition},
+		{models.ErrBatchAborted, http.StatusFailedDependency, ProblemBatchAborted},
+		{fmt.Errorf("%w: search", models.ErrNotImplemented), http.StatusNotImplemented, ProblemNotImplemented},
+		{errors.New("open /var/lib/todo/tasks.db: permission denied"), http.StatusInternalServerError, ProblemInternal},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/tasks/42?x=1", nil)
+		rec := httptest.NewRecorder()
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			respondWithError(w, r, tt.err)
+		}).ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%v: status %d, want %d", tt.err, rec.Code, tt.status)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != problemContentType {
+			t.Errorf("%v: Content-Type %q, want %q", tt.err, ct, problemContentType)
+		}
+
+		body := rec.Body.String()
+		var p Problem
+		if err := json.Unmarshal([]byte(body), &p); err != nil {
+			t.Errorf("%v: decode problem: %v", tt.err, err)
+			continue
+		}
+		if p.Status != tt.status || p.Type != tt.typ || p.Title != http.StatusText(tt.status) || p.Instance != "/api/tasks/42?x=1" {
+			t.Errorf("%v: problem %+v, want status %d of type %s for /api/tasks/42?x=1", tt.err, p, tt.status, tt.typ)
+		}
+
+		if tt.err == verr {
+			if !slices.Equal(p.Errors, verr.Fields) {
+				t.Errorf("validation problem errors %+v, want %+v", p.Errors, verr.Fields)
+			}
+		} else if len(p.Errors) > 0 {
+			t.Errorf("%v: errors %+v, want none", tt.err, p.Errors)
+		}
+
+		switch tt.status {
+		case http.StatusInternalServerError:
+			// Internal errors are logged, never shown.
+			if p.Detail != "" || strings.Contains(body, "tasks.db") {
+				t.Errorf("internal error leaked: %s", body)
+			}
+		default:
+			if p.Detail != tt.err.Error() {
+				t.Errorf("%v: detail %q, want the error", tt.err, p.Detail)
+			}
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/canyouhearthemusic/todo-list/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	projectService *services.ProjectService
+)
+
+// SetProjectService sets the ProjectService used by the project handlers.
+func SetProjectService(s *services.ProjectService) {
+	projectService = s
+}
+
+// GetProjects godoc
+// @Summary List projects
+// @Description List every project, oldest first. Tasks created without a projectId go to the "default" project.
+// @Tags projects
+// @Produce  json
+// @Produce  application/problem+json
+// @Success 200 {array} models.Project
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/projects [get]
+func GetProjects(w http.ResponseWriter, r *http.Request) {
+	projects, err := projectService.GetProjects(r.Context())
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, projects)
+}
+
+// GetProject godoc
+// @Summary Get project by ID
+// @Description Get a project by ID; list its tasks with GET /api/tasks?project={id}
+// @Tags projects
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   id  path  string  true  "Project ID"
+// @Param   If-None-Match  header  string  false  "Answer 304 if the project still has one of these ETags"
+// @Success 200 {object} models.Project
+// @Header  200 {string} ETag "Version of the project"
+// @Success 304 {string} string "Not Modified"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/projects/{id} [get]
+func GetProject(w http.ResponseWriter, r *http.Request) {
+	project, err := projectService.GetProject(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", project.ETag())
+	if preconditionFrom(r).NotModified(project) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, project)
+}
+
+// PostProject godoc
+// @Summary Create a project
+// @Description Create a project; names are unique
+// @Tags projects
+// @Accept  json
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   project  body  models.ProjectRequest  true  "Project"
+// @Success 201 {object} models.Project
+// @Header  201 {string} ETag "Version of the project"
+// @Failure 400 {object} handlers.Problem "Bad Request"
+// @Failure 409 {object} handlers.Problem "Conflict"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/projects [post]
+func PostProject(w http.ResponseWriter, r *http.Request) {
+	var project models.Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+
+	if err := project.Validate(); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	project.ID = uuid.New().String()
+
+	if err := projectService.PostProject(r.Context(), &project); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", project.ETag())
+	respondWithJSON(w, http.StatusCreated, project)
+}
+
+// PutProject godoc
+// @Summary Update a project
+// @Description Rename a project or change its description
+// @Tags projects
+// @Accept  json
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   id       path  string                 true  "Project ID"
+// @Param   project  body  models.ProjectRequest  true  "Project"
+// @Param   If-Match       header  string  false  "Update only if the project has one of these ETags"
+// @Param   If-None-Match  header  string  false  "Update only if the project has none of these ETags"
+// @Success 200 {object} models.Project
+// @Header  200 {string} ETag "New version of the project"
+// @Failure 400 {object} handlers.Problem "Bad Request"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 409 {object} handlers.Problem "Conflict"
+// @Failure 412 {object} handlers.Problem "Precondition Failed"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/projects/{id} [put]
+func PutProject(w http.ResponseWriter, r *http.Request) {
+	var project models.Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+
+	if err := project.Validate(); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	if err := projectService.PutProject(r.Context(), chi.URLParam(r, "id"), &project, preconditionFrom(r)); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", project.ETag())
+	respondWithJSON(w, http.StatusOK, project)
+}
+
+// DeleteProject godoc
+// @Summary Delete a project
+// @Description Delete a project without tasks. The default project cannot be deleted.
+// @Tags projects
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   id  path  string  true  "Project ID"
+// @Param   If-Match       header  string  false  "Delete only if the project has one of these ETags"
+// @Param   If-None-Match  header  string  false  "Delete only if the project has none of these ETags"
+// @Success 204 {string} string "No Content"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 409 {object} handlers.Problem "Conflict: the project has tasks or is the default one"
+// @Failure 412 {object} handlers.Problem "Precondition Failed"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/projects/{id} [delete]
+func DeleteProject(w http.ResponseWriter, r *http.Request) {
+	if err := projectService.DeleteProject(r.Context(), chi.URLParam(r, "id"), preconditionFrom(r)); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,78 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/calendar"
+	"github.com/canyouhearthemusic/todo-list/internal/i18n"
+	"github.com/canyouhearthemusic/todo-list/internal/idempotency"
+	"github.com/canyouhearthemusic/todo-list/internal/repositories"
+	"github.com/canyouhearthemusic/todo-list/internal/routes"
+	"github.com/canyouhearthemusic/todo-list/internal/services"
+)
+
+// newServer serves the API, as routes.New wires it, over repo. The handlers
+// share their services package-wide, so tests using it cannot run in
+// parallel.
+func newServer(t *testing.T, repo repositories.TaskRepo) *httptest.Server {
+	t.Helper()
+
+	messages, err := i18n.Load("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(routes.New(routes.Deps{
+		Tasks:       services.New(repo),
+		Projects:    services.NewProjectService(repo),
+		Holidays:    services.NewHolidayService(calendar.New()),
+		Messages:    messages,
+		Idempotency: idempotency.NewStore(time.Hour),
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// do sends a request with body, encoded as JSON unless it is a string, and
+// decodes the response into out unless it is nil.
+func do(t *testing.T, server *httptest.Server, method, path string, body, out any) *http.Response {
+	t.Helper()
+
+	var data []byte
+	switch body := body.(type) {
+	case nil:
+	case string:
+		data = []byte(body)
+	default:
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req, err := http.NewRequest(method, server.URL+path, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decode %d response: %v", method, path, resp.StatusCode, err)
+		}
+	}
+
+	return resp
+}
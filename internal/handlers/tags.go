@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetTags godoc
+// @Summary List tags
+// @Description List the tags in use with the number of tasks carrying each, most used first
+// @Tags tags
+// @Produce  json
+// @Produce  application/problem+json
+// @Success 200 {array} models.Tag
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/tags [get]
+func GetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := service.ListTags(r.Context())
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, tags)
+}
+
+// RenameTag godoc
+// @Summary Rename a tag
+// @Description Rename a tag on every task carrying it. The new name must not be in use yet; merge the tags instead.
+// @Tags tags
+// @Accept  json
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   name  path  string                   true  "Tag"
+// @Param   tag   body  models.TagRenameRequest  true  "New name"
+// @Success 200 {object} models.Tag
+// @Failure 400 {object} handlers.Problem "Bad Request"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 409 {object} handlers.Problem "Conflict: the new name is in use"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/tags/{name} [put]
+func RenameTag(w http.ResponseWriter, r *http.Request) {
+	var req models.TagRenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+
+	tag, err := service.RenameTag(r.Context(), chi.URLParam(r, "name"), req.Name)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, tag)
+}
+
+// MergeTag godoc
+// @Summary Merge a tag into another
+// @Description Replace a tag with another on every task carrying it; tasks carrying both keep one
+// @Tags tags
+// @Accept  json
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   name  path  string                  true  "Tag to merge away"
+// @Param   tag   body  models.TagMergeRequest  true  "Tag to merge into"
+// @Success 200 {object} models.Tag
+// @Failure 400 {object} handlers.Problem "Bad Request"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/tags/{name}/merge [post]
+func MergeTag(w http.ResponseWriter, r *http.Request) {
+	var req models.TagMergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+
+	tag, err := service.MergeTag(r.Context(), chi.URLParam(r, "name"), req.Into)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, tag)
+}
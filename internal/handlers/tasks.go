@@ -2,57 +2,170 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
 
 	"github.com/canyouhearthemusic/todo-list/internal/models"
-	"github.com/canyouhearthemusic/todo-list/internal/repositories"
 	"github.com/canyouhearthemusic/todo-list/internal/services"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
 )
 
 var (
-	service *services.TaskService = services.New(repositories.NewSyncMapTaskRepo())
+	service *services.TaskService
 )
 
+// SetService sets the TaskService used by the task handlers.
+func SetService(s *services.TaskService) {
+	service = s
+}
+
 // GetAllTasks godoc
 // @Summary Get all tasks
 // @Description Get all tasks by status
 // @Tags tasks
 // @Accept  json
 // @Produce  json
-// @Param   status  query  string  false  "Status Filter"  Enum(active,done)
-// @Success 200 {array} models.Task
-// @Failure 500 {string} string "Internal Server Error"
+// @Produce  application/problem+json
+// @Param   project query  string  false  "Only tasks of this project"
+// @Param   status  query  string  false  "Comma-separated workflow statuses, e.g. in_progress,review (defaults to the open ones unless filter is given)"
+// @Param   filter  query  string  false  "Filter expression over id, projectId, title, description, activeAt, createdAt, status, priority and dueAt, e.g. activeAt>=2024-05-01 AND title~report AND priority IN (high,urgent)"
+// @Param   region  query  string  false  "Holiday region, e.g. KZ (defaults to the configured region)"
+// @Param   decorate  query  bool  false  "Prefix weekend and holiday display titles with a label"  default(true)
+// @Param   Accept-Language  header  string  false  "Language of display labels (ru, en, kk)"
+// @Param   parent  query  string  false  "Only subtasks of this task"
+// @Param   overdue query  bool    false  "Only tasks past their dueAt that are not closed"
+// @Param   tagsAny   query  string  false  "Comma-separated tags; only tasks with at least one of them"
+// @Param   tagsAll   query  string  false  "Comma-separated tags; only tasks with all of them"
+// @Param   tagsNone  query  string  false  "Comma-separated tags; only tasks with none of them"
+// @Param   render  query  string  false  "Add descriptionHtml, the description rendered as sanitized HTML"  Enums(html)
+// @Param   sort    query  string  false  "Comma-separated sort fields (activeAt, title, createdAt, status, priority, dueAt); prefix with - for descending"  default(activeAt)
+// @Param   limit   query  int     false  "Page size (max 1000)"  default(100)
+// @Param   offset  query  int     false  "Number of tasks to skip"
+// @Param   cursor  query  string  false  "Opaque cursor from X-Next-Cursor; cannot be combined with offset"
+// @Success 200 {array} models.TaskView
+// @Header  200 {integer} X-Total-Count "Number of tasks matching the filters"
+// @Header  200 {string} X-Next-Cursor "Cursor of the next page, absent on the last page"
+// @Header  200 {string} Link "RFC 8288 first/prev/next/last links"
+// @Failure 400 {object} handlers.Problem "Bad Request"
+// @Failure 404 {object} handlers.Problem "Not Found: no such project"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
 // @Router /api/tasks [get]
 func GetAllTasks(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	status := r.URL.Query().Get("status")
-	switch status {
-	case "", "active":
-		status = "active"
-	case "done":
-		status = "done"
+
+	decorate := true
+	if raw := r.URL.Query().Get("decorate"); raw != "" {
+		var err error
+		if decorate, err = strconv.ParseBool(raw); err != nil {
+			respondWithBadRequest(w, r, fmt.Errorf("invalid decorate value %q", raw))
+			return
+		}
+	}
+
+	var overdue bool
+	if raw := r.URL.Query().Get("overdue"); raw != "" {
+		var err error
+		if overdue, err = strconv.ParseBool(raw); err != nil {
+			respondWithBadRequest(w, r, fmt.Errorf("invalid overdue value %q", raw))
+			return
+		}
+	}
+
+	opts := services.ListOptions{
+		Project:  r.URL.Query().Get("project"),
+		Parent:   r.URL.Query().Get("parent"),
+		Status:   r.URL.Query().Get("status"),
+		Overdue:  overdue,
+		TagsAny:  r.URL.Query().Get("tagsAny"),
+		TagsAll:  r.URL.Query().Get("tagsAll"),
+		TagsNone: r.URL.Query().Get("tagsNone"),
+		Filter:   r.URL.Query().Get("filter"),
+		Region:   r.URL.Query().Get("region"),
+		Decorate: decorate,
+		Render:   r.URL.Query().Get("render"),
+		Sort:     r.URL.Query().Get("sort"),
+		Cursor:   r.URL.Query().Get("cursor"),
 	}
 
-	tasks, err := service.GetAllTasks(ctx, status)
+	var err error
+	if opts.Limit, err = queryInt(r, "limit"); err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+	if opts.Offset, err = queryInt(r, "offset"); err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+	if opts.Cursor != "" && opts.Offset != 0 {
+		respondWithBadRequest(w, r, errors.New("cursor and offset cannot be combined"))
+		return
+	}
+
+	list, err := service.GetAllTasks(ctx, opts)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, tasks)
+	setPaginationHeaders(w, r, opts, list)
+	respondWithJSON(w, http.StatusOK, list.Tasks)
+}
+
+// SearchTasks godoc
+// @Summary Search tasks
+// @Description Full-text search over task titles and descriptions. Every word of the query must match a word in the task, exactly or as its prefix; results are ranked by relevance, with matches in the title counting more.
+// @Tags tasks
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   q      query  string  true   "Search query, e.g. годов отч"
+// @Param   limit  query  int     false  "Maximum number of results (max 100)"  default(20)
+// @Success 200 {array} models.TaskSearchResult
+// @Failure 400 {object} handlers.Problem "Bad Request"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Failure 501 {object} handlers.Problem "Not Implemented: the storage has no full-text index"
+// @Router /api/tasks/search [get]
+func SearchTasks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+
+	results, err := service.SearchTasks(ctx, r.URL.Query().Get("q"), limit)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, results)
 }
 
 // GetTask godoc
 // @Summary Get task by ID
-// @Description Get a task by ID
+// @Description Get a task by ID along with its progress over subtasks and checklist items. With render=html the task also carries descriptionHtml, its Markdown description rendered as sanitized HTML. The ETag versions the task itself, so changes to its subtasks do not change it.
 // @Tags tasks
 // @Accept  json
 // @Produce  json
-// @Param   id   path  string  true  "Task ID"
-// @Success 200 {object} models.Task
-// @Failure 404 {string} string "Not Found"
+// @Produce  application/problem+json
+// @Param   id      path   string  true   "Task ID"
+// @Param   render  query  string  false  "Also render the description"  Enums(html)
+// @Param   If-None-Match  header  string  false  "Answer 304 if the task still has one of these ETags"
+// @Success 200 {object} models.TaskDetail
+// @Header  200 {string} ETag "Version of the task"
+// @Success 304 {string} string "Not Modified"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
 // @Router /api/tasks/{id} [get]
 func GetTask(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -60,59 +173,88 @@ func GetTask(w http.ResponseWriter, r *http.Request) {
 
 	task, err := service.GetTask(ctx, id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		respondWithError(w, r, err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, task)
+	detail, err := service.TaskDetail(ctx, task, r.URL.Query().Get("render"))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", task.ETag())
+	if preconditionFrom(r).NotModified(task) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, detail)
 }
 
 // PostTask godoc
 // @Summary Create a new task
-// @Description Create a new task
+// @Description Create a new task, in the default project unless projectId names another. A parentId makes it a subtask of that task, within the configured nesting depth. A recurrence rule (RFC 5545 RRULE of dates, e.g. FREQ=WEEKLY;BYDAY=MO) makes it repeat, starting at activeAt. Retries sent with the same Idempotency-Key and body get the first response back instead of creating the task again.
 // @Tags tasks
 // @Accept  json
 // @Produce  json
+// @Produce  application/problem+json
 // @Param   task  body  models.TaskRequest  true  "Task"
+// @Param   Idempotency-Key  header  string  false  "Unique key of this request, e.g. a UUID, kept for the configured TTL"
 // @Success 201 {object} models.Task
-// @Failure 400 {string} string "Bad Request"
-// @Failure 500 {string} string "Internal Server Error"
+// @Header  201 {string} ETag "Version of the task"
+// @Header  201 {string} Idempotent-Replayed "true if this is the stored response of an earlier request"
+// @Failure 400 {object} handlers.Problem "Bad Request"
+// @Failure 404 {object} handlers.Problem "Not Found: no such project"
+// @Failure 409 {object} handlers.Problem "Conflict (also while a request with the same Idempotency-Key is in progress)"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity (also when the Idempotency-Key was used with a different body)"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
 // @Router /api/tasks [post]
 func PostTask(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var task models.Task
 
 	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		respondWithBadRequest(w, r, err)
 		return
 	}
 
 	if err := task.Validate(); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		respondWithError(w, r, err)
 		return
 	}
 
 	task.ID = uuid.New().String()
 
 	if err := service.PostTask(ctx, &task); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondWithError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", task.ETag())
 	respondWithJSON(w, http.StatusCreated, task)
 }
 
 // PutTask godoc
 // @Summary Update a task
-// @Description Update a task by ID
+// @Description Update a task by ID. Without projectId the task stays in its project, and without parentId under its parent; its checklist and blockers are left as they are. Keeping the recurrence rule keeps the task in its series, a new rule starts a series at activeAt, and leaving it out stops the task from repeating.
 // @Tags tasks
 // @Accept  json
 // @Produce  json
+// @Produce  application/problem+json
 // @Param   id    path  string       true  "Task ID"
 // @Param   task  body  models.TaskRequest  true  "Task"
+// @Param   If-Match       header  string  false  "Update only if the task has one of these ETags"
+// @Param   If-None-Match  header  string  false  "Update only if the task has none of these ETags"
 // @Success 204 {string} string "No Content"
-// @Failure 400 {string} string "Bad Request"
-// @Failure 404 {string} string "Not Found"
+// @Header  204 {string} ETag "New version of the task"
+// @Header  204 {string} Warning "Set when the task was marked done while blocked by unfinished tasks"
+// @Failure 400 {object} handlers.Problem "Bad Request"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 409 {object} handlers.Problem "Conflict"
+// @Failure 412 {object} handlers.Problem "Precondition Failed"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
 // @Router /api/tasks/{id} [put]
 func PutTask(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -121,39 +263,118 @@ func PutTask(w http.ResponseWriter, r *http.Request) {
 	var updatedTask models.Task
 
 	if err := json.NewDecoder(r.Body).Decode(&updatedTask); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		respondWithBadRequest(w, r, err)
 		return
 	}
 
 	if err := updatedTask.Validate(); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		respondWithError(w, r, err)
 		return
 	}
 
-	if err := service.PutTask(ctx, id, &updatedTask); err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+	if err := service.PutTask(ctx, id, &updatedTask, preconditionFrom(r)); err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", updatedTask.ETag())
+	warnUnfinishedBlockers(w, r, &updatedTask)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Media types accepted by PatchTask.
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// maxPatchSize bounds the body of a PATCH request.
+const maxPatchSize = 1 << 20
+
+// PatchTask godoc
+// @Summary Patch a task
+// @Description Change some of a task's title, activeAt, projectId, parentId, status, description, tags, priority, dueAt and recurrence with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902), e.g. {"status":"active"} or [{"op":"replace","path":"/title","value":"New title"}]. The patched task is validated like a PUT.
+// @Tags tasks
+// @Accept  application/merge-patch+json
+// @Accept  application/json-patch+json
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   id     path  string            true  "Task ID"
+// @Param   patch  body  models.TaskPatch  true  "Merge patch over these fields, or a JSON Patch array"
+// @Param   If-Match       header  string  false  "Patch only if the task has one of these ETags"
+// @Param   If-None-Match  header  string  false  "Patch only if the task has none of these ETags"
+// @Success 200 {object} models.Task
+// @Header  200 {string} ETag "New version of the task"
+// @Header  200 {string} Warning "Set when the task was marked done while blocked by unfinished tasks"
+// @Failure 400 {object} handlers.Problem "Bad Request"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 409 {object} handlers.Problem "Conflict, including a failed JSON Patch test"
+// @Failure 412 {object} handlers.Problem "Precondition Failed"
+// @Failure 415 {object} handlers.Problem "Unsupported Media Type"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/tasks/{id} [patch]
+func PatchTask(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	var format services.PatchFormat
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case mergePatchContentType:
+		format = services.MergePatch
+	case jsonPatchContentType:
+		format = services.JSONPatch
+	default:
+		w.Header().Set("Accept-Patch", mergePatchContentType+", "+jsonPatchContentType)
+		writeProblem(w, newProblem(r, http.StatusUnsupportedMediaType, ProblemUnsupportedMedia,
+			fmt.Sprintf("Content-Type must be %s or %s", mergePatchContentType, jsonPatchContentType)))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+	if err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+	if !json.Valid(body) {
+		respondWithBadRequest(w, r, errors.New("patch is not valid JSON"))
+		return
+	}
+
+	task, err := service.PatchTask(ctx, id, format, body, preconditionFrom(r))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", task.ETag())
+	warnUnfinishedBlockers(w, r, task)
+	respondWithJSON(w, http.StatusOK, task)
+}
+
 // DeleteTask godoc
 // @Summary Delete a task
-// @Description Delete a task by ID
+// @Description Delete a task by ID; a task with subtasks cannot be deleted
 // @Tags tasks
 // @Accept  json
 // @Produce  json
+// @Produce  application/problem+json
 // @Param   id  path  string  true  "Task ID"
+// @Param   If-Match       header  string  false  "Delete only if the task has one of these ETags"
+// @Param   If-None-Match  header  string  false  "Delete only if the task has none of these ETags"
 // @Success 204 {string} string "No Content"
-// @Failure 404 {string} string "Not Found"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 409 {object} handlers.Problem "Conflict: the task has subtasks"
+// @Failure 412 {object} handlers.Problem "Precondition Failed"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
 // @Router /api/tasks/{id} [delete]
 func DeleteTask(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
 
-	if err := service.DeleteTask(ctx, id); err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+	if err := service.DeleteTask(ctx, id, preconditionFrom(r)); err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -162,29 +383,155 @@ func DeleteTask(w http.ResponseWriter, r *http.Request) {
 
 // DoneTask godoc
 // @Summary Mark task as done
-// @Description Update a task status by ID
+// @Description Move a task to the done status, if the workflow allows it from its current one. A recurring task gets its activeAt appended to its title and stops repeating, and its next occurrence is created under its title, moved off weekends and holidays as configured. If parent auto-completion is configured, the parent follows once all of its subtasks are closed.
 // @Tags tasks
+// @Produce  json
+// @Produce  application/problem+json
 // @Param   id    path  string       true  "Task ID"
-// @Success 200 {string} string "OK"
-// @Failure 400 {string} string "Bad Request"
-// @Failure 404 {string} string "Not Found"
+// @Param   If-Match       header  string  false  "Mark done only if the task has one of these ETags"
+// @Param   If-None-Match  header  string  false  "Mark done only if the task has none of these ETags"
+// @Param   X-Actor        header  string  false  "Who makes the change, recorded as statusChangedBy"
+// @Success 200 {object} models.Task
+// @Header  200 {string} ETag "New version of the task"
+// @Header  200 {string} Warning "Set when the task was marked done while blocked by unfinished tasks"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 409 {object} handlers.Problem "Conflict: transition not allowed, or unfinished blockers"
+// @Failure 412 {object} handlers.Problem "Precondition Failed"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
 // @Router /api/tasks/{id}/done [put]
 func DoneTask(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
 
-	if err := service.DoneTask(ctx, id); err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+	task, err := service.DoneTask(ctx, id, preconditionFrom(r))
+	if err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("ETag", task.ETag())
+	warnUnfinishedBlockers(w, r, task)
+	respondWithJSON(w, http.StatusOK, task)
+}
+
+// ReopenTask godoc
+// @Summary Reopen a task
+// @Description Move a task back to the initial status, if the workflow allows it from its current one
+// @Tags tasks
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   id    path  string       true  "Task ID"
+// @Param   If-Match       header  string  false  "Reopen only if the task has one of these ETags"
+// @Param   If-None-Match  header  string  false  "Reopen only if the task has none of these ETags"
+// @Param   X-Actor        header  string  false  "Who makes the change, recorded as statusChangedBy"
+// @Success 200 {object} models.Task
+// @Header  200 {string} ETag "New version of the task"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 409 {object} handlers.Problem "Conflict: transition not allowed"
+// @Failure 412 {object} handlers.Problem "Precondition Failed"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/tasks/{id}/reopen [put]
+func ReopenTask(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	task, err := service.ReopenTask(ctx, id, preconditionFrom(r))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", task.ETag())
+	respondWithJSON(w, http.StatusOK, task)
+}
+
+// MoveTask godoc
+// @Summary Move a task to another project
+// @Description Move a task to another project, where its title must not be taken yet
+// @Tags tasks
+// @Accept  json
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   id       path  string              true  "Task ID"
+// @Param   project  body  models.MoveRequest  true  "Target project"
+// @Param   If-Match       header  string  false  "Move only if the task has one of these ETags"
+// @Param   If-None-Match  header  string  false  "Move only if the task has none of these ETags"
+// @Success 200 {object} models.Task
+// @Header  200 {string} ETag "New version of the task"
+// @Failure 400 {object} handlers.Problem "Bad Request"
+// @Failure 404 {object} handlers.Problem "Not Found: no such task or project"
+// @Failure 409 {object} handlers.Problem "Conflict: the title is taken in the target project"
+// @Failure 412 {object} handlers.Problem "Precondition Failed"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/tasks/{id}/project [put]
+func MoveTask(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	var req models.MoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+
+	task, err := service.MoveTask(ctx, id, req.ProjectID, preconditionFrom(r))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", task.ETag())
+	respondWithJSON(w, http.StatusOK, task)
+}
+
+// PutTaskStatus godoc
+// @Summary Change the status of a task
+// @Description Move a task to another status, if the workflow allows the transition from its current one
+// @Tags tasks
+// @Accept  json
+// @Produce  json
+// @Produce  application/problem+json
+// @Param   id      path  string                true  "Task ID"
+// @Param   status  body  models.StatusRequest  true  "New status"
+// @Param   If-Match       header  string  false  "Change only if the task has one of these ETags"
+// @Param   If-None-Match  header  string  false  "Change only if the task has none of these ETags"
+// @Param   X-Actor        header  string  false  "Who makes the change, recorded as statusChangedBy"
+// @Success 200 {object} models.Task
+// @Header  200 {string} ETag "New version of the task"
+// @Header  200 {string} Warning "Set when the task was marked done while blocked by unfinished tasks"
+// @Failure 400 {object} handlers.Problem "Bad Request"
+// @Failure 404 {object} handlers.Problem "Not Found"
+// @Failure 409 {object} handlers.Problem "Conflict: transition not allowed, or unfinished blockers"
+// @Failure 412 {object} handlers.Problem "Precondition Failed"
+// @Failure 422 {object} handlers.Problem "Unprocessable Entity"
+// @Failure 500 {object} handlers.Problem "Internal Server Error"
+// @Router /api/tasks/{id}/status [put]
+func PutTaskStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	var req models.StatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithBadRequest(w, r, err)
+		return
+	}
+
+	task, err := service.TransitionTask(ctx, id, req.Status, preconditionFrom(r))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", task.ETag())
+	warnUnfinishedBlockers(w, r, task)
+	respondWithJSON(w, http.StatusOK, task)
 }
 
 func respondWithJSON(w http.ResponseWriter, status int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(payload); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Errorf("write response: %v", err)
 	}
 }
@@ -0,0 +1,163 @@
+// Package i18n holds the message catalogs and picks a language per request.
+//
+// Catalogs live in locales/<lang>.yaml as flat key: message maps and are
+// embedded into the binary. Adding a language is adding a file.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Message keys.
+const (
+	LabelWeekend = "label.weekend"
+	LabelHoliday = "label.holiday"
+)
+
+//go:embed locales/*.yaml
+var locales embed.FS
+
+type Bundle struct {
+	fallback string
+	messages map[string]map[string]string
+}
+
+// Load reads the embedded catalogs. fallback is used when a request asks for
+// nothing we support, and for keys missing from the chosen catalog.
+func Load(fallback string) (*Bundle, error) {
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{
+		fallback: fallback,
+		messages: make(map[string]map[string]string),
+	}
+
+	for _, f := range files {
+		data, err := locales.ReadFile(path.Join("locales", f.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		catalog := make(map[string]string)
+		if err := yaml.Unmarshal(data, &catalog); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", f.Name(), err)
+		}
+
+		b.messages[strings.TrimSuffix(f.Name(), path.Ext(f.Name()))] = catalog
+	}
+
+	if _, ok := b.messages[fallback]; !ok {
+		return nil, fmt.Errorf("no catalog for default language %q", fallback)
+	}
+
+	return b, nil
+}
+
+// Languages returns the supported language tags, sorted.
+func (b *Bundle) Languages() []string {
+	langs := make([]string, 0, len(b.messages))
+	for lang := range b.messages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	return langs
+}
+
+// T returns the message for key in lang, falling back to the default
+// language and finally to the key itself.
+func (b *Bundle) T(lang, key string) string {
+	if msg, ok := b.messages[lang][key]; ok {
+		return msg
+	}
+
+	if msg, ok := b.messages[b.fallback][key]; ok {
+		return msg
+	}
+
+	return key
+}
+
+// Match picks the supported language that best satisfies an Accept-Language
+// header, honouring q-values. Region subtags match their base language
+// ("en-GB" selects "en").
+func (b *Bundle) Match(acceptLanguage string) string {
+	best, bestQ := b.fallback, 0.0
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, q := parseLanguageRange(part)
+		if tag == "" || q <= bestQ {
+			continue
+		}
+
+		if tag == "*" {
+			best, bestQ = b.fallback, q
+			continue
+		}
+
+		base, _, _ := strings.Cut(tag, "-")
+		if _, ok := b.messages[base]; ok {
+			best, bestQ = base, q
+		}
+	}
+
+	return best
+}
+
+// Middleware stores the negotiated language in the request context and
+// announces it in Content-Language.
+func (b *Bundle) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := b.Match(r.Header.Get("Accept-Language"))
+
+		w.Header().Set("Content-Language", lang)
+		w.Header().Add("Vary", "Accept-Language")
+
+		next.ServeHTTP(w, r.WithContext(WithLanguage(r.Context(), lang)))
+	})
+}
+
+func parseLanguageRange(s string) (string, float64) {
+	tag, params, _ := strings.Cut(strings.TrimSpace(s), ";")
+	tag = strings.ToLower(strings.TrimSpace(tag))
+
+	q := 1.0
+	for _, param := range strings.Split(params, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || name != "q" {
+			continue
+		}
+
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", 0
+		}
+		q = parsed
+	}
+
+	return tag, q
+}
+
+type contextKey struct{}
+
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, contextKey{}, lang)
+}
+
+// LanguageFrom returns the language stored by Middleware, or "" if none.
+func LanguageFrom(ctx context.Context) string {
+	lang, _ := ctx.Value(contextKey{}).(string)
+	return lang
+}
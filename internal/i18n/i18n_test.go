@@ -0,0 +1,33 @@
+package i18n_test
+
+import (
+	"testing"
+
+	"github.com/canyouhearthemusic/todo-list/internal/i18n"
+)
+
+func TestMatch(t *testing.T) {
+	b, err := i18n.Load("ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "ru"},
+		{"en", "en"},
+		{"en-GB,en;q=0.9", "en"},
+		{"de-DE,kk;q=0.8,en;q=0.5", "kk"},
+		{"fr, *;q=0.1", "ru"},
+		{"en;q=0.2, kk;q=0.9", "kk"},
+		{"de", "ru"},
+	}
+
+	for _, tt := range tests {
+		if got := b.Match(tt.header); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package idempotency remembers the responses to requests made with an
+// Idempotency-Key, so that a retried request is answered without running
+// it again.
+//
+// Keys are kept in memory: a restart forgets them, after which a retry
+// falls back to the checks of the endpoint itself (e.g. duplicate titles).
+package idempotency
+
+import (
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTTL is how long a response is kept when no TTL is configured.
+	DefaultTTL = 24 * time.Hour
+	// sweepInterval bounds how often expired entries are looked for.
+	sweepInterval = time.Minute
+)
+
+var (
+	// ErrKeyReused means the key was first used for a different request.
+	ErrKeyReused = errors.New("idempotency key was already used for a different request")
+	// ErrInProgress means the first request with the key has not finished.
+	ErrInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+// Fingerprint identifies a request, so that a key sent again with another
+// method, path or body is told apart from a retry.
+type Fingerprint [sha256.Size]byte
+
+// NewFingerprint hashes the method, path and body of a request.
+func NewFingerprint(method, path string, body []byte) Fingerprint {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+
+	var f Fingerprint
+	copy(f[:], h.Sum(nil))
+
+	return f
+}
+
+// Response is a stored response, replayed as is.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+type entry struct {
+	fingerprint Fingerprint
+	// response is nil while the first request is in progress.
+	response *Response
+	expires  time.Time
+}
+
+// Store keeps responses by key for a fixed TTL. It is safe for concurrent
+// use.
+type Store struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+type Option func(*Store)
+
+// WithClock makes the store read the time from now instead of time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(s *Store) {
+		s.now = now
+	}
+}
+
+// NewStore returns a store that keeps responses for ttl, or for DefaultTTL
+// if ttl is not positive.
+func NewStore(ttl time.Duration, opts ...Option) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	s := &Store{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*entry),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Begin looks up key. If a response was stored for the same fingerprint it
+// is returned and the request must not run again. Otherwise the key is
+// reserved for this request, which must end with Complete or Release.
+//
+// Begin fails with ErrKeyReused if the key belongs to another fingerprint
+// and with ErrInProgress if its first request is still running.
+func (s *Store) Begin(key string, f Fingerprint) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		switch {
+		case e.fingerprint != f:
+			return nil, ErrKeyReused
+		case e.response == nil:
+			return nil, ErrInProgress
+		}
+		return e.response, nil
+	}
+
+	s.entries[key] = &entry{fingerprint: f, expires: now.Add(s.ttl)}
+
+	return nil, nil
+}
+
+// Complete stores resp for the key reserved by Begin. The TTL counts from
+// now.
+func (s *Store) Complete(key string, resp *Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.response = resp
+		e.expires = s.now().Add(s.ttl)
+	}
+}
+
+// Release frees the key reserved by Begin without storing a response, so
+// that a retry runs the request again.
+func (s *Store) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok && e.response == nil {
+		delete(s.entries, key)
+	}
+}
+
+// sweep drops the entries past their TTL, at most once per sweepInterval.
+// Callers hold s.mu.
+func (s *Store) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
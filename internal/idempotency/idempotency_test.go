@@ -0,0 +1,71 @@
+package idempotency_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/idempotency"
+)
+
+func TestStore(t *testing.T) {
+	now := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	store := idempotency.NewStore(time.Hour, idempotency.WithClock(func() time.Time { return now }))
+
+	create := idempotency.NewFingerprint(http.MethodPost, "/api/tasks", []byte(`{"title":"a"}`))
+	other := idempotency.NewFingerprint(http.MethodPost, "/api/tasks", []byte(`{"title":"b"}`))
+
+	if resp, err := store.Begin("k", create); resp != nil || err != nil {
+		t.Fatalf("first Begin = %v, %v; want the key reserved", resp, err)
+	}
+
+	if _, err := store.Begin("k", create); !errors.Is(err, idempotency.ErrInProgress) {
+		t.Errorf("Begin while in progress: err = %v, want ErrInProgress", err)
+	}
+
+	want := &idempotency.Response{Status: http.StatusCreated, Body: []byte(`{"id":"1"}`)}
+	store.Complete("k", want)
+
+	if resp, err := store.Begin("k", create); err != nil || resp != want {
+		t.Errorf("retry Begin = %v, %v; want the stored response", resp, err)
+	}
+
+	if _, err := store.Begin("k", other); !errors.Is(err, idempotency.ErrKeyReused) {
+		t.Errorf("Begin with another body: err = %v, want ErrKeyReused", err)
+	}
+
+	now = now.Add(time.Hour)
+	if resp, err := store.Begin("k", other); resp != nil || err != nil {
+		t.Errorf("Begin after the TTL = %v, %v; want the key reserved again", resp, err)
+	}
+}
+
+func TestStoreRelease(t *testing.T) {
+	store := idempotency.NewStore(time.Hour)
+	f := idempotency.NewFingerprint(http.MethodPost, "/api/tasks", nil)
+
+	if _, err := store.Begin("k", f); err != nil {
+		t.Fatal(err)
+	}
+	store.Release("k")
+
+	if resp, err := store.Begin("k", f); resp != nil || err != nil {
+		t.Errorf("Begin after Release = %v, %v; want the key reserved again", resp, err)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	body := []byte(`{"title":"a"}`)
+	f := idempotency.NewFingerprint(http.MethodPost, "/api/tasks", body)
+
+	if idempotency.NewFingerprint(http.MethodPost, "/api/tasks", body) != f {
+		t.Error("same request gave different fingerprints")
+	}
+	if idempotency.NewFingerprint(http.MethodPut, "/api/tasks", body) == f {
+		t.Error("method is not part of the fingerprint")
+	}
+	if idempotency.NewFingerprint(http.MethodPost, "/api/other", body) == f {
+		t.Error("path is not part of the fingerprint")
+	}
+}
@@ -0,0 +1,118 @@
+// Package markdown renders CommonMark to HTML that is safe to embed in a
+// page.
+//
+// Markdown is rendered by goldmark, with raw HTML in the source escaped
+// rather than passed through and images rendered as links, so that viewing
+// a description fetches nothing. The result is then sanitized by bluemonday:
+// only the tags CommonMark produces are kept, link targets other than
+// relative, http, https and mailto URLs are dropped, and links carry
+// rel="nofollow noreferrer".
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	md = goldmark.New(goldmark.WithRendererOptions(
+		renderer.WithNodeRenderers(util.Prioritized(inertRenderer{}, 500)),
+	))
+	policy = newPolicy()
+)
+
+// HTML renders src.
+func HTML(src string) string {
+	var b bytes.Buffer
+	if err := md.Convert([]byte(src), &b); err != nil {
+		// Rendering into a buffer does not fail.
+		panic(err)
+	}
+
+	return policy.Sanitize(b.String())
+}
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+
+	p.AllowElements("p", "br", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote",
+		"ul", "ol", "li", "pre", "code", "hr", "em", "strong")
+	p.AllowAttrs("start").Matching(regexp.MustCompile(`^[0-9]{1,9}$`)).OnElements("ol")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.AllowRelativeURLs(true)
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.RequireNoReferrerOnLinks(true)
+
+	return p
+}
+
+// inertRenderer overrides goldmark's rendering of the nodes that could
+// make a page load or run something: raw HTML is written as escaped text
+// and images as links to them.
+type inertRenderer struct{}
+
+func (inertRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindRawHTML, renderRawHTML)
+	reg.Register(ast.KindHTMLBlock, renderHTMLBlock)
+	reg.Register(ast.KindImage, renderImage)
+}
+
+func renderRawHTML(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		segments := node.(*ast.RawHTML).Segments
+		for i := 0; i < segments.Len(); i++ {
+			segment := segments.At(i)
+			_, _ = w.Write(util.EscapeHTML(segment.Value(source)))
+		}
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+func renderHTMLBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.HTMLBlock)
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	var text []byte
+	for i := 0; i < n.Lines().Len(); i++ {
+		line := n.Lines().At(i)
+		text = append(text, line.Value(source)...)
+	}
+	if n.HasClosure() {
+		text = append(text, n.ClosureLine.Value(source)...)
+	}
+
+	_, _ = w.WriteString("<p>")
+	_, _ = w.Write(util.EscapeHTML(bytes.TrimRight(text, "\n")))
+	_, _ = w.WriteString("</p>\n")
+
+	return ast.WalkContinue, nil
+}
+
+func renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = w.WriteString("</a>")
+		return ast.WalkContinue, nil
+	}
+
+	dest := util.URLEscape(node.(*ast.Image).Destination, true)
+	_, _ = w.WriteString(`<a href="`)
+	if !gmhtml.IsDangerousURL(dest) {
+		_, _ = w.Write(util.EscapeHTML(dest))
+	}
+	_, _ = w.WriteString(`">`)
+
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,140 @@
+package markdown_test
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/canyouhearthemusic/todo-list/internal/markdown"
+	"golang.org/x/net/html"
+)
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"paragraphs", "one\ntwo\n\nthree", "<p>one\ntwo</p>\n<p>three</p>\n"},
+		{"hard break", "one  \ntwo\\\nthree", "<p>one<br>\ntwo<br>\nthree</p>\n"},
+		{"heading", "## Plan ##\ntext", "<h2>Plan</h2>\n<p>text</p>\n"},
+		{"not a heading", "#hashtag", "<p>#hashtag</p>\n"},
+		{"emphasis", "*a **b** c* and _d_", "<p><em>a <strong>b</strong> c</em> and <em>d</em></p>\n"},
+		{"intraword underscore", "snake_case_name", "<p>snake_case_name</p>\n"},
+		{"escapes", `\*not em\* 2 \< 3`, "<p>*not em* 2 &lt; 3</p>\n"},
+		{"code span", "run `a <b> **c**` now", "<p>run <code>a &lt;b&gt; **c**</code> now</p>\n"},
+		{"fenced code", "```go\nx := \"<y>\"\n```\nafter", "<pre><code class=\"language-go\">x := &#34;&lt;y&gt;&#34;\n</code></pre>\n<p>after</p>\n"},
+		{"quote", "> quoted\n> *text*", "<blockquote>\n<p>quoted\n<em>text</em></p>\n</blockquote>\n"},
+		{"rule", "a\n\n* * *\nb", "<p>a</p>\n<hr>\n<p>b</p>\n"},
+		{
+			"nested list",
+			"- one\n- two\n  1. a\n  2. b\n- three",
+			"<ul>\n<li>one</li>\n<li>two\n<ol>\n<li>a</li>\n<li>b</li>\n</ol>\n</li>\n<li>three</li>\n</ul>\n",
+		},
+		{"ordered start", "3. c\n4. d", "<ol start=\"3\">\n<li>c</li>\n<li>d</li>\n</ol>\n"},
+		{"link", "[docs](https://example.com/a?b=1&c=(2))", `<p><a href="https://example.com/a?b=1&amp;c=(2)" rel="nofollow noreferrer">docs</a></p>` + "\n"},
+		{"relative link", "[task](/api/tasks/1)", `<p><a href="/api/tasks/1" rel="nofollow noreferrer">task</a></p>` + "\n"},
+		{"image", "![chart](https://example.com/c.png)", `<p><a href="https://example.com/c.png" rel="nofollow noreferrer">chart</a></p>` + "\n"},
+		{"autolink", "<mailto:a@example.com>", `<p><a href="mailto:a@example.com" rel="nofollow noreferrer">mailto:a@example.com</a></p>` + "\n"},
+		{"unclosed", "*a `b [c", "<p>*a `b [c</p>\n"},
+	}
+
+	for _, tt := range tests {
+		if got := markdown.HTML(tt.src); got != tt.want {
+			t.Errorf("%s: HTML(%q)\n got %q\nwant %q", tt.name, tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestHTMLIsSanitized(t *testing.T) {
+	attacks := []string{
+		`<script>alert(1)</script>`,
+		`<img src=x onerror=alert(1)>`,
+		`[x](javascript:alert(1))`,
+		`[x](JavaScript:alert(1))`,
+		"[x](java\tscript:alert(1))",
+		`[x](data:text/html;base64,PHNjcmlwdD4=)`,
+		`![x](vbscript:msgbox)`,
+		`<javascript:alert(1)>`,
+		`[x](https://example.com/" onclick="alert(1))`,
+		"```\"><script>\nalert(1)\n```",
+		"``` x\"onload=alert(1)\ncode\n```",
+		// Entity- and percent-encoded schemes.
+		`[x](&#106;avascript:alert(1))`,
+		`[x](&#x6A;&#x61;&#x76;&#x61;&#x73;&#x63;&#x72;&#x69;&#x70;&#x74;:alert(1))`,
+		`[x](javascript&#58;alert(1))`,
+		`[x](javascript&colon;alert(1))`,
+		`[x](%6Aavascript:alert(1))`,
+		`[x]( javascript:alert(1))`,
+		`[x](<javascript:alert(1)>)`,
+		"[x]: javascript:alert(1)\n\n[x]",
+		// Nested and unbalanced emphasis and links.
+		`[a [b](javascript:alert(1)) c](https://example.com)`,
+		`[[x](javascript:alert(1))](https://example.com)`,
+		`**[x](javascript:alert(1)**)`,
+		`*[x*](javascript:alert(1))`,
+		`[x](https://example.com "title" onclick="alert(1)")`,
+		// Raw HTML in link text and titles.
+		`[<img src=x onerror=alert(1)>](https://example.com)`,
+		`[<script>alert(1)</script>](https://example.com)`,
+		`[x](https://example.com "<script>alert(1)</script>")`,
+		`<a href="javascript:alert(1)">x</a>`,
+		`<a href="https://example.com" onclick="alert(1)">x</a>`,
+		// Attribute breakouts.
+		`[x](https://example.com/"onmouseover="alert(1))`,
+		`[x](https://example.com/'onmouseover='alert(1))`,
+		"[x](<https://example.com/\" onmouseover=\"alert(1)>)",
+		`![x" onerror="alert(1)](https://example.com/c.png)`,
+		"```go\" onmouseover=\"alert(1)\ncode\n```",
+		"<https://example.com/\"onmouseover=\"alert(1)>",
+	}
+
+	for _, src := range attacks {
+		got := markdown.HTML(src)
+		if problem := unsafeMarkup(got); problem != "" {
+			t.Errorf("HTML(%q) = %q: %s", src, got, problem)
+		}
+	}
+}
+
+// unsafeMarkup tokenizes rendered HTML the way a browser would and reports
+// the first tag, attribute or link target that should not be there.
+func unsafeMarkup(rendered string) string {
+	tags := map[string][]string{
+		"a": {"href", "rel"}, "ol": {"start"}, "code": {"class"},
+		"p": nil, "br": nil, "h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+		"blockquote": nil, "ul": nil, "li": nil, "pre": nil, "hr": nil, "em": nil, "strong": nil,
+	}
+
+	z := html.NewTokenizer(strings.NewReader(rendered))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			allowed, ok := tags[tok.Data]
+			if !ok {
+				return "tag " + tok.Data
+			}
+			for _, attr := range tok.Attr {
+				if !slices.Contains(allowed, attr.Key) {
+					return "attribute " + attr.Key + " on " + tok.Data
+				}
+				if attr.Key != "href" {
+					continue
+				}
+				u, err := url.Parse(attr.Val)
+				if err != nil {
+					return "unparseable href " + attr.Val
+				}
+				switch strings.ToLower(u.Scheme) {
+				case "", "http", "https", "mailto":
+				default:
+					return "href " + attr.Val
+				}
+			}
+		}
+	}
+}
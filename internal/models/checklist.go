@@ -0,0 +1,55 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MaxChecklistItems caps the checklist of a task.
+	MaxChecklistItems = 100
+	// MaxChecklistItemLength caps the text of a checklist item, in
+	// characters.
+	MaxChecklistItemLength = 200
+	// DefaultMaxTaskDepth is how deep tasks nest unless configured
+	// otherwise: top-level tasks are at depth 1, their subtasks at 2.
+	DefaultMaxTaskDepth = 3
+)
+
+// ChecklistItem is a step of a task too small to be a subtask.
+type ChecklistItem struct {
+	ID   string `json:"id" example:"3f1c2a"`
+	Text string `json:"text" example:"Book the room"`
+	Done bool   `json:"done"`
+}
+
+// ChecklistItemRequest adds or changes a checklist item.
+type ChecklistItemRequest struct {
+	Text string `json:"text" example:"Book the room"`
+	Done bool   `json:"done"`
+}
+
+// Progress sums up how far a task is: its subtasks, not counting those
+// closed without being done, and its checklist items.
+type Progress struct {
+	SubtasksDone   int `json:"subtasksDone" example:"1"`
+	SubtasksTotal  int `json:"subtasksTotal" example:"2"`
+	ChecklistDone  int `json:"checklistDone" example:"3"`
+	ChecklistTotal int `json:"checklistTotal" example:"4"`
+	// Percent is the share of subtasks and items done, rounded down.
+	Percent int `json:"percent" example:"66"`
+}
+
+func (item *ChecklistItem) Validate() error {
+	verr := &ValidationError{}
+
+	switch text := strings.TrimSpace(item.Text); {
+	case text == "":
+		verr.Add("text", "text mustn't be empty")
+	case utf8.RuneCountInString(text) > MaxChecklistItemLength:
+		verr.Add("text", fmt.Sprintf("text exceeds %d characters", MaxChecklistItemLength))
+	}
+
+	return verr.Err()
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrValidation = errors.New("validation failed")
+	// ErrTaskNotFound is an ErrNotFound.
+	ErrTaskNotFound = fmt.Errorf("task %w", ErrNotFound)
+	// ErrChecklistItemNotFound is an ErrNotFound.
+	ErrChecklistItemNotFound = fmt.Errorf("checklist item %w", ErrNotFound)
+	// ErrBlockerNotFound is an ErrNotFound: the task is not blocked by the
+	// named one.
+	ErrBlockerNotFound = fmt.Errorf("blocker %w", ErrNotFound)
+	// ErrTagNotFound is an ErrNotFound.
+	ErrTagNotFound = fmt.Errorf("tag %w", ErrNotFound)
+	// ErrParentNotFound is an ErrNotFound: the parent of a task must exist.
+	ErrParentNotFound = fmt.Errorf("parent task %w", ErrNotFound)
+	// ErrProjectNotFound is an ErrNotFound.
+	ErrProjectNotFound = fmt.Errorf("project %w", ErrNotFound)
+	// ErrConflict means the request is valid but clashes with the current
+	// state of the stored data.
+	ErrConflict = errors.New("conflict")
+	// ErrDuplicateTitle is an ErrConflict.
+	ErrDuplicateTitle = fmt.Errorf("%w: task with the same title already exists in the project", ErrConflict)
+	// ErrDuplicateProjectName is an ErrConflict.
+	ErrDuplicateProjectName = fmt.Errorf("%w: project with the same name already exists", ErrConflict)
+	// ErrProjectNotEmpty is an ErrConflict: only projects without tasks can
+	// be deleted.
+	ErrProjectNotEmpty = fmt.Errorf("%w: project still has tasks", ErrConflict)
+	// ErrDefaultProject is an ErrConflict: the default project cannot be
+	// deleted.
+	ErrDefaultProject = fmt.Errorf("%w: the default project cannot be deleted", ErrConflict)
+	// ErrHasSubtasks is an ErrConflict: a task with subtasks cannot be
+	// deleted.
+	ErrHasSubtasks = fmt.Errorf("%w: task has subtasks", ErrConflict)
+	// ErrBlocked is an ErrConflict: a task cannot be done while tasks it is
+	// blocked by are not finished.
+	ErrBlocked = fmt.Errorf("%w: task is blocked by unfinished tasks", ErrConflict)
+	// ErrTagExists is an ErrConflict: renaming a tag to one in use would
+	// merge them, which must be asked for explicitly.
+	ErrTagExists = fmt.Errorf("%w: tag already exists, merge it instead", ErrConflict)
+	// ErrTransitionNotAllowed is an ErrConflict: the workflow does not allow
+	// the task to move to the requested status from its current one.
+	ErrTransitionNotAllowed = fmt.Errorf("%w: status transition not allowed", ErrConflict)
+	// ErrPreconditionFailed means a conditional request (If-Match,
+	// If-None-Match) does not hold for the stored data.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrVersionMismatch is an ErrPreconditionFailed: the task or project
+	// changed since the version the caller based its write on.
+	ErrVersionMismatch = fmt.Errorf("%w: modified concurrently", ErrPreconditionFailed)
+	// ErrNotImplemented means the request asks for something this server,
+	// as configured, does not provide.
+	ErrNotImplemented = errors.New("not implemented")
+	// ErrBatchAborted is reported for the operations of an all-or-nothing
+	// batch that were not applied because another one failed.
+	ErrBatchAborted = errors.New("not applied: another operation of the batch failed")
+)
+
+// BlockerCycleError is an ErrConflict: the tasks it names are blocked by
+// each other, directly or through other tasks, so none of them can go
+// first.
+type BlockerCycleError struct {
+	IDs []string
+}
+
+func (e *BlockerCycleError) Error() string {
+	return fmt.Sprintf("%v: the blockers of tasks %s form a cycle", ErrConflict, strings.Join(e.IDs, ", "))
+}
+
+func (e *BlockerCycleError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a request. It matches
+// ErrValidation with errors.Is.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Message
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// Err returns e if any field failed, nil otherwise.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+
+	return e
+}
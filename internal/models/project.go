@@ -0,0 +1,64 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultProjectID is the project of tasks created without one. It always
+// exists and cannot be deleted.
+const DefaultProjectID = "default"
+
+// MaxProjectNameLength caps project names, in characters.
+const MaxProjectNameLength = 100
+
+// Project groups tasks. Task titles are unique within a project.
+type Project struct {
+	ID          string `json:"id" example:"default"`
+	Name        string `json:"name" example:"Inbox"`
+	Description string `json:"description,omitempty" example:"Tasks not filed anywhere else"`
+	// CreatedAt is set by the repository when the project is first stored.
+	CreatedAt time.Time `json:"createdAt"`
+	// Version starts at 1 and is incremented by the repository on every
+	// change; it is the project's ETag.
+	Version int64 `json:"version" example:"1"`
+}
+
+// ETag returns the strong entity tag of this version of the project.
+func (p *Project) ETag() string {
+	return strconv.Quote(strconv.FormatInt(p.Version, 10))
+}
+
+type ProjectRequest struct {
+	Name        string `json:"name" example:"Inbox"`
+	Description string `json:"description,omitempty" example:"Tasks not filed anywhere else"`
+}
+
+// MoveRequest moves a task to another project.
+type MoveRequest struct {
+	ProjectID string `json:"projectId" example:"default"`
+}
+
+func (p *Project) Validate() error {
+	verr := &ValidationError{}
+
+	if p.ID != "" {
+		verr.Add("id", "id mustn't present in request")
+	}
+
+	switch name := strings.TrimSpace(p.Name); {
+	case name == "":
+		verr.Add("name", "name mustn't be empty")
+	case utf8.RuneCountInString(name) > MaxProjectNameLength:
+		verr.Add("name", fmt.Sprintf("name exceeds %d characters", MaxProjectNameLength))
+	}
+
+	if utf8.RuneCountInString(p.Description) > MaxDescriptionLength {
+		verr.Add("description", fmt.Sprintf("description exceeds %d characters", MaxDescriptionLength))
+	}
+
+	return verr.Err()
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxTags caps the number of tags on a task.
+	MaxTags = 20
+	// MaxTagLength caps the length of a tag, in characters.
+	MaxTagLength = 50
+)
+
+// Tag is a tag and the number of tasks carrying it.
+type Tag struct {
+	Name  string `json:"name" example:"backend"`
+	Count int    `json:"count" example:"3"`
+}
+
+// TagRenameRequest renames a tag.
+type TagRenameRequest struct {
+	Name string `json:"name" example:"customer-y"`
+}
+
+// TagMergeRequest merges a tag into another one.
+type TagMergeRequest struct {
+	Into string `json:"into" example:"backend"`
+}
+
+// NormalizeTag trims tag and folds it to lower case, so "Backend " and
+// "backend" are the same tag.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// NormalizeTags normalizes every tag and returns them sorted, without
+// duplicates. It returns nil for no tags.
+func NormalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	normalized := make([]string, len(tags))
+	for i, tag := range tags {
+		normalized[i] = NormalizeTag(tag)
+	}
+	slices.Sort(normalized)
+
+	return slices.Compact(normalized)
+}
+
+// ValidateTag checks tag, once normalized, is a non-empty run of letters,
+// digits, '-', '_' and ':' of at most MaxTagLength characters.
+func ValidateTag(tag string) error {
+	tag = NormalizeTag(tag)
+
+	if tag == "" {
+		return fmt.Errorf("tags mustn't be empty")
+	}
+	if utf8.RuneCountInString(tag) > MaxTagLength {
+		return fmt.Errorf("tag %q exceeds %d characters", tag, MaxTagLength)
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != ':' {
+			return fmt.Errorf("tag %q may only contain letters, digits, '-', '_' and ':'", tag)
+		}
+	}
+
+	return nil
+}
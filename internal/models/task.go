@@ -1,34 +1,269 @@
 package models
 
 import (
-	"errors"
+	"fmt"
+	"slices"
+	"strconv"
 	"time"
+	"unicode/utf8"
+
+	"github.com/canyouhearthemusic/todo-list/internal/rrule"
+)
+
+// DateLayout is the format of activeAt and other calendar dates.
+const DateLayout = "2006-01-02"
+
+// MaxTitleLength caps titles, in bytes.
+const MaxTitleLength = 200
+
+// MaxDescriptionLength caps descriptions, in characters.
+const MaxDescriptionLength = 10000
+
+// MaxBlockers caps the number of tasks a task can be blocked by.
+const MaxBlockers = 50
+
+// Task priorities, lowest first.
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
 )
 
+// Priorities lists the valid priorities, lowest first.
+var Priorities = []string{PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent}
+
 type Task struct {
 	ID       string `json:"id"`
 	Title    string `json:"title"`
 	ActiveAt string `json:"activeAt"`
 	Status   string `json:"status"`
+	// ProjectID names the project of the task, DefaultProjectID if empty.
+	ProjectID string `json:"projectId" example:"default"`
+	// ParentID names the task this one is a subtask of, if any.
+	ParentID string `json:"parentId,omitempty"`
+	// Description holds details in Markdown, stored as given.
+	Description string `json:"description,omitempty" example:"Collect the **Q2** numbers first"`
+	// Tags label the task, normalized by NormalizeTags.
+	Tags []string `json:"tags,omitempty" example:"backend,customer-x"`
+	// Checklist is changed through its own endpoints only; updates of the
+	// task keep it as it is.
+	Checklist []ChecklistItem `json:"checklist,omitempty"`
+	// BlockedBy names the tasks to finish before this one. Like the
+	// checklist, it is changed through its own endpoints only.
+	BlockedBy []string `json:"blockedBy,omitempty"`
+	// Recurrence makes the task repeat: marking it done creates the next
+	// occurrence.
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	// Priority is low, normal, high or urgent; the repository stores
+	// normal if it is empty.
+	Priority string `json:"priority" enums:"low,normal,high,urgent" example:"normal"`
+	// DueAt is an optional RFC 3339 timestamp, kept with the offset it was
+	// given in.
+	DueAt string `json:"dueAt,omitempty" example:"2024-05-10T18:00:00+05:00"`
+	// CreatedAt is set by the repository when the task is first stored.
+	CreatedAt time.Time `json:"createdAt"`
+	// Version starts at 1 and is incremented by the repository on every
+	// change; it is the task's ETag.
+	Version int64 `json:"version" example:"1"`
+	// StatusChangedAt and StatusChangedBy record the last status change.
+	// The repository sets the time; the caller names who made the change.
+	StatusChangedAt *time.Time `json:"statusChangedAt,omitempty"`
+	StatusChangedBy string     `json:"statusChangedBy,omitempty" example:"alice"`
+}
+
+// Recurrence is the RFC 5545 rule a task repeats by, along with where in
+// its series the task is. Only the rule is taken from requests; the rest is
+// kept by the service.
+type Recurrence struct {
+	// Rule is an RRULE of dates, e.g. FREQ=WEEKLY;BYDAY=MO,WE; times of day
+	// are not supported.
+	Rule string `json:"rule" example:"FREQ=WEEKLY;BYDAY=MO"`
+	// Start is the activeAt of the first occurrence, the DTSTART of the
+	// rule.
+	Start string `json:"start,omitempty" example:"2024-05-06"`
+	// Occurrence numbers this task in the series, starting at 1.
+	Occurrence int `json:"occurrence,omitempty" example:"1"`
+	// Scheduled is the date the rule gives this occurrence, which differs
+	// from activeAt if that was moved off a weekend or holiday.
+	Scheduled string `json:"scheduled,omitempty" example:"2024-05-06"`
+}
+
+// RecurrenceRequest makes a task repeat.
+type RecurrenceRequest struct {
+	Rule string `json:"rule" example:"FREQ=WEEKLY;BYDAY=MO"`
+}
+
+// ETag returns the strong entity tag of this version of the task.
+func (t *Task) ETag() string {
+	return strconv.Quote(strconv.FormatInt(t.Version, 10))
+}
+
+// TaskView is a Task as presented in listings: the stored fields, untouched,
+// plus presentation fields computed on the fly.
+type TaskView struct {
+	Task
+	IsWeekend bool `json:"isWeekend"`
+	// DayType is workday, weekend or holiday.
+	DayType      string `json:"dayType" example:"workday"`
+	Holiday      string `json:"holiday,omitempty" example:"Nauryz"`
+	DisplayTitle string `json:"displayTitle"`
+	// Overdue is set for tasks past their dueAt that are not closed yet.
+	Overdue bool `json:"overdue"`
+	// Progress is set for tasks with subtasks or a checklist.
+	Progress *Progress `json:"progress,omitempty"`
+	// DescriptionHTML is the description rendered as sanitized HTML, if
+	// requested with render=html.
+	DescriptionHTML string `json:"descriptionHtml,omitempty"`
+}
+
+// TaskDetail is a single task as presented on its own: the stored fields
+// plus its progress and, if requested, its description rendered as
+// sanitized HTML.
+type TaskDetail struct {
+	Task
+	Progress        *Progress `json:"progress,omitempty"`
+	DescriptionHTML string    `json:"descriptionHtml,omitempty"`
+}
+
+// TaskSearchResult is a task found by full-text search.
+type TaskSearchResult struct {
+	Task
+	// Score ranks results; higher is more relevant.
+	Score float64 `json:"score" example:"1.42"`
+	// Highlights maps each matching field to an HTML-escaped snippet with
+	// the matched words wrapped in <mark> tags.
+	Highlights map[string]string `json:"highlights"`
+}
+
+// StatusRequest moves a task to another status.
+type StatusRequest struct {
+	Status string `json:"status" example:"done"`
 }
 
 type TaskRequest struct {
 	Title    string `json:"title"`
 	ActiveAt string `json:"activeAt"`
+	// ProjectID defaults to the default project on create and to the
+	// current project on update.
+	ProjectID string `json:"projectId,omitempty" example:"default"`
+	// ParentID makes the task a subtask; on update, an empty one keeps the
+	// current parent.
+	ParentID string `json:"parentId,omitempty"`
+	// Checklist and BlockedBy are only taken on create.
+	Checklist []ChecklistItemRequest `json:"checklist,omitempty"`
+	BlockedBy []string               `json:"blockedBy,omitempty"`
+	// Recurrence makes the task repeat; on update, leaving it out stops it
+	// from repeating.
+	Recurrence  *RecurrenceRequest `json:"recurrence,omitempty"`
+	Description string             `json:"description,omitempty" example:"Collect the **Q2** numbers first"`
+	Tags        []string           `json:"tags,omitempty" example:"backend,customer-x"`
+	Priority    string             `json:"priority" enums:"low,normal,high,urgent" example:"normal"`
+	DueAt       string             `json:"dueAt,omitempty" example:"2024-05-10T18:00:00+05:00"`
+}
+
+// TaskPatch is the part of a task a PATCH request can change. Patches are
+// applied to this document, not to the whole task.
+type TaskPatch struct {
+	Title       string   `json:"title"`
+	ActiveAt    string   `json:"activeAt"`
+	ProjectID   string   `json:"projectId" example:"default"`
+	ParentID    string   `json:"parentId"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Status      string   `json:"status" example:"done"`
+	Priority    string   `json:"priority" example:"high"`
+	DueAt       string   `json:"dueAt" example:"2024-05-10T18:00:00+05:00"`
+	// Recurrence is null for tasks that do not repeat.
+	Recurrence *RecurrenceRequest `json:"recurrence"`
+}
+
+// BatchRequest is the body of a batch of task writes.
+type BatchRequest struct {
+	// Atomic applies either every operation or none of them.
+	Atomic     bool             `json:"atomic"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation is one write of a batch: create takes task, update takes
+// id and task, done and delete take id. A non-zero version must match the
+// stored task, like an If-Match header.
+type BatchOperation struct {
+	Op      string `json:"op" enums:"create,update,done,delete"`
+	ID      string `json:"id,omitempty"`
+	Task    *Task  `json:"task,omitempty"`
+	Version int64  `json:"version,omitempty"`
 }
 
 func (t *Task) Validate() error {
+	verr := &ValidationError{}
+
 	if t.ID != "" {
-		return errors.New("id mustn't present in request")
+		verr.Add("id", "id mustn't present in request")
+	}
+
+	switch {
+	case len(t.Title) > MaxTitleLength:
+		verr.Add("title", fmt.Sprintf("title exceeds %d characters", MaxTitleLength))
+	case t.Recurrence != nil && len(OccurrenceTitle(t.Title, DateLayout)) > MaxTitleLength:
+		verr.Add("title", fmt.Sprintf("title of a recurring task exceeds %d characters", MaxTitleLength-len(OccurrenceTitle("", DateLayout))))
+	}
+
+	if _, err := time.Parse(DateLayout, t.ActiveAt); err != nil {
+		verr.Add("activeAt", "invalid activeAt format")
 	}
 
-	if len(t.Title) > 200 {
-		return errors.New("title exceeds 200 characters")
+	switch {
+	case !utf8.ValidString(t.Description):
+		verr.Add("description", "description must be valid UTF-8")
+	case utf8.RuneCountInString(t.Description) > MaxDescriptionLength:
+		verr.Add("description", fmt.Sprintf("description exceeds %d characters", MaxDescriptionLength))
+	}
+
+	if len(t.Tags) > MaxTags {
+		verr.Add("tags", fmt.Sprintf("a task can have at most %d tags", MaxTags))
+	}
+	for _, tag := range t.Tags {
+		if err := ValidateTag(tag); err != nil {
+			verr.Add("tags", err.Error())
+		}
 	}
 
-	if _, err := time.Parse("2006-01-02", t.ActiveAt); err != nil {
-		return errors.New("invalid activeAt format")
+	if len(t.Checklist) > MaxChecklistItems {
+		verr.Add("checklist", fmt.Sprintf("a checklist can have at most %d items", MaxChecklistItems))
 	}
+	for _, item := range t.Checklist {
+		if err := item.Validate(); err != nil {
+			verr.Add("checklist", err.Error())
+		}
+	}
+
+	if len(t.BlockedBy) > MaxBlockers {
+		verr.Add("blockedBy", fmt.Sprintf("a task can be blocked by at most %d tasks", MaxBlockers))
+	}
+
+	if t.Recurrence != nil {
+		if _, err := rrule.Parse(t.Recurrence.Rule); err != nil {
+			verr.Add("recurrence", err.Error())
+		}
+	}
+
+	if t.Priority != "" && !slices.Contains(Priorities, t.Priority) {
+		verr.Add("priority", "priority must be one of low, normal, high, urgent")
+	}
+
+	if t.DueAt != "" {
+		if _, err := time.Parse(time.RFC3339, t.DueAt); err != nil {
+			verr.Add("dueAt", "dueAt must be an RFC 3339 timestamp with a timezone, e.g. 2024-05-10T18:00:00+05:00")
+		}
+	}
+
+	return verr.Err()
+}
 
-	return nil
+// OccurrenceTitle is the title an occurrence of a recurring task titled
+// title and active at activeAt gets once done, so that it stands apart from
+// the occurrences after it.
+func OccurrenceTitle(title, activeAt string) string {
+	return fmt.Sprintf("%s (%s)", title, activeAt)
 }
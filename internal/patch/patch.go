@@ -0,0 +1,337 @@
+// Package patch applies JSON Merge Patch (RFC 7396) and JSON Patch
+// (RFC 6902) documents to JSON values.
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalid means the patch is malformed or cannot be applied, e.g.
+	// because a path does not exist.
+	ErrInvalid = errors.New("invalid patch")
+	// ErrTestFailed means a JSON Patch "test" operation did not hold.
+	ErrTestFailed = errors.New("patch test failed")
+)
+
+// Merge applies the merge patch p to doc.
+func Merge(doc, p []byte) ([]byte, error) {
+	var target, patch any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(p, &patch); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	return json.Marshal(merge(target, patch))
+}
+
+func merge(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+		} else {
+			t[key] = merge(t[key], value)
+		}
+	}
+
+	return t
+}
+
+type operation struct {
+	Op    string           `json:"op"`
+	Path  *string          `json:"path"`
+	From  *string          `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// Apply applies the JSON Patch p to doc. Operations apply in order and the
+// patch is atomic: if any fails, doc is left as it was.
+func Apply(doc, p []byte) ([]byte, error) {
+	var ops []operation
+	if err := json.Unmarshal(p, &ops); err != nil {
+		return nil, fmt.Errorf("%w: a JSON Patch must be an array of operations: %v", ErrInvalid, err)
+	}
+
+	var root any
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		var err error
+		if root, err = op.apply(root); err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i, op.Op, err)
+		}
+	}
+
+	return json.Marshal(root)
+}
+
+func (op operation) apply(root any) (any, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: missing path", ErrInvalid)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalid)
+		}
+		if err := json.Unmarshal(*op.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: missing from", ErrInvalid)
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = get(root, from); err != nil {
+			return nil, err
+		}
+
+		if op.Op == "move" {
+			if path.within(from) {
+				return nil, fmt.Errorf("%w: cannot move %s into itself", ErrInvalid, *op.From)
+			}
+			if root, err = remove(root, from); err != nil {
+				return nil, err
+			}
+		} else {
+			value = deepCopy(value)
+		}
+	}
+
+	switch op.Op {
+	case "add", "move", "copy":
+		return add(root, path, value)
+	case "remove":
+		return remove(root, path)
+	case "replace":
+		if root, err = remove(root, path); err != nil {
+			return nil, err
+		}
+		return add(root, path, value)
+	case "test":
+		current, err := get(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, fmt.Errorf("%w: %s does not have the expected value", ErrTestFailed, *op.Path)
+		}
+		return root, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalid, op.Op)
+}
+
+// pointer is a parsed JSON Pointer (RFC 6901); empty refers to the root.
+type pointer []string
+
+func parsePointer(s string) (pointer, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("%w: path %q must start with /", ErrInvalid, s)
+	}
+
+	tokens := strings.Split(s[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	return tokens, nil
+}
+
+// within reports whether p is a location strictly inside prefix.
+func (p pointer) within(prefix pointer) bool {
+	return len(p) > len(prefix) && reflect.DeepEqual(p[:len(prefix)], prefix)
+}
+
+func (p pointer) String() string {
+	var b strings.Builder
+	for _, token := range p {
+		b.WriteString("/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+
+	return b.String()
+}
+
+func get(root any, path pointer) (any, error) {
+	node := root
+	for i, token := range path {
+		switch n := node.(type) {
+		case map[string]any:
+			child, ok := n[token]
+			if !ok {
+				return nil, notFound(path[:i+1])
+			}
+			node = child
+		case []any:
+			idx, err := index(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[idx]
+		default:
+			return nil, notFound(path[:i+1])
+		}
+	}
+
+	return node, nil
+}
+
+func add(root any, path pointer, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return update(root, path, func(container any, token string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			c[token] = value
+			return c, nil
+		case []any:
+			idx := len(c)
+			if token != "-" {
+				var err error
+				if idx, err = index(token, len(c)); err != nil {
+					return nil, err
+				}
+			}
+			c = append(c, nil)
+			copy(c[idx+1:], c[idx:])
+			c[idx] = value
+			return c, nil
+		}
+
+		return nil, notFound(path[:len(path)-1])
+	})
+}
+
+func remove(root any, path pointer) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalid)
+	}
+
+	return update(root, path, func(container any, token string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			if _, ok := c[token]; !ok {
+				return nil, notFound(path)
+			}
+			delete(c, token)
+			return c, nil
+		case []any:
+			idx, err := index(token, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(c[:idx], c[idx+1:]...), nil
+		}
+
+		return nil, notFound(path)
+	})
+}
+
+// update walks to the container holding the last token of path and
+// replaces it with what f returns, so that slices can grow and shrink.
+func update(root any, path pointer, f func(container any, token string) (any, error)) (any, error) {
+	var walk func(node any, depth int) (any, error)
+	walk = func(node any, depth int) (any, error) {
+		if depth == len(path)-1 {
+			return f(node, path[depth])
+		}
+
+		switch n := node.(type) {
+		case map[string]any:
+			child, ok := n[path[depth]]
+			if !ok {
+				return nil, notFound(path[:depth+1])
+			}
+			updated, err := walk(child, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			n[path[depth]] = updated
+			return n, nil
+		case []any:
+			idx, err := index(path[depth], len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			updated, err := walk(n[idx], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			n[idx] = updated
+			return n, nil
+		}
+
+		return nil, notFound(path[:depth+1])
+	}
+
+	return walk(root, 0)
+}
+
+// index parses an array index no greater than max.
+func index(token string, max int) (int, error) {
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrInvalid, token)
+	}
+	if idx > max {
+		return 0, fmt.Errorf("%w: array index %d is out of range", ErrInvalid, idx)
+	}
+
+	return idx, nil
+}
+
+func notFound(path pointer) error {
+	return fmt.Errorf("%w: path %s does not exist", ErrInvalid, path)
+}
+
+func deepCopy(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for key, value := range v {
+			c[key] = deepCopy(value)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, value := range v {
+			c[i] = deepCopy(value)
+		}
+		return c
+	}
+
+	return v
+}
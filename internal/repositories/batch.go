@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+)
+
+// BatchOp is one operation of a batch. It makes its writes through tx and
+// returns the task as stored, nil after a delete.
+type BatchOp func(tx TaskRepo) (*models.Task, error)
+
+// BatchResult is the outcome of a BatchOp: the task as stored, nil after a
+// delete, or the error that stopped it.
+type BatchResult struct {
+	Task *models.Task
+	Err  error
+}
+
+// errBatchAborted rolls back the transaction of an atomic batch once one
+// of its operations has failed.
+var errBatchAborted = errors.New("batch aborted")
+
+// runBatch implements TaskRepo.Batch on top of repo.Atomically, which must
+// be the Atomically of the outermost wrapper so that the writes of ops go
+// through it.
+func runBatch(ctx context.Context, repo TaskRepo, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	results := make([]BatchResult, len(ops))
+
+	err := repo.Atomically(ctx, func(tx TaskRepo) error {
+		for i, op := range ops {
+			err := tx.Atomically(ctx, func(tx TaskRepo) error {
+				var err error
+				results[i].Task, err = op(tx)
+				return err
+			})
+			if err == nil {
+				continue
+			}
+
+			results[i] = BatchResult{Err: err}
+			if atomic {
+				for j := range results {
+					if j != i {
+						results[j] = BatchResult{Err: models.ErrBatchAborted}
+					}
+				}
+				return errBatchAborted
+			}
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBatchAborted) {
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,208 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/canyouhearthemusic/todo-list/internal/search"
+)
+
+// TaskSearcher is implemented by repositories that support full-text search.
+type TaskSearcher interface {
+	// Search returns the tasks matching every term of q, best first. A
+	// limit of zero means no limit.
+	Search(ctx context.Context, q search.Query, limit int) ([]*SearchHit, error)
+}
+
+type SearchHit struct {
+	Task  *models.Task
+	Score float64
+}
+
+// IndexedTaskRepo wraps a TaskRepo with a full-text index of task titles
+// and descriptions, built from the wrapped repository on creation and kept
+// current by Post, Put, Delete, Atomically and Batch. Every method that
+// changes a task's text must be overridden here, and on indexedTx, to
+// update the index.
+type IndexedTaskRepo struct {
+	TaskRepo
+
+	// mu serializes writes so the index sees them in the order the
+	// wrapped repository applied them.
+	mu    sync.Mutex
+	index *search.Index
+}
+
+func NewIndexedTaskRepo(ctx context.Context, repo TaskRepo) (*IndexedTaskRepo, error) {
+	tasks, err := repo.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	indexed := &IndexedTaskRepo{TaskRepo: repo, index: search.NewIndex()}
+	for _, task := range tasks {
+		indexed.indexTask(task)
+	}
+
+	return indexed, nil
+}
+
+func (repo *IndexedTaskRepo) Post(ctx context.Context, task *models.Task) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if err := repo.TaskRepo.Post(ctx, task); err != nil {
+		return err
+	}
+
+	repo.indexTask(task)
+
+	return nil
+}
+
+func (repo *IndexedTaskRepo) Put(ctx context.Context, id string, task *models.Task, version int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if err := repo.TaskRepo.Put(ctx, id, task, version); err != nil {
+		return err
+	}
+
+	repo.indexTask(task)
+
+	return nil
+}
+
+func (repo *IndexedTaskRepo) Delete(ctx context.Context, id string, version int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if err := repo.TaskRepo.Delete(ctx, id, version); err != nil {
+		return err
+	}
+
+	repo.index.Remove(id)
+
+	return nil
+}
+
+// Atomically updates the index once the transaction is stored, with the
+// changes of the nested calls of fn that did not fail.
+func (repo *IndexedTaskRepo) Atomically(ctx context.Context, fn func(tx TaskRepo) error) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	var changes []func()
+	err := repo.TaskRepo.Atomically(ctx, func(tx TaskRepo) error {
+		return fn(&indexedTx{TaskRepo: tx, repo: repo, changes: &changes})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		change()
+	}
+
+	return nil
+}
+
+// Batch updates the index with the ops that succeed once the batch is
+// stored.
+func (repo *IndexedTaskRepo) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	return runBatch(ctx, repo, ops, atomic)
+}
+
+func (repo *IndexedTaskRepo) Search(ctx context.Context, q search.Query, limit int) ([]*SearchHit, error) {
+	var results []*SearchHit
+
+	// Hits are fetched without a limit: a task deleted between the index
+	// lookup and GetByID is skipped and must not shorten the page.
+	for _, hit := range repo.index.Search(q, 0) {
+		task, err := repo.GetByID(ctx, hit.ID)
+		if err != nil {
+			if errors.Is(err, models.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		results = append(results, &SearchHit{Task: task, Score: hit.Score})
+		if limit > 0 && len(results) == limit {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+// titleWeight makes a word in the title count as much as several in the
+// description, which is typically much longer.
+const titleWeight = 3
+
+func (repo *IndexedTaskRepo) indexTask(task *models.Task) {
+	repo.index.Set(task.ID,
+		search.Field{Name: "title", Text: task.Title, Weight: titleWeight},
+		search.Field{Name: "description", Text: task.Description},
+	)
+}
+
+// indexedTx is the TaskRepo passed to the fn of IndexedTaskRepo.Atomically.
+// It collects the index changes to make once the transaction is stored.
+type indexedTx struct {
+	TaskRepo
+
+	repo    *IndexedTaskRepo
+	changes *[]func()
+}
+
+func (tx *indexedTx) Post(ctx context.Context, task *models.Task) error {
+	if err := tx.TaskRepo.Post(ctx, task); err != nil {
+		return err
+	}
+
+	indexed := *task
+	*tx.changes = append(*tx.changes, func() { tx.repo.indexTask(&indexed) })
+
+	return nil
+}
+
+func (tx *indexedTx) Put(ctx context.Context, id string, task *models.Task, version int64) error {
+	if err := tx.TaskRepo.Put(ctx, id, task, version); err != nil {
+		return err
+	}
+
+	indexed := *task
+	*tx.changes = append(*tx.changes, func() { tx.repo.indexTask(&indexed) })
+
+	return nil
+}
+
+func (tx *indexedTx) Delete(ctx context.Context, id string, version int64) error {
+	if err := tx.TaskRepo.Delete(ctx, id, version); err != nil {
+		return err
+	}
+
+	*tx.changes = append(*tx.changes, func() { tx.repo.index.Remove(id) })
+
+	return nil
+}
+
+// Atomically drops the changes of fn if it fails.
+func (tx *indexedTx) Atomically(ctx context.Context, fn func(tx TaskRepo) error) error {
+	mark := len(*tx.changes)
+	err := tx.TaskRepo.Atomically(ctx, func(inner TaskRepo) error {
+		return fn(&indexedTx{TaskRepo: inner, repo: tx.repo, changes: tx.changes})
+	})
+	if err != nil {
+		*tx.changes = (*tx.changes)[:mark]
+	}
+
+	return err
+}
+
+func (tx *indexedTx) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	return runBatch(ctx, tx, ops, atomic)
+}
@@ -0,0 +1,610 @@
+package repositories
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+type FsyncPolicy string
+
+const (
+	// FsyncAlways syncs the journal after every write.
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncInterval syncs the journal in the background every FsyncInterval.
+	FsyncInterval FsyncPolicy = "interval"
+	// FsyncNever leaves flushing to the operating system.
+	FsyncNever FsyncPolicy = "never"
+)
+
+const (
+	journalFile  = "journal.log"
+	snapshotFile = "snapshot.json"
+)
+
+type JournalOptions struct {
+	Dir           string
+	Fsync         FsyncPolicy
+	FsyncInterval time.Duration
+	// CompactEvery is the number of journal records after which the journal
+	// is folded into a fresh snapshot. Zero disables automatic compaction.
+	CompactEvery int
+}
+
+type journalOp string
+
+const (
+	opPost   journalOp = "post"
+	opPut    journalOp = "put"
+	opDone   journalOp = "done"
+	opDelete journalOp = "delete"
+	// opBatch groups the records of a transaction in a single line, so
+	// that a crash never leaves it half-written.
+	opBatch journalOp = "batch"
+	// opPutProject stores a created or changed project, opDeleteProject
+	// removes one.
+	opPutProject    journalOp = "put_project"
+	opDeleteProject journalOp = "delete_project"
+)
+
+type journalRecord struct {
+	Seq     uint64          `json:"seq"`
+	Op      journalOp       `json:"op"`
+	ID      string          `json:"id"`
+	Task    *models.Task    `json:"task,omitempty"`
+	Project *models.Project `json:"project,omitempty"`
+	Ops     []journalRecord `json:"ops,omitempty"`
+}
+
+type snapshot struct {
+	Seq   uint64         `json:"seq"`
+	Tasks []*models.Task `json:"tasks"`
+	// Projects is absent from snapshots taken before projects existed.
+	Projects []*models.Project `json:"projects,omitempty"`
+}
+
+// JournaledTaskRepo is a SyncMapTaskRepo whose mutations are appended to a
+// journal on disk. On startup the last snapshot is loaded and the journal
+// replayed on top of it, so the in-memory state survives restarts and crashes.
+type JournaledTaskRepo struct {
+	*SyncMapTaskRepo
+
+	opts JournalOptions
+
+	mu       sync.Mutex
+	log      *os.File
+	seq      uint64
+	pending  int
+	dirty    bool
+	writeErr error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewJournaledTaskRepo(opts JournalOptions) (*JournaledTaskRepo, error) {
+	if opts.Fsync == "" {
+		opts.Fsync = FsyncAlways
+	}
+	if opts.Fsync == FsyncInterval && opts.FsyncInterval <= 0 {
+		opts.FsyncInterval = time.Second
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	repo := &JournaledTaskRepo{
+		SyncMapTaskRepo: NewSyncMapTaskRepo(),
+		opts:            opts,
+	}
+
+	if err := repo.loadSnapshot(); err != nil {
+		return nil, err
+	}
+
+	if err := repo.replay(); err != nil {
+		return nil, err
+	}
+
+	if opts.Fsync == FsyncInterval {
+		repo.stop = make(chan struct{})
+		repo.done = make(chan struct{})
+		go repo.syncLoop()
+	}
+
+	return repo, nil
+}
+
+func (repo *JournaledTaskRepo) Post(ctx context.Context, task *models.Task) error {
+	return repo.Atomically(ctx, func(tx TaskRepo) error {
+		return tx.Post(ctx, task)
+	})
+}
+
+func (repo *JournaledTaskRepo) Put(ctx context.Context, id string, task *models.Task, version int64) error {
+	return repo.Atomically(ctx, func(tx TaskRepo) error {
+		return tx.Put(ctx, id, task, version)
+	})
+}
+
+func (repo *JournaledTaskRepo) MarkAsDone(ctx context.Context, id string, version int64) error {
+	return repo.Atomically(ctx, func(tx TaskRepo) error {
+		return tx.MarkAsDone(ctx, id, version)
+	})
+}
+
+func (repo *JournaledTaskRepo) Delete(ctx context.Context, id string, version int64) error {
+	return repo.Atomically(ctx, func(tx TaskRepo) error {
+		return tx.Delete(ctx, id, version)
+	})
+}
+
+func (repo *JournaledTaskRepo) RenameTag(ctx context.Context, from, to string, merge bool) ([]*models.Task, error) {
+	var tasks []*models.Task
+	err := repo.Atomically(ctx, func(tx TaskRepo) error {
+		var err error
+		tasks, err = tx.RenameTag(ctx, from, to, merge)
+		return err
+	})
+
+	return tasks, err
+}
+
+func (repo *JournaledTaskRepo) PostProject(ctx context.Context, project *models.Project) error {
+	return repo.Atomically(ctx, func(tx TaskRepo) error {
+		return tx.PostProject(ctx, project)
+	})
+}
+
+func (repo *JournaledTaskRepo) PutProject(ctx context.Context, id string, project *models.Project, version int64) error {
+	return repo.Atomically(ctx, func(tx TaskRepo) error {
+		return tx.PutProject(ctx, id, project, version)
+	})
+}
+
+func (repo *JournaledTaskRepo) DeleteProject(ctx context.Context, id string, version int64) error {
+	return repo.Atomically(ctx, func(tx TaskRepo) error {
+		return tx.DeleteProject(ctx, id, version)
+	})
+}
+
+// Atomically journals the changes fn made, in a single record if there are
+// several, before the in-memory transaction is let go: if the journal
+// cannot be written, the changes are undone.
+func (repo *JournaledTaskRepo) Atomically(ctx context.Context, fn func(tx TaskRepo) error) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.writeErr != nil {
+		return repo.writeErr
+	}
+
+	return repo.SyncMapTaskRepo.Atomically(ctx, func(tx TaskRepo) error {
+		var records []journalRecord
+		if err := fn(&journalTx{TaskRepo: tx, records: &records}); err != nil {
+			return err
+		}
+
+		switch len(records) {
+		case 0:
+			return nil
+		case 1:
+			return repo.append(records[0])
+		default:
+			return repo.append(journalRecord{Op: opBatch, Ops: records})
+		}
+	})
+}
+
+// Batch journals the ops that succeed in a single record.
+func (repo *JournaledTaskRepo) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	return runBatch(ctx, repo, ops, atomic)
+}
+
+// Compact writes a snapshot of the current state and truncates the journal.
+func (repo *JournaledTaskRepo) Compact() error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.compact()
+}
+
+// Close compacts the journal so the next start does not need to replay it,
+// then releases the journal file.
+func (repo *JournaledTaskRepo) Close() error {
+	if repo.stop != nil {
+		close(repo.stop)
+		<-repo.done
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	var err error
+	if repo.writeErr == nil {
+		err = repo.compact()
+	}
+
+	if cerr := repo.log.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// append must be called with repo.mu held, after the change has been applied
+// in memory. A failed write leaves memory ahead of disk, so the repo refuses
+// further writes rather than silently diverging.
+func (repo *JournaledTaskRepo) append(rec journalRecord) error {
+	rec.Seq = repo.seq + 1
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.log.Write(append(line, '\n')); err != nil {
+		repo.writeErr = fmt.Errorf("journal write failed: %w", err)
+		return repo.writeErr
+	}
+
+	if repo.opts.Fsync == FsyncAlways {
+		if err := repo.log.Sync(); err != nil {
+			repo.writeErr = fmt.Errorf("journal sync failed: %w", err)
+			return repo.writeErr
+		}
+	} else {
+		repo.dirty = true
+	}
+
+	repo.seq = rec.Seq
+	repo.pending++
+
+	// The record is written and applied, so a failed compaction does not
+	// fail the write: pending stays up and the next append tries again.
+	if repo.opts.CompactEvery > 0 && repo.pending >= repo.opts.CompactEvery {
+		if err := repo.compact(); err != nil {
+			log.Errorf("journal compaction failed, will retry: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// compact reads the store without its lock: it runs either inside a
+// transaction, which holds it, or with mu held, which keeps writers out.
+func (repo *JournaledTaskRepo) compact() error {
+	data, err := json.Marshal(snapshot{Seq: repo.seq, Tasks: repo.all(), Projects: repo.listProjects()})
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(filepath.Join(repo.opts.Dir, snapshotFile), data); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+
+	// Everything in the journal is now covered by the snapshot. Should we
+	// crash before the truncate, replay skips records by sequence number.
+	if err := repo.log.Truncate(0); err != nil {
+		repo.writeErr = fmt.Errorf("journal truncate failed: %w", err)
+		return repo.writeErr
+	}
+	if _, err := repo.log.Seek(0, io.SeekStart); err != nil {
+		repo.writeErr = fmt.Errorf("journal truncate failed: %w", err)
+		return repo.writeErr
+	}
+
+	repo.pending = 0
+	repo.dirty = false
+
+	return nil
+}
+
+func (repo *JournaledTaskRepo) syncLoop() {
+	defer close(repo.done)
+
+	ticker := time.NewTicker(repo.opts.FsyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-repo.stop:
+			return
+		case <-ticker.C:
+			repo.mu.Lock()
+			if repo.dirty && repo.writeErr == nil {
+				if err := repo.log.Sync(); err != nil {
+					repo.writeErr = fmt.Errorf("journal sync failed: %w", err)
+				}
+				repo.dirty = false
+			}
+			repo.mu.Unlock()
+		}
+	}
+}
+
+func (repo *JournaledTaskRepo) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(repo.opts.Dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("read snapshot: %w", err)
+	}
+
+	for _, project := range snap.Projects {
+		repo.projects.Store(project.ID, project)
+	}
+	for _, task := range snap.Tasks {
+		repo.storeTask(task)
+	}
+	repo.seq = snap.Seq
+
+	return nil
+}
+
+// replay applies journal records newer than the snapshot and leaves the
+// journal open for appending. A torn final record, as left by a crash
+// mid-write, is discarded; corruption anywhere else is an error.
+func (repo *JournaledTaskRepo) replay() error {
+	f, err := os.OpenFile(filepath.Join(repo.opts.Dir, journalFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+
+	var valid int64
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Anything left without a trailing newline is a partial write.
+			break
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+
+		var rec journalRecord
+		if err := json.Unmarshal(bytes.TrimSpace(line), &rec); err != nil {
+			f.Close()
+			return fmt.Errorf("journal corrupt at offset %d: %w", valid, err)
+		}
+
+		valid += int64(len(line))
+
+		if rec.Seq <= repo.seq {
+			continue
+		}
+
+		repo.apply(rec)
+		repo.seq = rec.Seq
+		repo.pending++
+	}
+
+	if err := f.Truncate(valid); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+
+	repo.log = f
+
+	return nil
+}
+
+func (repo *JournaledTaskRepo) apply(rec journalRecord) {
+	switch rec.Op {
+	case opPost, opPut:
+		repo.storeTask(rec.Task)
+	case opDone:
+		if rec.Task != nil {
+			repo.storeTask(rec.Task)
+			break
+		}
+		// Journals written before done records carried the task.
+		if value, ok := repo.db.Load(rec.ID); ok {
+			task := *value.(*models.Task)
+			task.Status = "done"
+			task.Version++
+			repo.db.Store(rec.ID, &task)
+		}
+	case opDelete:
+		repo.db.Delete(rec.ID)
+	case opBatch:
+		for _, op := range rec.Ops {
+			repo.apply(op)
+		}
+	case opPutProject:
+		repo.projects.Store(rec.ID, rec.Project)
+	case opDeleteProject:
+		repo.projects.Delete(rec.ID)
+	}
+}
+
+// storeTask stores a task read back from disk. Tasks written before
+// projects existed belong to the default project.
+func (repo *JournaledTaskRepo) storeTask(task *models.Task) {
+	if task.ProjectID == "" {
+		task.ProjectID = models.DefaultProjectID
+	}
+
+	repo.db.Store(task.ID, task)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	// Persist the rename itself.
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
+
+// journalTx is the TaskRepo passed to the fn of JournaledTaskRepo.Atomically.
+// It collects a journal record for every change, as stored.
+type journalTx struct {
+	TaskRepo
+
+	records *[]journalRecord
+}
+
+func (tx *journalTx) Post(ctx context.Context, task *models.Task) error {
+	if err := tx.TaskRepo.Post(ctx, task); err != nil {
+		return err
+	}
+
+	tx.record(journalRecord{Op: opPost, ID: task.ID, Task: cloneTask(task)})
+
+	return nil
+}
+
+func (tx *journalTx) Put(ctx context.Context, id string, task *models.Task, version int64) error {
+	if err := tx.TaskRepo.Put(ctx, id, task, version); err != nil {
+		return err
+	}
+
+	tx.record(journalRecord{Op: opPut, ID: id, Task: cloneTask(task)})
+
+	return nil
+}
+
+func (tx *journalTx) MarkAsDone(ctx context.Context, id string, version int64) error {
+	if err := tx.TaskRepo.MarkAsDone(ctx, id, version); err != nil {
+		return err
+	}
+
+	// The task is journaled as stored, with the time of the change.
+	task, err := tx.TaskRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	tx.record(journalRecord{Op: opDone, ID: id, Task: task})
+
+	return nil
+}
+
+func (tx *journalTx) Delete(ctx context.Context, id string, version int64) error {
+	if err := tx.TaskRepo.Delete(ctx, id, version); err != nil {
+		return err
+	}
+
+	tx.record(journalRecord{Op: opDelete, ID: id})
+
+	return nil
+}
+
+// RenameTag journals the renamed tasks as puts.
+func (tx *journalTx) RenameTag(ctx context.Context, from, to string, merge bool) ([]*models.Task, error) {
+	tasks, err := tx.TaskRepo.RenameTag(ctx, from, to, merge)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range tasks {
+		tx.record(journalRecord{Op: opPut, ID: task.ID, Task: cloneTask(task)})
+	}
+
+	return tasks, nil
+}
+
+func (tx *journalTx) PostProject(ctx context.Context, project *models.Project) error {
+	if err := tx.TaskRepo.PostProject(ctx, project); err != nil {
+		return err
+	}
+
+	stored := *project
+	tx.record(journalRecord{Op: opPutProject, ID: project.ID, Project: &stored})
+
+	return nil
+}
+
+func (tx *journalTx) PutProject(ctx context.Context, id string, project *models.Project, version int64) error {
+	if err := tx.TaskRepo.PutProject(ctx, id, project, version); err != nil {
+		return err
+	}
+
+	stored := *project
+	tx.record(journalRecord{Op: opPutProject, ID: id, Project: &stored})
+
+	return nil
+}
+
+func (tx *journalTx) DeleteProject(ctx context.Context, id string, version int64) error {
+	if err := tx.TaskRepo.DeleteProject(ctx, id, version); err != nil {
+		return err
+	}
+
+	tx.record(journalRecord{Op: opDeleteProject, ID: id})
+
+	return nil
+}
+
+// Atomically drops the records of fn if it fails.
+func (tx *journalTx) Atomically(ctx context.Context, fn func(tx TaskRepo) error) error {
+	mark := len(*tx.records)
+	err := tx.TaskRepo.Atomically(ctx, func(inner TaskRepo) error {
+		return fn(&journalTx{TaskRepo: inner, records: tx.records})
+	})
+	if err != nil {
+		*tx.records = (*tx.records)[:mark]
+	}
+
+	return err
+}
+
+func (tx *journalTx) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	return runBatch(ctx, tx, ops, atomic)
+}
+
+func (tx *journalTx) record(rec journalRecord) {
+	*tx.records = append(*tx.records, rec)
+}
@@ -0,0 +1,196 @@
+package repositories
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+)
+
+// ProjectRepo stores projects. Project names are unique. PostProject sets
+// the Version of a project to 1 and every change increments it; PutProject
+// and DeleteProject take the version the caller expects to be stored, as
+// TaskRepo.Put does. The models.DefaultProjectID project always exists, and
+// DeleteProject fails with models.ErrDefaultProject for it and with
+// models.ErrProjectNotEmpty for a project that still has tasks.
+type ProjectRepo interface {
+	GetProject(ctx context.Context, id string) (*models.Project, error)
+	// Projects lists every project, oldest first.
+	Projects(ctx context.Context) ([]*models.Project, error)
+	PostProject(ctx context.Context, project *models.Project) error
+	PutProject(ctx context.Context, id string, project *models.Project, version int64) error
+	DeleteProject(ctx context.Context, id string, version int64) error
+}
+
+// defaultProjectName is the name the default project is created with.
+const defaultProjectName = "Inbox"
+
+func newDefaultProject() *models.Project {
+	return &models.Project{
+		ID:        models.DefaultProjectID,
+		Name:      defaultProjectName,
+		CreatedAt: time.Now().UTC(),
+		Version:   1,
+	}
+}
+
+func (repo *SyncMapTaskRepo) GetProject(ctx context.Context, id string) (*models.Project, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	return repo.getProject(id)
+}
+
+func (repo *SyncMapTaskRepo) Projects(ctx context.Context) ([]*models.Project, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	return repo.listProjects(), nil
+}
+
+func (repo *SyncMapTaskRepo) PostProject(ctx context.Context, project *models.Project) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.postProject(project)
+}
+
+func (repo *SyncMapTaskRepo) PutProject(ctx context.Context, id string, project *models.Project, version int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.putProject(id, project, version)
+}
+
+func (repo *SyncMapTaskRepo) DeleteProject(ctx context.Context, id string, version int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.deleteProject(id, version)
+}
+
+// The methods below must be called with mu held, as those of tasks.
+
+func (repo *SyncMapTaskRepo) getProject(id string) (*models.Project, error) {
+	value, ok := repo.projects.Load(id)
+	if !ok {
+		return nil, models.ErrProjectNotFound
+	}
+
+	project := *value.(*models.Project)
+
+	return &project, nil
+}
+
+func (repo *SyncMapTaskRepo) listProjects() []*models.Project {
+	var projects []*models.Project
+	repo.projects.Range(func(key, value interface{}) bool {
+		project := *value.(*models.Project)
+		projects = append(projects, &project)
+
+		return true
+	})
+
+	sortProjects(projects)
+
+	return projects
+}
+
+func (repo *SyncMapTaskRepo) postProject(project *models.Project) error {
+	if repo.projectNameTaken(project.Name, "") {
+		return models.ErrDuplicateProjectName
+	}
+
+	project.CreatedAt = time.Now().UTC()
+	project.Version = 1
+
+	stored := *project
+	repo.store(&repo.projects, project.ID, &stored)
+
+	return nil
+}
+
+func (repo *SyncMapTaskRepo) putProject(id string, project *models.Project, version int64) error {
+	old, err := repo.currentProject(id, version)
+	if err != nil {
+		return err
+	}
+
+	if repo.projectNameTaken(project.Name, id) {
+		return models.ErrDuplicateProjectName
+	}
+
+	project.ID = old.ID
+	project.CreatedAt = old.CreatedAt
+	project.Version = old.Version + 1
+
+	stored := *project
+	repo.store(&repo.projects, id, &stored)
+
+	return nil
+}
+
+func (repo *SyncMapTaskRepo) deleteProject(id string, version int64) error {
+	if _, err := repo.currentProject(id, version); err != nil {
+		return err
+	}
+
+	if id == models.DefaultProjectID {
+		return models.ErrDefaultProject
+	}
+
+	var used bool
+	repo.db.Range(func(key, value interface{}) bool {
+		task, ok := value.(*models.Task)
+		used = ok && task.ProjectID == id
+
+		return !used
+	})
+	if used {
+		return models.ErrProjectNotEmpty
+	}
+
+	repo.remove(&repo.projects, id)
+
+	return nil
+}
+
+// currentProject is current for projects.
+func (repo *SyncMapTaskRepo) currentProject(id string, version int64) (*models.Project, error) {
+	project, err := repo.getProject(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != 0 && project.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+
+	return project, nil
+}
+
+// projectNameTaken reports whether a project other than exceptID already
+// has name.
+func (repo *SyncMapTaskRepo) projectNameTaken(name, exceptID string) bool {
+	var found bool
+	repo.projects.Range(func(key, value interface{}) bool {
+		project := value.(*models.Project)
+		found = project.ID != exceptID && project.Name == name
+
+		return !found
+	})
+
+	return found
+}
+
+// sortProjects orders projects oldest first, then by ID.
+func sortProjects(projects []*models.Project) {
+	slices.SortFunc(projects, func(a, b *models.Project) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+}
@@ -0,0 +1,381 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/filter"
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+)
+
+// TaskQuery selects a page of tasks.
+type TaskQuery struct {
+	// ProjectID keeps only the tasks of this project; empty matches all.
+	ProjectID string
+	// ParentIDs keeps only the subtasks of these tasks; empty matches all.
+	ParentIDs []string
+	// Statuses keeps only tasks with one of these statuses; empty matches
+	// all.
+	Statuses []string
+	// Filter keeps only tasks matching the expression, parsed against
+	// TaskFilterSchema; nil matches all.
+	Filter filter.Expr
+	// DueBefore, if set, keeps only tasks due before it.
+	DueBefore *time.Time
+	// AnyTags keeps only tasks with at least one of these tags, AllTags
+	// those with all of them and NoTags those with none of them. Tags must
+	// be normalized.
+	AnyTags []string
+	AllTags []string
+	NoTags  []string
+	// Sort orders the page; ties, and an empty Sort, fall back to ID so the
+	// order is always total and stable across pages.
+	Sort Sort
+	// After, if set, starts the page right after the task the cursor
+	// points at (keyset pagination). It is applied before Offset and must
+	// have been produced for the same Sort.
+	After  *Cursor
+	Offset int
+	// Limit caps the page size; zero means no limit.
+	Limit int
+}
+
+type TaskPage struct {
+	Tasks []*models.Task
+	// Total counts every task matching the query's filters, regardless of
+	// After, Offset and Limit.
+	Total int
+}
+
+// SortKey orders tasks by one field.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// Sort is a list of keys, most significant first.
+type Sort []SortKey
+
+// TaskFilterSchema lists the fields TaskQuery.Filter may refer to.
+var TaskFilterSchema = filter.Schema{
+	"id":          filter.String,
+	"projectId":   filter.String,
+	"parentId":    filter.String,
+	"title":       filter.String,
+	"description": filter.String,
+	"activeAt":    filter.Date,
+	"createdAt":   filter.Time,
+	"status":      filter.String,
+	"priority":    filter.String,
+	"dueAt":       filter.Time,
+}
+
+// fieldColumns maps task fields to their SQL columns, or expressions
+// yielding the values Match sees; NULL where fieldValue has none.
+var fieldColumns = map[string]string{
+	"id":          "id",
+	"projectId":   "project_id",
+	"parentId":    "COALESCE(parent_id, '')",
+	"title":       "title",
+	"description": "description",
+	"activeAt":    "active_at",
+	"createdAt":   "created_at",
+	"status":      "status",
+	"priority":    "priority",
+	"dueAt":       "NULLIF(due_utc, '" + noDueAt + "')",
+}
+
+// sortFields maps the fields tasks can be sorted by to their SQL columns,
+// or expressions yielding the same values as sortValue.
+var sortFields = map[string]string{
+	"activeAt":  "active_at",
+	"title":     "title",
+	"createdAt": "created_at",
+	"status":    "status",
+	"priority":  `CASE priority WHEN 'low' THEN '0' WHEN 'high' THEN '2' WHEN 'urgent' THEN '3' ELSE '1' END`,
+	"dueAt":     "due_utc",
+}
+
+// SortFields lists the fields accepted by ParseSort.
+func SortFields() []string {
+	fields := make([]string, 0, len(sortFields))
+	for field := range sortFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+// ParseSort parses a comma-separated list of fields, each optionally
+// prefixed with "-" for descending order, e.g. "activeAt,-title".
+func ParseSort(s string) (Sort, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var keys Sort
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ",") {
+		key := SortKey{Field: strings.TrimSpace(part)}
+		if strings.HasPrefix(key.Field, "-") {
+			key.Field, key.Desc = key.Field[1:], true
+		}
+
+		if _, ok := sortFields[key.Field]; !ok {
+			return nil, fmt.Errorf("cannot sort by %q; allowed fields are %s", key.Field, strings.Join(SortFields(), ", "))
+		}
+		if seen[key.Field] {
+			return nil, fmt.Errorf("field %q appears more than once", key.Field)
+		}
+		seen[key.Field] = true
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// String returns s in the form accepted by ParseSort.
+func (s Sort) String() string {
+	parts := make([]string, len(s))
+	for i, key := range s {
+		parts[i] = key.Field
+		if key.Desc {
+			parts[i] = "-" + key.Field
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// createdAtLayout has a fixed width so that stored timestamps compare
+// correctly as strings, both here and in SQL, the way filters expect.
+const createdAtLayout = filter.TimeLayout
+
+// noDueAt is the dueAt value of tasks without one for sorting: they are
+// due after every other task. Filters see no value at all, so that no
+// comparison matches them.
+const noDueAt = "9999-12-31T23:59:59.999999999Z"
+
+// dueValue normalizes an RFC 3339 dueAt to createdAtLayout in UTC.
+func dueValue(dueAt string) string {
+	due, err := time.Parse(time.RFC3339, dueAt)
+	if err != nil {
+		return noDueAt
+	}
+
+	return due.UTC().Format(createdAtLayout)
+}
+
+// fieldValue returns the value of field for filtering, or false if task
+// has none.
+func fieldValue(task *models.Task, field string) (string, bool) {
+	switch field {
+	case "id":
+		return task.ID, true
+	case "projectId":
+		return task.ProjectID, true
+	case "parentId":
+		return task.ParentID, true
+	case "activeAt":
+		return task.ActiveAt, true
+	case "title":
+		return task.Title, true
+	case "description":
+		return task.Description, true
+	case "createdAt":
+		return task.CreatedAt.UTC().Format(createdAtLayout), true
+	case "status":
+		return task.Status, true
+	case "priority":
+		return task.Priority, true
+	case "dueAt":
+		due := dueValue(task.DueAt)
+		return due, due != noDueAt
+	}
+
+	return "", false
+}
+
+// sortValue returns the value of field for ordering and cursors. Priorities
+// order by rank rather than by name, and tasks without a dueAt last.
+func sortValue(task *models.Task, field string) string {
+	switch field {
+	case "priority":
+		rank := slices.Index(models.Priorities, task.Priority)
+		if rank < 0 {
+			rank = slices.Index(models.Priorities, models.PriorityNormal)
+		}
+		return strconv.Itoa(rank)
+	case "dueAt":
+		return dueValue(task.DueAt)
+	}
+
+	value, _ := fieldValue(task, field)
+
+	return value
+}
+
+// compare orders a task, given by its sort values and ID, against another.
+func (s Sort) compare(aValues []string, aID string, bValues []string, bID string) int {
+	for i, key := range s {
+		c := strings.Compare(aValues[i], bValues[i])
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	return strings.Compare(aID, bID)
+}
+
+func (s Sort) values(task *models.Task) []string {
+	values := make([]string, len(s))
+	for i, key := range s {
+		values[i] = sortValue(task, key.Field)
+	}
+
+	return values
+}
+
+// sortTasks orders tasks by s, then by ID.
+func sortTasks(tasks []*models.Task, s Sort) {
+	values := make(map[string][]string, len(tasks))
+	for _, task := range tasks {
+		values[task.ID] = s.values(task)
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		return s.compare(values[a.ID], a.ID, values[b.ID], b.ID) < 0
+	})
+}
+
+// Cursor marks a position in a listing. Clients see it only as the opaque
+// string produced by Encode.
+type Cursor struct {
+	// Sort is the order the cursor was issued for.
+	Sort string `json:"sort,omitempty"`
+	// Values holds the task's value for each sort key.
+	Values []string `json:"values,omitempty"`
+	ID     string   `json:"id"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorAfter returns the cursor positioned at task in a listing ordered by s.
+func CursorAfter(task *models.Task, s Sort) *Cursor {
+	return &Cursor{Sort: s.String(), Values: s.values(task), ID: task.ID}
+}
+
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// Matches reports whether c was issued for a listing ordered by s.
+func (c *Cursor) Matches(s Sort) bool {
+	return c.Sort == s.String() && len(c.Values) == len(s)
+}
+
+// matches reports whether task satisfies q's filters.
+func (q TaskQuery) matches(task *models.Task) bool {
+	if q.ProjectID != "" && task.ProjectID != q.ProjectID {
+		return false
+	}
+
+	if len(q.ParentIDs) > 0 && !slices.Contains(q.ParentIDs, task.ParentID) {
+		return false
+	}
+
+	if len(q.Statuses) > 0 && !slices.Contains(q.Statuses, task.Status) {
+		return false
+	}
+
+	if q.DueBefore != nil && dueValue(task.DueAt) >= q.DueBefore.UTC().Format(createdAtLayout) {
+		return false
+	}
+
+	hasTag := func(tag string) bool { return slices.Contains(task.Tags, tag) }
+	if len(q.AnyTags) > 0 && !slices.ContainsFunc(q.AnyTags, hasTag) {
+		return false
+	}
+	for _, tag := range q.AllTags {
+		if !hasTag(tag) {
+			return false
+		}
+	}
+	if slices.ContainsFunc(q.NoTags, hasTag) {
+		return false
+	}
+
+	return filter.Match(q.Filter, func(field string) (string, bool) {
+		return fieldValue(task, field)
+	})
+}
+
+// paginate applies q's cursor, offset and limit to tasks, which must already
+// be filtered and ordered by q.Sort.
+func paginate(tasks []*models.Task, q TaskQuery) *TaskPage {
+	page := &TaskPage{Total: len(tasks)}
+
+	if q.After != nil {
+		start := len(tasks)
+		for i, task := range tasks {
+			if q.Sort.compare(q.Sort.values(task), task.ID, q.After.Values, q.After.ID) > 0 {
+				start = i
+				break
+			}
+		}
+		tasks = tasks[start:]
+	}
+
+	if q.Offset >= len(tasks) {
+		tasks = nil
+	} else {
+		tasks = tasks[q.Offset:]
+	}
+
+	if q.Limit > 0 && q.Limit < len(tasks) {
+		tasks = tasks[:q.Limit]
+	}
+
+	page.Tasks = tasks
+
+	return page
+}
+
+// parseCreatedAt reads a timestamp stored with createdAtLayout; legacy rows
+// without one yield the zero time.
+func parseCreatedAt(s string) time.Time {
+	t, err := time.Parse(createdAtLayout, s)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t
+}
@@ -0,0 +1,1487 @@
+// Package repotest is a conformance suite for repositories.TaskRepo.
+//
+// A backend runs it from its own tests:
+//
+//	func TestMyRepo(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) repositories.TaskRepo {
+//			return NewMyRepo(...)
+//		})
+//	}
+//
+// NewRepo is called once per subtest and must return an empty repository.
+// Run the suite with -race to exercise the concurrency checks.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/filter"
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/canyouhearthemusic/todo-list/internal/repositories"
+	"github.com/google/uuid"
+)
+
+type NewRepo func(t *testing.T) repositories.TaskRepo
+
+func Run(t *testing.T, newRepo NewRepo) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo repositories.TaskRepo)
+	}{
+		{"PostAndGet", testPostAndGet},
+		{"NotFound", testNotFound},
+		{"DuplicateTitle", testDuplicateTitle},
+		{"All", testAll},
+		{"ListByStatus", testListByStatus},
+		{"ListOffsetLimit", testListOffsetLimit},
+		{"ListCursor", testListCursor},
+		{"ListSorted", testListSorted},
+		{"ListSortedCursor", testListSortedCursor},
+		{"ListFilter", testListFilter},
+		{"PriorityAndDue", testPriorityAndDue},
+		{"Description", testDescription},
+		{"Subtasks", testSubtasks},
+		{"BlockedBy", testBlockedBy},
+		{"Recurrence", testRecurrence},
+		{"Tags", testTags},
+		{"Projects", testProjects},
+		{"ProjectScopedTitles", testProjectScopedTitles},
+		{"RenameTag", testRenameTag},
+		{"PutPreservesID", testPutPreservesID},
+		{"PutDuplicateTitle", testPutDuplicateTitle},
+		{"MarkAsDone", testMarkAsDone},
+		{"StatusChange", testStatusChange},
+		{"Versioning", testVersioning},
+		{"ConcurrentVersionedPut", testConcurrentVersionedPut},
+		{"Delete", testDelete},
+		{"Atomically", testAtomically},
+		{"AtomicallyNested", testAtomicallyNested},
+		{"AtomicallyIsolated", testAtomicallyIsolated},
+		{"Batch", testBatch},
+		{"ReturnedTasksAreCopies", testReturnedTasksAreCopies},
+		{"ConcurrentAccess", testConcurrentAccess},
+		{"ConcurrentDuplicatePost", testConcurrentDuplicatePost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+// NewTask returns a task with a fresh ID, ready to be posted.
+func NewTask(title, activeAt string) *models.Task {
+	return &models.Task{
+		ID:       uuid.New().String(),
+		Title:    title,
+		ActiveAt: activeAt,
+	}
+}
+
+func mustPost(t *testing.T, repo repositories.TaskRepo, title, activeAt string) *models.Task {
+	t.Helper()
+
+	task := NewTask(title, activeAt)
+	if err := repo.Post(context.Background(), task); err != nil {
+		t.Fatalf("Post(%q): %v", title, err)
+	}
+
+	return task
+}
+
+func mustGet(t *testing.T, repo repositories.TaskRepo, id string) *models.Task {
+	t.Helper()
+
+	task, err := repo.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetByID(%q): %v", id, err)
+	}
+
+	return task
+}
+
+func testPostAndGet(t *testing.T, repo repositories.TaskRepo) {
+	posted := mustPost(t, repo, "write report", "2024-05-06")
+
+	if posted.Status != "active" {
+		t.Errorf("Post set status %q, want %q", posted.Status, "active")
+	}
+	if posted.CreatedAt.IsZero() {
+		t.Error("Post did not set createdAt")
+	}
+
+	got := mustGet(t, repo, posted.ID)
+	if !reflect.DeepEqual(got, posted) {
+		t.Errorf("GetByID = %+v, want %+v", *got, *posted)
+	}
+}
+
+func testNotFound(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+	missing := uuid.New().String()
+
+	if _, err := repo.GetByID(ctx, missing); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetByID of a missing task: got %v, want ErrNotFound", err)
+	}
+
+	if err := repo.Put(ctx, missing, NewTask("x", "2024-05-06"), 0); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Put of a missing task: got %v, want ErrNotFound", err)
+	}
+
+	if err := repo.MarkAsDone(ctx, missing, 0); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("MarkAsDone of a missing task: got %v, want ErrNotFound", err)
+	}
+
+	if err := repo.Delete(ctx, missing, 0); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Delete of a missing task: got %v, want ErrNotFound", err)
+	}
+}
+
+func testDuplicateTitle(t *testing.T, repo repositories.TaskRepo) {
+	mustPost(t, repo, "same", "2024-05-06")
+
+	err := repo.Post(context.Background(), NewTask("same", "2024-05-07"))
+	if !errors.Is(err, models.ErrDuplicateTitle) {
+		t.Fatalf("Post with a duplicate title: got %v, want ErrDuplicateTitle", err)
+	}
+	if !errors.Is(err, models.ErrConflict) {
+		t.Errorf("ErrDuplicateTitle must also match ErrConflict")
+	}
+
+	tasks, err := repo.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tasks) != 1 {
+		t.Errorf("All returned %d tasks after rejected duplicate, want 1", len(tasks))
+	}
+}
+
+func newProject(t *testing.T, repo repositories.TaskRepo, name string) *models.Project {
+	t.Helper()
+
+	project := &models.Project{ID: uuid.New().String(), Name: name}
+	if err := repo.PostProject(context.Background(), project); err != nil {
+		t.Fatalf("PostProject(%q): %v", name, err)
+	}
+
+	return project
+}
+
+func testProjects(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+
+	if _, err := repo.GetProject(ctx, models.DefaultProjectID); err != nil {
+		t.Fatalf("default project: %v", err)
+	}
+
+	work := newProject(t, repo, "work")
+	if work.Version != 1 || work.CreatedAt.IsZero() {
+		t.Errorf("PostProject stored version %d, createdAt %v", work.Version, work.CreatedAt)
+	}
+	if err := repo.PostProject(ctx, &models.Project{ID: uuid.New().String(), Name: "work"}); !errors.Is(err, models.ErrDuplicateProjectName) {
+		t.Errorf("PostProject with a duplicate name: got %v, want ErrDuplicateProjectName", err)
+	}
+
+	renamed := &models.Project{Name: "job", Description: "9 to 5"}
+	if err := repo.PutProject(ctx, work.ID, renamed, work.Version); err != nil {
+		t.Fatal(err)
+	}
+	if renamed.ID != work.ID || renamed.Version != 2 || !renamed.CreatedAt.Equal(work.CreatedAt) {
+		t.Errorf("PutProject stored %+v", *renamed)
+	}
+	if err := repo.PutProject(ctx, work.ID, &models.Project{Name: "late"}, work.Version); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Errorf("PutProject at a stale version: got %v, want ErrVersionMismatch", err)
+	}
+	if err := repo.PutProject(ctx, uuid.New().String(), &models.Project{Name: "x"}, 0); !errors.Is(err, models.ErrProjectNotFound) {
+		t.Errorf("PutProject of a missing project: got %v, want ErrProjectNotFound", err)
+	}
+
+	projects, err := repo.Projects(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 2 || projects[0].ID != models.DefaultProjectID || projects[1].Name != "job" {
+		t.Errorf("Projects = %v, want the default project, then job", projects)
+	}
+
+	task := NewTask("file taxes", "2024-05-06")
+	task.ProjectID = work.ID
+	if err := repo.Post(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.DeleteProject(ctx, work.ID, 0); !errors.Is(err, models.ErrProjectNotEmpty) {
+		t.Errorf("DeleteProject with tasks: got %v, want ErrProjectNotEmpty", err)
+	}
+	if err := repo.DeleteProject(ctx, models.DefaultProjectID, 0); !errors.Is(err, models.ErrDefaultProject) {
+		t.Errorf("DeleteProject of the default project: got %v, want ErrDefaultProject", err)
+	}
+
+	if err := repo.Delete(ctx, task.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteProject(ctx, work.ID, 0); err != nil {
+		t.Fatalf("DeleteProject once empty: %v", err)
+	}
+	if _, err := repo.GetProject(ctx, work.ID); !errors.Is(err, models.ErrProjectNotFound) {
+		t.Errorf("GetProject after DeleteProject: got %v, want ErrProjectNotFound", err)
+	}
+
+	if err := repo.Post(ctx, task); !errors.Is(err, models.ErrProjectNotFound) {
+		t.Errorf("Post into a deleted project: got %v, want ErrProjectNotFound", err)
+	}
+}
+
+func testProjectScopedTitles(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+	home := newProject(t, repo, "home")
+
+	inbox := mustPost(t, repo, "groceries", "2024-05-06")
+	if inbox.ProjectID != models.DefaultProjectID {
+		t.Errorf("Post without a project stored project %q, want %q", inbox.ProjectID, models.DefaultProjectID)
+	}
+
+	other := NewTask("groceries", "2024-05-06")
+	other.ProjectID = home.ID
+	if err := repo.Post(ctx, other); err != nil {
+		t.Fatalf("Post of a title taken in another project: %v", err)
+	}
+
+	page, err := repo.List(ctx, repositories.TaskQuery{ProjectID: home.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids(page.Tasks) != other.ID || page.Total != 1 {
+		t.Errorf("List of the project = %q, want only %q", ids(page.Tasks), other.ID)
+	}
+
+	// Moving a task is a Put with another project.
+	moved := *inbox
+	moved.ProjectID = home.ID
+	if err := repo.Put(ctx, inbox.ID, &moved, 0); !errors.Is(err, models.ErrDuplicateTitle) {
+		t.Errorf("moving onto a taken title: got %v, want ErrDuplicateTitle", err)
+	}
+
+	moved.Title = "more groceries"
+	if err := repo.Put(ctx, inbox.ID, &moved, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, repo, inbox.ID); got.ProjectID != home.ID {
+		t.Errorf("moved task is in project %q, want %q", got.ProjectID, home.ID)
+	}
+
+	moved.ProjectID = uuid.New().String()
+	if err := repo.Put(ctx, inbox.ID, &moved, 0); !errors.Is(err, models.ErrProjectNotFound) {
+		t.Errorf("moving to a missing project: got %v, want ErrProjectNotFound", err)
+	}
+}
+
+func testAll(t *testing.T, repo repositories.TaskRepo) {
+	want := map[string]bool{}
+	for i := 0; i < 5; i++ {
+		want[mustPost(t, repo, fmt.Sprintf("task %d", i), "2024-05-06").ID] = true
+	}
+
+	tasks, err := repo.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tasks) != len(want) {
+		t.Fatalf("All returned %d tasks, want %d", len(tasks), len(want))
+	}
+
+	for _, task := range tasks {
+		if !want[task.ID] {
+			t.Errorf("All returned unexpected task %q", task.ID)
+		}
+	}
+}
+
+func testListByStatus(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+
+	mustPost(t, repo, "one", "2024-05-06")
+	mustPost(t, repo, "two", "2024-05-06")
+	done := mustPost(t, repo, "three", "2024-05-06")
+
+	if err := repo.MarkAsDone(ctx, done.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	active, err := repo.List(ctx, repositories.TaskQuery{Statuses: []string{"active"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active.Tasks) != 2 || active.Total != 2 {
+		t.Errorf("List(active) returned %d tasks, total %d; want 2, 2", len(active.Tasks), active.Total)
+	}
+
+	finished, err := repo.List(ctx, repositories.TaskQuery{Statuses: []string{"done"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(finished.Tasks) != 1 || finished.Tasks[0].ID != done.ID {
+		t.Errorf("List(done) = %v, want only %q", finished.Tasks, done.ID)
+	}
+
+	// Post keeps a status it is given.
+	review := NewTask("four", "2024-05-06")
+	review.Status = "review"
+	if err := repo.Post(ctx, review); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, repo, review.ID); got.Status != "review" {
+		t.Errorf("Post stored status %q, want review", got.Status)
+	}
+
+	some, err := repo.List(ctx, repositories.TaskQuery{Statuses: []string{"review", "done"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if some.Total != 2 {
+		t.Errorf("List(review, done) total = %d, want 2", some.Total)
+	}
+
+	all, err := repo.List(ctx, repositories.TaskQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all.Total != 4 {
+		t.Errorf("List() total = %d, want 4", all.Total)
+	}
+}
+
+func testListOffsetLimit(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+
+	for i := 0; i < 7; i++ {
+		mustPost(t, repo, fmt.Sprintf("task %d", i), "2024-05-06")
+	}
+
+	all, err := repo.List(ctx, repositories.TaskQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := repo.List(ctx, repositories.TaskQuery{Offset: 2, Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if page.Total != 7 {
+		t.Errorf("total = %d, want 7", page.Total)
+	}
+	if got, want := ids(page.Tasks), ids(all.Tasks[2:5]); got != want {
+		t.Errorf("offset 2 limit 3 = %s, want %s", got, want)
+	}
+
+	past, err := repo.List(ctx, repositories.TaskQuery{Offset: 10, Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(past.Tasks) != 0 || past.Total != 7 {
+		t.Errorf("offset past the end returned %d tasks, total %d", len(past.Tasks), past.Total)
+	}
+}
+
+func testListCursor(t *testing.T, repo repositories.TaskRepo) {
+	for i := 0; i < 7; i++ {
+		mustPost(t, repo, fmt.Sprintf("task %d", i), "2024-05-06")
+	}
+
+	walkCursor(t, repo, nil)
+}
+
+func testListSorted(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+
+	c := mustPost(t, repo, "c", "2024-05-01")
+	a := mustPost(t, repo, "a", "2024-05-03")
+	b := mustPost(t, repo, "b", "2024-05-01")
+	d := mustPost(t, repo, "d", "2024-05-02")
+
+	if err := repo.MarkAsDone(ctx, b.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		sort string
+		want []*models.Task
+	}{
+		{"title", []*models.Task{a, b, c, d}},
+		{"-title", []*models.Task{d, c, b, a}},
+		{"activeAt,title", []*models.Task{b, c, d, a}},
+		{"activeAt,-title", []*models.Task{c, b, d, a}},
+		{"-activeAt,title", []*models.Task{a, d, b, c}},
+		{"status,-activeAt", []*models.Task{a, d, c, b}},
+		{"createdAt", []*models.Task{c, a, b, d}},
+	}
+
+	for _, tt := range tests {
+		s, err := repositories.ParseSort(tt.sort)
+		if err != nil {
+			t.Fatalf("ParseSort(%q): %v", tt.sort, err)
+		}
+
+		page, err := repo.List(ctx, repositories.TaskQuery{Sort: s})
+		if err != nil {
+			t.Fatalf("List(sort=%s): %v", tt.sort, err)
+		}
+
+		if got, want := titles(page.Tasks), titles(tt.want); got != want {
+			t.Errorf("sort=%s: got %s, want %s", tt.sort, got, want)
+		}
+	}
+}
+
+func testListFilter(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+
+	report := mustPost(t, repo, "Quarterly report", "2024-05-02")
+	cyrillic := mustPost(t, repo, "Годовой ОТЧЁТ", "2024-05-10")
+	old := mustPost(t, repo, "old report", "2024-04-01")
+	other := mustPost(t, repo, "groceries", "2024-05-03")
+
+	if err := repo.MarkAsDone(ctx, old.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	title, _ := repositories.ParseSort("title")
+
+	tests := []struct {
+		filter string
+		status string
+		want   []*models.Task
+	}{
+		{`activeAt>=2024-05-01 AND title~"REPORT" AND status IN (active,done)`, "", []*models.Task{report}},
+		{"title~отчёт", "", []*models.Task{cyrillic}},
+		{"title~report", "", []*models.Task{report, old}},
+		{"title~report", "active", []*models.Task{report}},
+		{"NOT title~report AND activeAt < 2024-05-05", "", []*models.Task{other}},
+		{"status = done OR activeAt > 2024-05-09", "", []*models.Task{old, cyrillic}},
+		{"status NOT IN (done) AND (activeAt = 2024-05-02 OR activeAt = 2024-05-03)", "", []*models.Task{report, other}},
+		{"id = " + other.ID, "", []*models.Task{other}},
+		{"createdAt >= 2000-01-01", "done", []*models.Task{old}},
+		{"createdAt < 2000-01-01", "", nil},
+	}
+
+	for _, tt := range tests {
+		expr, err := filter.Parse(tt.filter, repositories.TaskFilterSchema)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.filter, err)
+		}
+
+		var statuses []string
+		if tt.status != "" {
+			statuses = []string{tt.status}
+		}
+
+		page, err := repo.List(ctx, repositories.TaskQuery{Statuses: statuses, Filter: expr, Sort: title})
+		if err != nil {
+			t.Fatalf("List(%q): %v", tt.filter, err)
+		}
+
+		if got, want := titles(page.Tasks), titles(tt.want); got != want {
+			t.Errorf("filter %q status %q: got [%s], want [%s]", tt.filter, tt.status, got, want)
+		}
+		if page.Total != len(tt.want) {
+			t.Errorf("filter %q status %q: total = %d, want %d", tt.filter, tt.status, page.Total, len(tt.want))
+		}
+	}
+}
+
+// testListSortedCursor walks a listing whose sort keys tie, so the cursor
+// has to fall back to the ID to make progress.
+func testListSortedCursor(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+
+	for i := 0; i < 9; i++ {
+		task := mustPost(t, repo, fmt.Sprintf("task %d", i), fmt.Sprintf("2024-05-0%d", 1+i%3))
+		if i%2 == 0 {
+			if err := repo.MarkAsDone(ctx, task.ID, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, sort := range []string{"activeAt", "-activeAt", "status,-activeAt", "-status,activeAt,-title"} {
+		s, err := repositories.ParseSort(sort)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		walkCursor(t, repo, s)
+	}
+
+	// A cursor from one order is meaningless in another.
+	page, err := repo.List(ctx, repositories.TaskQuery{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorted, _ := repositories.ParseSort("title")
+	q := repositories.TaskQuery{Sort: sorted, After: repositories.CursorAfter(page.Tasks[0], nil)}
+	if _, err := repo.List(ctx, q); !errors.Is(err, repositories.ErrInvalidCursor) {
+		t.Errorf("List with a cursor for another sort: got %v, want ErrInvalidCursor", err)
+	}
+}
+
+func testPriorityAndDue(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+
+	post := func(title, priority, dueAt string) *models.Task {
+		t.Helper()
+		task := NewTask(title, "2024-05-06")
+		task.Priority, task.DueAt = priority, dueAt
+		if err := repo.Post(ctx, task); err != nil {
+			t.Fatalf("Post(%q): %v", title, err)
+		}
+		return task
+	}
+
+	// 18:00 in Almaty is 13:00 UTC, before noon in New York (16:00 UTC).
+	urgent := post("urgent", models.PriorityUrgent, "2024-05-10T18:00:00+05:00")
+	low := post("low", models.PriorityLow, "2024-05-10T12:00:00-04:00")
+	normal := post("normal", "", "")
+	high := post("high", models.PriorityHigh, "2024-05-09T00:00:00Z")
+
+	got := mustGet(t, repo, urgent.ID)
+	if got.DueAt != "2024-05-10T18:00:00+05:00" || got.Priority != models.PriorityUrgent {
+		t.Errorf("stored priority %q, dueAt %q; want them as posted", got.Priority, got.DueAt)
+	}
+	if got := mustGet(t, repo, normal.ID); got.Priority != models.PriorityNormal {
+		t.Errorf("task posted without a priority has %q, want normal", got.Priority)
+	}
+
+	tests := []struct {
+		sort   string
+		filter string
+		want   []*models.Task
+	}{
+		{"-priority", "", []*models.Task{urgent, high, normal, low}},
+		{"priority", "priority IN (low,urgent)", []*models.Task{low, urgent}},
+		// Tasks without a due time come last.
+		{"dueAt", "", []*models.Task{high, urgent, low, normal}},
+		{"dueAt", "dueAt < 2024-05-10T14:00:00Z", []*models.Task{high, urgent}},
+		// Filters never match a missing due time, negated or not.
+		{"title", "dueAt >= 2024-05-10", []*models.Task{low, urgent}},
+		{"title", "NOT dueAt < 2024-05-10", []*models.Task{low, urgent}},
+		{"title", "dueAt != 2024-05-09", []*models.Task{low, urgent}},
+		{"title", "dueAt > 2024-05-01 OR priority = normal", []*models.Task{high, low, normal, urgent}},
+	}
+
+	for _, tt := range tests {
+		order, err := repositories.ParseSort(tt.sort)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expr, err := filter.Parse(tt.filter, repositories.TaskFilterSchema)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.filter, err)
+		}
+
+		page, err := repo.List(ctx, repositories.TaskQuery{Filter: expr, Sort: order})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := titles(page.Tasks), titles(tt.want); got != want {
+			t.Errorf("sort %q filter %q: got [%s], want [%s]", tt.sort, tt.filter, got, want)
+		}
+
+		walkCursor(t, repo, order)
+	}
+
+	before := time.Date(2024, 5, 10, 15, 0, 0, 0, time.UTC)
+	page, err := repo.List(ctx, repositories.TaskQuery{DueBefore: &before, Sort: repositories.Sort{{Field: "dueAt"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := titles(page.Tasks), titles([]*models.Task{high, urgent}); got != want {
+		t.Errorf("due before %v: got [%s], want [%s]", before, got, want)
+	}
+
+	// A PUT without a priority resets it, and can drop the due time.
+	high.Priority, high.DueAt = "", ""
+	if err := repo.Put(ctx, high.ID, high, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, repo, high.ID); got.Priority != models.PriorityNormal || got.DueAt != "" {
+		t.Errorf("after Put: priority %q, dueAt %q", got.Priority, got.DueAt)
+	}
+}
+
+func testDescription(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+
+	task := NewTask("with details", "2024-05-06")
+	task.Description = "# Steps\n\n1. Collect *Q2* numbers\n2. Ask <Алия>\n"
+	if err := repo.Post(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	mustPost(t, repo, "without details", "2024-05-06")
+
+	if got := mustGet(t, repo, task.ID); got.Description != task.Description {
+		t.Errorf("stored description %q, want it unchanged", got.Description)
+	}
+
+	expr, err := filter.Parse("description~алия", repositories.TaskFilterSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := repo.List(ctx, repositories.TaskQuery{Filter: expr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := titles(page.Tasks), "with details"; got != want {
+		t.Errorf("description filter: got [%s], want [%s]", got, want)
+	}
+
+	task.Description = ""
+	if err := repo.Put(ctx, task.ID, task, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, repo, task.ID); got.Description != "" {
+		t.Errorf("description after clearing it: %q", got.Description)
+	}
+}
+
+func testSubtasks(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+
+	parent := mustPost(t, repo, "parent", "2024-05-06")
+	parent.Checklist = []models.ChecklistItem{{ID: "a", Text: "first"}, {ID: "b", Text: "second", Done: true}}
+	if err := repo.Put(ctx, parent.ID, parent, 0); err != nil {
+		t.Fatal(err)
+	}
+	other := mustPost(t, repo, "other parent", "2024-05-06")
+
+	for _, title := range []string{"child 1", "child 2"} {
+		child := NewTask(title, "2024-05-06")
+		child.ParentID = parent.ID
+		if err := repo.Post(ctx, child); err != nil {
+			t.Fatal(err)
+		}
+	}
+	third := NewTask("child 3", "2024-05-06")
+	third.ParentID = other.ID
+	if err := repo.Post(ctx, third); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := mustGet(t, repo, parent.ID); !reflect.DeepEqual(got.Checklist, parent.Checklist) {
+		t.Errorf("stored checklist %+v, want %+v", got.Checklist, parent.Checklist)
+	}
+	if got := mustGet(t, repo, third.ID); got.ParentID != other.ID {
+		t.Errorf("stored parentId %q, want %q", got.ParentID, other.ID)
+	}
+
+	page, err := repo.List(ctx, repositories.TaskQuery{ParentIDs: []string{parent.ID}, Sort: repositories.Sort{{Field: "title"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := titles(page.Tasks), "child 1,child 2"; got != want {
+		t.Errorf("subtasks of one parent: got [%s], want [%s]", got, want)
+	}
+
+	page, err = repo.List(ctx, repositories.TaskQuery{ParentIDs: []string{parent.ID, other.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 {
+		t.Errorf("subtasks of two parents: got %d, want 3", page.Total)
+	}
+
+	expr, err := filter.Parse(`parentId=""`, repositories.TaskFilterSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err = repo.List(ctx, repositories.TaskQuery{Filter: expr, Sort: repositories.Sort{{Field: "title"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := titles(page.Tasks), "other parent,parent"; got != want {
+		t.Errorf("top-level tasks: got [%s], want [%s]", got, want)
+	}
+
+	orphan := NewTask("orphan", "2024-05-06")
+	orphan.ParentID = "missing"
+	if err := repo.Post(ctx, orphan); !errors.Is(err, models.ErrParentNotFound) {
+		t.Errorf("Post under a missing parent: got %v, want ErrParentNotFound", err)
+	}
+	third.ParentID = "missing"
+	if err := repo.Put(ctx, third.ID, third, 0); !errors.Is(err, models.ErrParentNotFound) {
+		t.Errorf("Put under a missing parent: got %v, want ErrParentNotFound", err)
+	}
+
+	if err := repo.Delete(ctx, other.ID, 0); !errors.Is(err, models.ErrHasSubtasks) {
+		t.Errorf("Delete of a parent: got %v, want ErrHasSubtasks", err)
+	}
+	if err := repo.Delete(ctx, third.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, other.ID, 0); err != nil {
+		t.Errorf("Delete of a parent whose subtasks are gone: %v", err)
+	}
+}
+
+func testBlockedBy(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+
+	first := mustPost(t, repo, "first", "2024-05-06")
+	second := mustPost(t, repo, "second", "2024-05-06")
+
+	task := NewTask("blocked", "2024-05-06")
+	task.BlockedBy = []string{second.ID, first.ID}
+	if err := repo.Post(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, repo, task.ID); !slices.Equal(got.BlockedBy, task.BlockedBy) {
+		t.Errorf("stored blockedBy %v, want %v", got.BlockedBy, task.BlockedBy)
+	}
+
+	task.BlockedBy = nil
+	if err := repo.Put(ctx, task.ID, task, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, repo, task.ID); got.BlockedBy != nil {
+		t.Errorf("blockedBy after clearing it: %v", got.BlockedBy)
+	}
+}
+
+func testRecurrence(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+
+	recurrence := models.Recurrence{Rule: "FREQ=WEEKLY;BYDAY=MO", Start: "2024-05-06", Occurrence: 2, Scheduled: "2024-05-13"}
+	task := NewTask("standup", "2024-05-14")
+	task.Recurrence = &recurrence
+	if err := repo.Post(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, repo, task.ID); got.Recurrence == nil || *got.Recurrence != recurrence {
+		t.Errorf("stored recurrence %+v, want %+v", got.Recurrence, recurrence)
+	}
+
+	task.Recurrence = nil
+	if err := repo.Put(ctx, task.ID, task, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, repo, task.ID); got.Recurrence != nil {
+		t.Errorf("recurrence after clearing it: %+v", got.Recurrence)
+	}
+
+	if plain := mustPost(t, repo, "plain", "2024-05-06"); mustGet(t, repo, plain.ID).Recurrence != nil {
+		t.Error("a task posted without recurrence has one")
+	}
+}
+
+func postTagged(t *testing.T, repo repositories.TaskRepo, title string, tags ...string) *models.Task {
+	t.Helper()
+
+	task := NewTask(title, "2024-05-06")
+	task.Tags = tags
+	if err := repo.Post(context.Background(), task); err != nil {
+		t.Fatalf("Post(%q): %v", title, err)
+	}
+
+	return task
+}
+
+func testTags(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+
+	postTagged(t, repo, "a", "backend", "urgent")
+	postTagged(t, repo, "b", "backend")
+	postTagged(t, repo, "c", "frontend", "urgent")
+	postTagged(t, repo, "d")
+
+	if got := mustGet(t, repo, mustPost(t, repo, "e", "2024-05-06").ID).Tags; got != nil {
+		t.Errorf("untagged task has tags %q, want nil", got)
+	}
+
+	tests := []struct {
+		name string
+		q    repositories.TaskQuery
+		want string
+	}{
+		{"any", repositories.TaskQuery{AnyTags: []string{"frontend", "urgent"}}, "a,c"},
+		{"all", repositories.TaskQuery{AllTags: []string{"backend", "urgent"}}, "a"},
+		{"none", repositories.TaskQuery{NoTags: []string{"backend"}}, "c,d,e"},
+		{"combined", repositories.TaskQuery{AnyTags: []string{"backend"}, NoTags: []string{"urgent"}}, "b"},
+		{"unknown", repositories.TaskQuery{AllTags: []string{"backend", "nope"}}, ""},
+	}
+
+	for _, tt := range tests {
+		tt.q.Sort = repositories.Sort{{Field: "title"}}
+		page, err := repo.List(ctx, tt.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := titles(page.Tasks); got != tt.want {
+			t.Errorf("%s: List = %q, want %q", tt.name, got, tt.want)
+		}
+		if page.Total != len(page.Tasks) {
+			t.Errorf("%s: Total = %d, want %d", tt.name, page.Total, len(page.Tasks))
+		}
+	}
+
+	tags, err := repo.Tags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.Tag{{Name: "backend", Count: 2}, {Name: "urgent", Count: 2}, {Name: "frontend", Count: 1}}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("Tags = %v, want %v", tags, want)
+	}
+}
+
+func testRenameTag(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+
+	a := postTagged(t, repo, "a", "backend", "be")
+	b := postTagged(t, repo, "b", "be", "urgent")
+	c := postTagged(t, repo, "c", "frontend")
+
+	if _, err := repo.RenameTag(ctx, "be", "backend", false); !errors.Is(err, models.ErrTagExists) {
+		t.Errorf("RenameTag onto a tag in use without merging: got %v, want ErrTagExists", err)
+	}
+	if got := mustGet(t, repo, b.ID); got.Version != b.Version {
+		t.Errorf("refused rename moved b to version %d", got.Version)
+	}
+
+	renamed, err := repo.RenameTag(ctx, "be", "backend", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.SortFunc(renamed, func(x, y *models.Task) int { return strings.Compare(x.Title, y.Title) })
+	if got := titles(renamed); got != "a,b" {
+		t.Errorf("RenameTag returned %q, want %q", got, "a,b")
+	}
+
+	if got := mustGet(t, repo, a.ID); !reflect.DeepEqual(got.Tags, []string{"backend"}) || got.Version != a.Version+1 {
+		t.Errorf("a has tags %q at version %d, want [backend] at %d", got.Tags, got.Version, a.Version+1)
+	}
+	if got := mustGet(t, repo, b.ID); !reflect.DeepEqual(got.Tags, []string{"backend", "urgent"}) {
+		t.Errorf("b has tags %q, want [backend urgent]", got.Tags)
+	}
+	if got := mustGet(t, repo, c.ID); got.Version != c.Version {
+		t.Errorf("untouched task moved to version %d", got.Version)
+	}
+
+	renamed, err = repo.RenameTag(ctx, "nope", "other", false)
+	if err != nil || len(renamed) != 0 {
+		t.Errorf("RenameTag of an unused tag = %v, %v; want nothing", renamed, err)
+	}
+}
+
+// walkCursor pages through the whole listing ordered by s, three tasks at a
+// time, and checks it visits the same tasks in the same order as a single
+// unpaginated List.
+func walkCursor(t *testing.T, repo repositories.TaskRepo, s repositories.Sort) {
+	t.Helper()
+	ctx := context.Background()
+
+	all, err := repo.List(ctx, repositories.TaskQuery{Sort: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var walked []*models.Task
+	q := repositories.TaskQuery{Sort: s, Limit: 3}
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatalf("sort=%s: cursor pagination does not terminate", s)
+		}
+
+		page, err := repo.List(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != len(all.Tasks) {
+			t.Errorf("sort=%s: total with cursor = %d, want %d", s, page.Total, len(all.Tasks))
+		}
+
+		walked = append(walked, page.Tasks...)
+		if len(page.Tasks) < q.Limit {
+			break
+		}
+
+		q.After = repositories.CursorAfter(page.Tasks[len(page.Tasks)-1], s)
+	}
+
+	if got, want := ids(walked), ids(all.Tasks); got != want {
+		t.Errorf("sort=%s: walking cursors visited %s, want %s", s, got, want)
+	}
+}
+
+func titles(tasks []*models.Task) string {
+	var out []string
+	for _, task := range tasks {
+		out = append(out, task.Title)
+	}
+
+	return strings.Join(out, ",")
+}
+
+func ids(tasks []*models.Task) string {
+	var out []string
+	for _, task := range tasks {
+		out = append(out, task.ID)
+	}
+
+	return strings.Join(out, ",")
+}
+
+func testPutPreservesID(t *testing.T, repo repositories.TaskRepo) {
+	posted := mustPost(t, repo, "before", "2024-05-06")
+
+	update := &models.Task{ID: "ignored", Title: "after", ActiveAt: "2024-05-07", Status: "active"}
+	if err := repo.Put(context.Background(), posted.ID, update, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	got := mustGet(t, repo, posted.ID)
+	if got.ID != posted.ID {
+		t.Errorf("Put changed ID to %q, want %q", got.ID, posted.ID)
+	}
+	if got.Title != "after" || got.ActiveAt != "2024-05-07" {
+		t.Errorf("Put stored %+v, want title %q and activeAt %q", *got, "after", "2024-05-07")
+	}
+	if !got.CreatedAt.Equal(posted.CreatedAt) {
+		t.Errorf("Put changed createdAt to %v, want %v", got.CreatedAt, posted.CreatedAt)
+	}
+}
+
+func testPutDuplicateTitle(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+	mustPost(t, repo, "taken", "2024-05-06")
+	other := mustPost(t, repo, "free", "2024-05-06")
+
+	update := &models.Task{Title: "taken", ActiveAt: "2024-05-06", Status: "active"}
+	if err := repo.Put(ctx, other.ID, update, 0); !errors.Is(err, models.ErrDuplicateTitle) {
+		t.Errorf("Put to another task's title: got %v, want ErrDuplicateTitle", err)
+	}
+
+	// Keeping its own title is not a conflict.
+	update = &models.Task{Title: "free", ActiveAt: "2024-05-07", Status: "active"}
+	if err := repo.Put(ctx, other.ID, update, 0); err != nil {
+		t.Errorf("Put keeping the same title: %v", err)
+	}
+}
+
+func testMarkAsDone(t *testing.T, repo repositories.TaskRepo) {
+	posted := mustPost(t, repo, "finish me", "2024-05-06")
+
+	if err := repo.MarkAsDone(context.Background(), posted.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := mustGet(t, repo, posted.ID); got.Status != "done" {
+		t.Errorf("status after MarkAsDone = %q, want %q", got.Status, "done")
+	}
+
+	if err := repo.MarkAsDone(context.Background(), posted.ID, 0); err != nil {
+		t.Errorf("MarkAsDone of a done task: %v", err)
+	}
+}
+
+func testStatusChange(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+	posted := mustPost(t, repo, "track me", "2024-05-06")
+
+	if posted.StatusChangedAt != nil || posted.StatusChangedBy != "" {
+		t.Errorf("Post recorded a status change: %v by %q", posted.StatusChangedAt, posted.StatusChangedBy)
+	}
+
+	before := time.Now().Add(-time.Second)
+	done := &models.Task{Title: "track me", ActiveAt: "2024-05-06", Status: "done", StatusChangedBy: "alice"}
+	if err := repo.Put(ctx, posted.ID, done, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	got := mustGet(t, repo, posted.ID)
+	if got.StatusChangedAt == nil || got.StatusChangedAt.Before(before) || got.StatusChangedBy != "alice" {
+		t.Fatalf("after a status change: %v by %q, want now by alice", got.StatusChangedAt, got.StatusChangedBy)
+	}
+	changedAt := *got.StatusChangedAt
+
+	renamed := &models.Task{Title: "renamed", ActiveAt: "2024-05-06", Status: "done", StatusChangedBy: "bob"}
+	if err := repo.Put(ctx, posted.ID, renamed, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, repo, posted.ID); got.StatusChangedAt == nil || !got.StatusChangedAt.Equal(changedAt) || got.StatusChangedBy != "alice" {
+		t.Errorf("Put keeping the status changed the record to %v by %q", got.StatusChangedAt, got.StatusChangedBy)
+	}
+
+	reopened := &models.Task{Title: "renamed", ActiveAt: "2024-05-06", Status: "active", StatusChangedBy: "bob"}
+	if err := repo.Put(ctx, posted.ID, reopened, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.MarkAsDone(ctx, posted.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, repo, posted.ID); got.StatusChangedAt == nil || got.StatusChangedBy != "" {
+		t.Errorf("after MarkAsDone: %v by %q, want now by nobody", got.StatusChangedAt, got.StatusChangedBy)
+	}
+}
+
+func testVersioning(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+	posted := mustPost(t, repo, "versioned", "2024-05-06")
+
+	if posted.Version != 1 {
+		t.Fatalf("Post set version %d, want 1", posted.Version)
+	}
+
+	update := &models.Task{Title: "versioned", ActiveAt: "2024-05-07", Status: "active"}
+	if err := repo.Put(ctx, posted.ID, update, 1); err != nil {
+		t.Fatalf("Put at the current version: %v", err)
+	}
+	if update.Version != 2 {
+		t.Errorf("Put set version %d, want 2", update.Version)
+	}
+
+	stale := &models.Task{Title: "clobbered", ActiveAt: "2024-05-08", Status: "active"}
+	err := repo.Put(ctx, posted.ID, stale, 1)
+	if !errors.Is(err, models.ErrVersionMismatch) || !errors.Is(err, models.ErrPreconditionFailed) {
+		t.Errorf("Put at a stale version: got %v, want ErrVersionMismatch", err)
+	}
+	if got := mustGet(t, repo, posted.ID); got.Title != "versioned" || got.Version != 2 {
+		t.Errorf("rejected Put changed the task to %+v", *got)
+	}
+
+	if err := repo.MarkAsDone(ctx, posted.ID, 1); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Errorf("MarkAsDone at a stale version: got %v, want ErrVersionMismatch", err)
+	}
+	if err := repo.MarkAsDone(ctx, posted.ID, 2); err != nil {
+		t.Errorf("MarkAsDone at the current version: %v", err)
+	}
+	if got := mustGet(t, repo, posted.ID); got.Status != "done" || got.Version != 3 {
+		t.Errorf("after MarkAsDone: status %q version %d, want done and 3", got.Status, got.Version)
+	}
+
+	if err := repo.Delete(ctx, posted.ID, 2); !errors.Is(err, models.ErrVersionMismatch) {
+		t.Errorf("Delete at a stale version: got %v, want ErrVersionMismatch", err)
+	}
+	mustGet(t, repo, posted.ID)
+
+	if err := repo.Delete(ctx, posted.ID, 3); err != nil {
+		t.Errorf("Delete at the current version: %v", err)
+	}
+	if err := repo.Delete(ctx, posted.ID, 3); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Delete of a deleted task with a version: got %v, want ErrNotFound", err)
+	}
+}
+
+// testConcurrentVersionedPut races writers that all read version 1: exactly
+// one may win.
+func testConcurrentVersionedPut(t *testing.T, repo repositories.TaskRepo) {
+	posted := mustPost(t, repo, "contended", "2024-05-06")
+
+	const writers = 16
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			update := &models.Task{Title: fmt.Sprintf("writer %d", i), ActiveAt: "2024-05-06", Status: "active"}
+			err := repo.Put(context.Background(), posted.ID, update, posted.Version)
+			switch {
+			case err == nil:
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			case !errors.Is(err, models.ErrVersionMismatch):
+				t.Error(err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("%d concurrent Puts at the same version succeeded, want exactly 1", succeeded)
+	}
+	if got := mustGet(t, repo, posted.ID); got.Version != 2 {
+		t.Errorf("version after the race = %d, want 2", got.Version)
+	}
+}
+
+func testDelete(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+	posted := mustPost(t, repo, "delete me", "2024-05-06")
+
+	if err := repo.Delete(ctx, posted.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.GetByID(ctx, posted.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetByID after Delete: got %v, want ErrNotFound", err)
+	}
+
+	if err := repo.Delete(ctx, posted.ID, 0); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("second Delete: got %v, want ErrNotFound", err)
+	}
+
+	// The title is free again.
+	mustPost(t, repo, "delete me", "2024-05-06")
+}
+
+func testAtomically(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+	kept := mustPost(t, repo, "kept", "2024-05-06")
+	created := NewTask("created", "2024-05-07")
+	failure := errors.New("changed my mind")
+
+	err := repo.Atomically(ctx, func(tx repositories.TaskRepo) error {
+		if err := tx.Post(ctx, created); err != nil {
+			return err
+		}
+		if err := tx.Put(ctx, kept.ID, &models.Task{Title: "renamed", ActiveAt: "2024-05-06", Status: "active"}, kept.Version); err != nil {
+			return err
+		}
+		if err := tx.PostProject(ctx, &models.Project{ID: "side", Name: "side"}); err != nil {
+			return err
+		}
+
+		// Reads see the writes made so far.
+		if got, err := tx.GetByID(ctx, kept.ID); err != nil || got.Title != "renamed" {
+			t.Errorf("GetByID inside the transaction = %v, %v; want it renamed", got, err)
+		}
+		if page, err := tx.List(ctx, repositories.TaskQuery{}); err != nil || page.Total != 2 {
+			t.Errorf("List inside the transaction = %v, %v; want 2 tasks", page, err)
+		}
+
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Atomically = %v, want the error of fn", err)
+	}
+
+	if got := mustGet(t, repo, kept.ID); got.Title != "kept" || got.Version != kept.Version {
+		t.Errorf("failed transaction changed the task to %+v", *got)
+	}
+	if _, err := repo.GetByID(ctx, created.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("task created by a failed transaction: got %v, want ErrNotFound", err)
+	}
+	if _, err := repo.GetProject(ctx, "side"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("project created by a failed transaction: got %v, want ErrNotFound", err)
+	}
+
+	err = repo.Atomically(ctx, func(tx repositories.TaskRepo) error {
+		if err := tx.Post(ctx, created); err != nil {
+			return err
+		}
+		return tx.Delete(ctx, kept.ID, kept.Version)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustGet(t, repo, created.ID)
+	if _, err := repo.GetByID(ctx, kept.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("task deleted by the transaction: got %v, want ErrNotFound", err)
+	}
+}
+
+func testAtomicallyNested(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+	outer := NewTask("outer", "2024-05-06")
+	inner := NewTask("inner", "2024-05-06")
+	after := NewTask("after", "2024-05-06")
+
+	err := repo.Atomically(ctx, func(tx repositories.TaskRepo) error {
+		if err := tx.Post(ctx, outer); err != nil {
+			return err
+		}
+
+		err := tx.Atomically(ctx, func(tx repositories.TaskRepo) error {
+			if err := tx.Post(ctx, inner); err != nil {
+				return err
+			}
+			return tx.Post(ctx, NewTask("outer", "2024-05-06"))
+		})
+		if !errors.Is(err, models.ErrDuplicateTitle) {
+			t.Errorf("nested Atomically = %v, want ErrDuplicateTitle", err)
+		}
+		if _, err := tx.GetByID(ctx, inner.ID); !errors.Is(err, models.ErrNotFound) {
+			t.Errorf("task of a failed nested call: got %v, want ErrNotFound", err)
+		}
+
+		return tx.Atomically(ctx, func(tx repositories.TaskRepo) error {
+			return tx.Post(ctx, after)
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustGet(t, repo, outer.ID)
+	mustGet(t, repo, after.ID)
+	if _, err := repo.GetByID(ctx, inner.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("task of a failed nested call after commit: got %v, want ErrNotFound", err)
+	}
+}
+
+// testAtomicallyIsolated checks that a reader never sees a transaction
+// half-applied: it waits for the transaction, which then fails.
+func testAtomicallyIsolated(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+	task := NewTask("uncommitted", "2024-05-06")
+	read := make(chan error, 1)
+
+	err := repo.Atomically(ctx, func(tx repositories.TaskRepo) error {
+		if err := tx.Post(ctx, task); err != nil {
+			return err
+		}
+
+		go func() {
+			_, err := repo.GetByID(ctx, task.ID)
+			read <- err
+		}()
+		time.Sleep(20 * time.Millisecond)
+
+		return errors.New("rolled back")
+	})
+	if err == nil {
+		t.Fatal("Atomically succeeded, want its error")
+	}
+
+	if err := <-read; !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("concurrent GetByID of an uncommitted task: got %v, want ErrNotFound", err)
+	}
+}
+
+func testBatch(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+	first := NewTask("first", "2024-05-06")
+	second := NewTask("second", "2024-05-06")
+
+	post := func(task *models.Task) repositories.BatchOp {
+		return func(tx repositories.TaskRepo) (*models.Task, error) {
+			posted := *task
+			return &posted, tx.Post(ctx, &posted)
+		}
+	}
+	rename := func(id, title string) repositories.BatchOp {
+		return func(tx repositories.TaskRepo) (*models.Task, error) {
+			task, err := tx.GetByID(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			task.Title = title
+			return task, tx.Put(ctx, id, task, task.Version)
+		}
+	}
+	// half posts task and then fails, so the op must change nothing.
+	half := func(task *models.Task) repositories.BatchOp {
+		return func(tx repositories.TaskRepo) (*models.Task, error) {
+			posted := *task
+			if err := tx.Post(ctx, &posted); err != nil {
+				return nil, err
+			}
+			return nil, tx.Delete(ctx, "missing", 0)
+		}
+	}
+
+	// Each op sees the ones before it; a failed one changes nothing and the
+	// others go on.
+	results, err := repo.Batch(ctx, []repositories.BatchOp{
+		post(first),
+		rename(first.ID, "first renamed"),
+		half(second),
+		post(second),
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[1].Err != nil || results[1].Task.Title != "first renamed" || results[1].Task.Version != 2 {
+		t.Errorf("rename of a task posted in the batch: %+v", results[1])
+	}
+	if !errors.Is(results[2].Err, models.ErrNotFound) || results[2].Task != nil {
+		t.Errorf("failed op: %+v, want ErrNotFound", results[2])
+	}
+	if results[0].Err != nil || results[3].Err != nil {
+		t.Errorf("ops around the failed one: %v, %v", results[0].Err, results[3].Err)
+	}
+	if got := mustGet(t, repo, first.ID); got.Title != "first renamed" {
+		t.Errorf("stored title %q, want first renamed", got.Title)
+	}
+	mustGet(t, repo, second.ID)
+
+	// In atomic mode the first failure undoes the ops before it and the
+	// ones after it are not run.
+	third := NewTask("third", "2024-05-06")
+	ran := false
+	results, err = repo.Batch(ctx, []repositories.BatchOp{
+		rename(first.ID, "first again"),
+		half(third),
+		func(tx repositories.TaskRepo) (*models.Task, error) {
+			ran = true
+			return nil, nil
+		},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(results[1].Err, models.ErrNotFound) {
+		t.Errorf("failed op of an atomic batch: %v, want ErrNotFound", results[1].Err)
+	}
+	for _, i := range []int{0, 2} {
+		if !errors.Is(results[i].Err, models.ErrBatchAborted) || results[i].Task != nil {
+			t.Errorf("op %d of an aborted batch: %+v, want ErrBatchAborted", i, results[i])
+		}
+	}
+	if ran {
+		t.Error("op after the failed one of an atomic batch ran")
+	}
+	if got := mustGet(t, repo, first.ID); got.Title != "first renamed" {
+		t.Errorf("aborted batch left the title %q", got.Title)
+	}
+	if _, err := repo.GetByID(ctx, third.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("task posted by a failed op: got %v, want ErrNotFound", err)
+	}
+
+	// A batch inside a transaction is undone with it.
+	failure := errors.New("changed my mind")
+	err = repo.Atomically(ctx, func(tx repositories.TaskRepo) error {
+		if _, err := tx.Batch(ctx, []repositories.BatchOp{post(third)}, true); err != nil {
+			return err
+		}
+		mustGet(t, tx, third.ID)
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Atomically = %v, want the error of fn", err)
+	}
+	if _, err := repo.GetByID(ctx, third.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("task of a batch in a failed transaction: got %v, want ErrNotFound", err)
+	}
+}
+
+func testReturnedTasksAreCopies(t *testing.T, repo repositories.TaskRepo) {
+	posted := mustPost(t, repo, "original", "2024-05-06")
+	posted.Title = "changed by caller"
+
+	got := mustGet(t, repo, posted.ID)
+	got.Title = "changed again"
+
+	tasks, err := repo.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks[0].Title = "and again"
+
+	if got := mustGet(t, repo, posted.ID); got.Title != "original" {
+		t.Errorf("stored title = %q, want %q; repo must not share task pointers with callers", got.Title, "original")
+	}
+}
+
+func testConcurrentAccess(t *testing.T, repo repositories.TaskRepo) {
+	ctx := context.Background()
+
+	const workers = 8
+	const perWorker = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < perWorker; i++ {
+				task := NewTask(fmt.Sprintf("worker %d task %d", w, i), "2024-05-06")
+				if err := repo.Post(ctx, task); err != nil {
+					errs <- err
+					continue
+				}
+
+				if _, err := repo.GetByID(ctx, task.ID); err != nil {
+					errs <- err
+				}
+
+				if _, err := repo.All(ctx); err != nil {
+					errs <- err
+				}
+
+				switch i % 3 {
+				case 0:
+					err := repo.MarkAsDone(ctx, task.ID, 0)
+					if err != nil {
+						errs <- err
+					}
+				case 1:
+					task.Title += " (edited)"
+					if err := repo.Put(ctx, task.ID, task, 0); err != nil {
+						errs <- err
+					}
+				case 2:
+					if err := repo.Delete(ctx, task.ID, 0); err != nil {
+						errs <- err
+					}
+				}
+			}
+		}(w)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	tasks, err := repo.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every third task per worker was deleted.
+	want := workers * (perWorker - perWorker/3)
+	if len(tasks) != want {
+		t.Errorf("All returned %d tasks after concurrent writes, want %d", len(tasks), want)
+	}
+}
+
+func testConcurrentDuplicatePost(t *testing.T, repo repositories.TaskRepo) {
+	const attempts = 16
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := repo.Post(context.Background(), NewTask("contended", "2024-05-06")); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("%d concurrent Posts with the same title succeeded, want exactly 1", succeeded)
+	}
+}
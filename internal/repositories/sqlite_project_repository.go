@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+func (repo *SQLiteTaskRepo) GetProject(ctx context.Context, id string) (*models.Project, error) {
+	project, err := scanProject(repo.q.QueryRowContext(ctx,
+		`SELECT `+projectColumns+` FROM projects WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrProjectNotFound
+	}
+
+	return project, err
+}
+
+func (repo *SQLiteTaskRepo) Projects(ctx context.Context) ([]*models.Project, error) {
+	rows, err := repo.q.QueryContext(ctx,
+		`SELECT `+projectColumns+` FROM projects ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []*models.Project
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+
+	return projects, rows.Err()
+}
+
+func (repo *SQLiteTaskRepo) PostProject(ctx context.Context, project *models.Project) error {
+	project.CreatedAt = time.Now().UTC()
+	project.Version = 1
+
+	_, err := repo.q.ExecContext(ctx,
+		`INSERT INTO projects (`+projectColumns+`) VALUES (?, ?, ?, ?, ?)`,
+		project.ID, project.Name, project.Description, project.CreatedAt.Format(createdAtLayout), project.Version)
+	if isConstraintViolation(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE) {
+		return models.ErrDuplicateProjectName
+	}
+
+	return err
+}
+
+func (repo *SQLiteTaskRepo) PutProject(ctx context.Context, id string, project *models.Project, version int64) error {
+	stored, err := scanProject(repo.q.QueryRowContext(ctx,
+		`UPDATE projects SET name = ?, description = ?, version = version + 1
+		WHERE id = ? AND (? = 0 OR version = ?) RETURNING `+projectColumns,
+		project.Name, project.Description, id, version, version))
+	if isConstraintViolation(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE) {
+		return models.ErrDuplicateProjectName
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return repo.projectMissOrMismatch(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	*project = *stored
+
+	return nil
+}
+
+// DeleteProject relies on the foreign key from tasks to refuse deleting a
+// project that still has tasks.
+func (repo *SQLiteTaskRepo) DeleteProject(ctx context.Context, id string, version int64) error {
+	if id == models.DefaultProjectID {
+		if _, err := repo.GetProject(ctx, id); err != nil {
+			return err
+		}
+		return models.ErrDefaultProject
+	}
+
+	res, err := repo.q.ExecContext(ctx,
+		`DELETE FROM projects WHERE id = ? AND (? = 0 OR version = ?)`, id, version, version)
+	if isConstraintViolation(err, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY) {
+		return models.ErrProjectNotEmpty
+	}
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return repo.projectMissOrMismatch(ctx, id)
+	}
+
+	return nil
+}
+
+// projectMissOrMismatch is missOrMismatch for projects.
+func (repo *SQLiteTaskRepo) projectMissOrMismatch(ctx context.Context, id string) error {
+	var exists bool
+	if err := repo.q.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM projects WHERE id = ?)`, id).Scan(&exists); err != nil {
+		return err
+	}
+
+	if exists {
+		return models.ErrVersionMismatch
+	}
+
+	return models.ErrProjectNotFound
+}
+
+func scanProject(s scanner) (*models.Project, error) {
+	var project models.Project
+	var createdAt string
+	if err := s.Scan(&project.ID, &project.Name, &project.Description, &createdAt, &project.Version); err != nil {
+		return nil, err
+	}
+	project.CreatedAt = parseCreatedAt(createdAt)
+
+	return &project, nil
+}
@@ -0,0 +1,754 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/filter"
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// migrations are applied in order; the index of the last applied one is kept
+// in PRAGMA user_version. Only ever append to this list.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS tasks (
+		id        TEXT PRIMARY KEY,
+		title     TEXT NOT NULL UNIQUE,
+		active_at TEXT NOT NULL,
+		status    TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
+	CREATE INDEX IF NOT EXISTS idx_tasks_active_at ON tasks(active_at);`,
+	// created_at uses createdAtLayout; existing rows get the migration time.
+	`ALTER TABLE tasks ADD COLUMN created_at TEXT NOT NULL DEFAULT '';
+	UPDATE tasks SET created_at = strftime('%Y-%m-%dT%H:%M:%f000000Z', 'now');
+	CREATE INDEX IF NOT EXISTS idx_tasks_created_at ON tasks(created_at);`,
+	`ALTER TABLE tasks ADD COLUMN version INTEGER NOT NULL DEFAULT 1;`,
+	// status_changed_at uses createdAtLayout; '' means never changed.
+	`ALTER TABLE tasks ADD COLUMN status_changed_at TEXT NOT NULL DEFAULT '';
+	ALTER TABLE tasks ADD COLUMN status_changed_by TEXT NOT NULL DEFAULT '';`,
+	// due_at is kept as given; due_utc holds it normalized by dueValue for
+	// filtering and sorting.
+	`ALTER TABLE tasks ADD COLUMN priority TEXT NOT NULL DEFAULT 'normal';
+	ALTER TABLE tasks ADD COLUMN due_at TEXT NOT NULL DEFAULT '';
+	ALTER TABLE tasks ADD COLUMN due_utc TEXT NOT NULL DEFAULT '` + noDueAt + `';
+	CREATE INDEX IF NOT EXISTS idx_tasks_due_utc ON tasks(due_utc);`,
+	`ALTER TABLE tasks ADD COLUMN description TEXT NOT NULL DEFAULT '';`,
+	// tags is a JSON array of normalized tags.
+	`ALTER TABLE tasks ADD COLUMN tags TEXT NOT NULL DEFAULT '[]';`,
+	// Titles become unique per project, which takes rebuilding the table
+	// to drop the UNIQUE constraint on title. Existing tasks move to the
+	// default project.
+	`CREATE TABLE projects (
+		id          TEXT PRIMARY KEY,
+		name        TEXT NOT NULL UNIQUE,
+		description TEXT NOT NULL DEFAULT '',
+		created_at  TEXT NOT NULL,
+		version     INTEGER NOT NULL DEFAULT 1
+	);
+	INSERT INTO projects (id, name, created_at)
+		VALUES ('` + models.DefaultProjectID + `', '` + defaultProjectName + `', strftime('%Y-%m-%dT%H:%M:%f000000Z', 'now'));
+	CREATE TABLE tasks_new (
+		id                TEXT PRIMARY KEY,
+		project_id        TEXT NOT NULL DEFAULT '` + models.DefaultProjectID + `' REFERENCES projects(id),
+		title             TEXT NOT NULL,
+		active_at         TEXT NOT NULL,
+		status            TEXT NOT NULL,
+		created_at        TEXT NOT NULL DEFAULT '',
+		version           INTEGER NOT NULL DEFAULT 1,
+		status_changed_at TEXT NOT NULL DEFAULT '',
+		status_changed_by TEXT NOT NULL DEFAULT '',
+		priority          TEXT NOT NULL DEFAULT 'normal',
+		due_at            TEXT NOT NULL DEFAULT '',
+		due_utc           TEXT NOT NULL DEFAULT '` + noDueAt + `',
+		description       TEXT NOT NULL DEFAULT '',
+		tags              TEXT NOT NULL DEFAULT '[]',
+		UNIQUE (project_id, title)
+	);
+	INSERT INTO tasks_new (id, title, active_at, status, created_at, version, status_changed_at,
+			status_changed_by, priority, due_at, due_utc, description, tags)
+		SELECT id, title, active_at, status, created_at, version, status_changed_at,
+			status_changed_by, priority, due_at, due_utc, description, tags FROM tasks;
+	DROP TABLE tasks;
+	ALTER TABLE tasks_new RENAME TO tasks;
+	CREATE INDEX idx_tasks_status ON tasks(status);
+	CREATE INDEX idx_tasks_active_at ON tasks(active_at);
+	CREATE INDEX idx_tasks_created_at ON tasks(created_at);
+	CREATE INDEX idx_tasks_due_utc ON tasks(due_utc);`,
+	// checklist is a JSON array of models.ChecklistItem.
+	`ALTER TABLE tasks ADD COLUMN parent_id TEXT NOT NULL DEFAULT '';
+	ALTER TABLE tasks ADD COLUMN checklist TEXT NOT NULL DEFAULT '[]';
+	CREATE INDEX idx_tasks_parent_id ON tasks(parent_id);`,
+	// blocked_by is a JSON array of task IDs.
+	`ALTER TABLE tasks ADD COLUMN blocked_by TEXT NOT NULL DEFAULT '[]';`,
+	// recurrence is a JSON models.Recurrence, empty for tasks that do not
+	// repeat.
+	`ALTER TABLE tasks ADD COLUMN recurrence TEXT NOT NULL DEFAULT '';`,
+	// parent_id becomes a foreign key, so that a task with subtasks cannot
+	// be deleted, which takes rebuilding the table. Top-level tasks have a
+	// NULL parent_id, as do tasks whose parent is missing.
+	`CREATE TABLE tasks_new (
+		id                TEXT PRIMARY KEY,
+		project_id        TEXT NOT NULL DEFAULT '` + models.DefaultProjectID + `' REFERENCES projects(id),
+		parent_id         TEXT REFERENCES tasks_new(id) ON DELETE RESTRICT,
+		title             TEXT NOT NULL,
+		active_at         TEXT NOT NULL,
+		status            TEXT NOT NULL,
+		created_at        TEXT NOT NULL DEFAULT '',
+		version           INTEGER NOT NULL DEFAULT 1,
+		status_changed_at TEXT NOT NULL DEFAULT '',
+		status_changed_by TEXT NOT NULL DEFAULT '',
+		priority          TEXT NOT NULL DEFAULT 'normal',
+		due_at            TEXT NOT NULL DEFAULT '',
+		due_utc           TEXT NOT NULL DEFAULT '` + noDueAt + `',
+		description       TEXT NOT NULL DEFAULT '',
+		tags              TEXT NOT NULL DEFAULT '[]',
+		checklist         TEXT NOT NULL DEFAULT '[]',
+		blocked_by        TEXT NOT NULL DEFAULT '[]',
+		recurrence        TEXT NOT NULL DEFAULT '',
+		UNIQUE (project_id, title)
+	);
+	INSERT INTO tasks_new (id, title, active_at, status, created_at, version, status_changed_at,
+			status_changed_by, priority, due_at, description, tags, project_id, parent_id, checklist,
+			blocked_by, recurrence, due_utc)
+		SELECT id, title, active_at, status, created_at, version, status_changed_at, status_changed_by,
+			priority, due_at, description, tags, project_id,
+			CASE WHEN parent_id IN (SELECT id FROM tasks) THEN parent_id END,
+			checklist, blocked_by, recurrence, due_utc FROM tasks;
+	DROP TABLE tasks;
+	ALTER TABLE tasks_new RENAME TO tasks;
+	CREATE INDEX idx_tasks_status ON tasks(status);
+	CREATE INDEX idx_tasks_active_at ON tasks(active_at);
+	CREATE INDEX idx_tasks_created_at ON tasks(created_at);
+	CREATE INDEX idx_tasks_due_utc ON tasks(due_utc);
+	CREATE INDEX idx_tasks_parent_id ON tasks(parent_id);`,
+}
+
+const (
+	taskColumns    = `id, title, active_at, status, created_at, version, status_changed_at, status_changed_by, priority, due_at, description, tags, project_id, parent_id, checklist, blocked_by, recurrence`
+	projectColumns = `id, name, description, created_at, version`
+)
+
+func init() {
+	// Filters match ~ with strings.ToLower in memory; registering it under
+	// its own name keeps Cyrillic and other scripts case-insensitive here
+	// too without changing lower() for other users of the driver.
+	sqlite.MustRegisterDeterministicScalarFunction(filter.LowerFunc, 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch v := args[0].(type) {
+		case string:
+			return strings.ToLower(v), nil
+		case []byte:
+			return strings.ToLower(string(v)), nil
+		}
+		return args[0], nil
+	})
+}
+
+type SQLiteTaskRepo struct {
+	db *sql.DB
+	// q is db, or the transaction tx inside Atomically.
+	q  querier
+	tx *sql.Tx
+	// savepoints counts the calls of Atomically nested in tx.
+	savepoints int
+}
+
+// NewSQLiteTaskRepo opens (creating if needed) the database at path and
+// brings its schema up to date. Use ":memory:" for a throwaway database.
+func NewSQLiteTaskRepo(path string) (*SQLiteTaskRepo, error) {
+	if path != ":memory:" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)")
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite serializes writers anyway; a single connection avoids
+	// SQLITE_BUSY and keeps ":memory:" databases from being per-connection.
+	db.SetMaxOpenConns(1)
+
+	repo := &SQLiteTaskRepo{db: db, q: db}
+	if err := repo.migrate(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (repo *SQLiteTaskRepo) Close() error {
+	return repo.db.Close()
+}
+
+func (repo *SQLiteTaskRepo) migrate(ctx context.Context) error {
+	var version int
+	if err := repo.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := repo.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (repo *SQLiteTaskRepo) GetByID(ctx context.Context, id string) (*models.Task, error) {
+	row := repo.q.QueryRowContext(ctx,
+		`SELECT `+taskColumns+` FROM tasks WHERE id = ?`, id)
+
+	task, err := scanTask(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrTaskNotFound
+	}
+
+	return task, err
+}
+
+func (repo *SQLiteTaskRepo) All(ctx context.Context) ([]*models.Task, error) {
+	return repo.query(ctx, repo.q, `SELECT `+taskColumns+` FROM tasks ORDER BY active_at, id`)
+}
+
+func (repo *SQLiteTaskRepo) List(ctx context.Context, q TaskQuery) (*TaskPage, error) {
+	if q.After != nil && !q.After.Matches(q.Sort) {
+		return nil, ErrInvalidCursor
+	}
+
+	where, args := filter.SQL(q.Filter, fieldColumns)
+
+	if q.ProjectID != "" {
+		where += " AND project_id = ?"
+		args = append(args, q.ProjectID)
+	}
+
+	if len(q.ParentIDs) > 0 {
+		where += " AND parent_id IN (" + placeholders(len(q.ParentIDs)) + ")"
+		args = appendStrings(args, q.ParentIDs)
+	}
+
+	if len(q.Statuses) > 0 {
+		where += " AND status IN (" + placeholders(len(q.Statuses)) + ")"
+		args = appendStrings(args, q.Statuses)
+	}
+
+	if q.DueBefore != nil {
+		where += " AND due_utc < ?"
+		args = append(args, q.DueBefore.UTC().Format(createdAtLayout))
+	}
+
+	if len(q.AnyTags) > 0 {
+		where += " AND EXISTS (SELECT 1 FROM json_each(tasks.tags) WHERE value IN (" + placeholders(len(q.AnyTags)) + "))"
+		args = appendStrings(args, q.AnyTags)
+	}
+	if len(q.AllTags) > 0 {
+		where += " AND (SELECT count(DISTINCT value) FROM json_each(tasks.tags) WHERE value IN (" + placeholders(len(q.AllTags)) + ")) = ?"
+		args = append(appendStrings(args, q.AllTags), len(q.AllTags))
+	}
+	if len(q.NoTags) > 0 {
+		where += " AND NOT EXISTS (SELECT 1 FROM json_each(tasks.tags) WHERE value IN (" + placeholders(len(q.NoTags)) + "))"
+		args = appendStrings(args, q.NoTags)
+	}
+
+	page := &TaskPage{}
+	if err := repo.q.QueryRowContext(ctx,
+		`SELECT count(*) FROM tasks WHERE `+where, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	if q.After != nil {
+		cond, condArgs := keysetCondition(q.Sort, q.After)
+		where += " AND " + cond
+		args = append(args, condArgs...)
+	}
+
+	// A negative LIMIT is no limit in SQLite.
+	limit := -1
+	if q.Limit > 0 {
+		limit = q.Limit
+	}
+	args = append(args, limit, q.Offset)
+
+	tasks, err := repo.query(ctx, repo.q,
+		`SELECT `+taskColumns+` FROM tasks WHERE `+where+` ORDER BY `+orderBy(q.Sort)+` LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	page.Tasks = tasks
+
+	return page, nil
+}
+
+// placeholders returns n comma-separated ? placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func appendStrings(args []any, values []string) []any {
+	for _, v := range values {
+		args = append(args, v)
+	}
+
+	return args
+}
+
+func orderBy(s Sort) string {
+	var terms []string
+	for _, key := range s {
+		term := sortFields[key.Field]
+		if key.Desc {
+			term += " DESC"
+		}
+		terms = append(terms, term)
+	}
+
+	return strings.Join(append(terms, "id"), ", ")
+}
+
+// keysetCondition matches the rows ordered after the cursor:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... OR (k1 = v1 AND ... AND id > id0),
+// with < for descending keys.
+func keysetCondition(s Sort, after *Cursor) (string, []any) {
+	var ors []string
+	var args []any
+
+	for i := 0; i <= len(s); i++ {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, sortFields[s[j].Field]+" = ?")
+			args = append(args, after.Values[j])
+		}
+
+		if i == len(s) {
+			ands = append(ands, "id > ?")
+			args = append(args, after.ID)
+		} else {
+			op := " > ?"
+			if s[i].Desc {
+				op = " < ?"
+			}
+			ands = append(ands, sortFields[s[i].Field]+op)
+			args = append(args, after.Values[i])
+		}
+
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+func (repo *SQLiteTaskRepo) Post(ctx context.Context, task *models.Task) error {
+	return repo.post(ctx, repo.q, task)
+}
+
+func (repo *SQLiteTaskRepo) Put(ctx context.Context, id string, updatedTask *models.Task, version int64) error {
+	return repo.put(ctx, repo.q, id, updatedTask, version)
+}
+
+func (repo *SQLiteTaskRepo) MarkAsDone(ctx context.Context, id string, version int64) error {
+	_, err := repo.setStatus(ctx, repo.q, id, version, "done", "")
+	return err
+}
+
+func (repo *SQLiteTaskRepo) Delete(ctx context.Context, id string, version int64) error {
+	return repo.delete(ctx, repo.q, id, version)
+}
+
+func (repo *SQLiteTaskRepo) Tags(ctx context.Context) ([]models.Tag, error) {
+	rows, err := repo.q.QueryContext(ctx,
+		`SELECT value, count(*) FROM tasks, json_each(tasks.tags) GROUP BY value ORDER BY count(*) DESC, value`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// RenameTag checks for to and updates the tasks carrying from in one
+// transaction.
+func (repo *SQLiteTaskRepo) RenameTag(ctx context.Context, from, to string, merge bool) ([]*models.Task, error) {
+	var renamed []*models.Task
+	err := repo.Atomically(ctx, func(tx TaskRepo) error {
+		var err error
+		renamed, err = tx.(*SQLiteTaskRepo).retag(ctx, from, to, merge)
+		return err
+	})
+
+	return renamed, err
+}
+
+// retag is RenameTag inside a transaction.
+func (repo *SQLiteTaskRepo) retag(ctx context.Context, from, to string, merge bool) ([]*models.Task, error) {
+	tasks, err := repo.query(ctx, repo.q,
+		`SELECT `+taskColumns+` FROM tasks WHERE EXISTS (SELECT 1 FROM json_each(tasks.tags) WHERE value = ?)`, from)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tasks) > 0 && !merge {
+		var taken bool
+		err := repo.q.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM tasks, json_each(tasks.tags) WHERE json_each.value = ?)`, to).Scan(&taken)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, models.ErrTagExists
+		}
+	}
+
+	renamed := make([]*models.Task, len(tasks))
+	for i, task := range tasks {
+		renamed[i], err = scanTask(repo.q.QueryRowContext(ctx,
+			`UPDATE tasks SET tags = ?, version = version + 1 WHERE id = ? RETURNING `+taskColumns,
+			stringsJSON(renameTag(task.Tags, from, to)), task.ID))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return renamed, nil
+}
+
+// Atomically runs fn in a transaction, or in a savepoint if repo is the
+// transaction of an enclosing call.
+func (repo *SQLiteTaskRepo) Atomically(ctx context.Context, fn func(tx TaskRepo) error) error {
+	if repo.tx != nil {
+		return repo.savepoint(ctx, fn)
+	}
+
+	tx, err := repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&SQLiteTaskRepo{db: repo.db, q: tx, tx: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Batch runs ops in a transaction, or in a savepoint of the enclosing one.
+func (repo *SQLiteTaskRepo) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	return runBatch(ctx, repo, ops, atomic)
+}
+
+func (repo *SQLiteTaskRepo) savepoint(ctx context.Context, fn func(tx TaskRepo) error) error {
+	inner := *repo
+	inner.savepoints++
+	name := fmt.Sprintf("atomically_%d", inner.savepoints)
+
+	if _, err := repo.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+
+	if err := fn(&inner); err != nil {
+		if _, rerr := repo.tx.ExecContext(ctx, "ROLLBACK TO "+name+"; RELEASE "+name); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+		return err
+	}
+
+	_, err := repo.tx.ExecContext(ctx, "RELEASE "+name)
+
+	return err
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx. With a single
+// connection, everything inside a transaction must go through the *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (repo *SQLiteTaskRepo) post(ctx context.Context, q querier, task *models.Task) error {
+	if task.Status == "" {
+		task.Status = "active"
+	}
+	if task.Priority == "" {
+		task.Priority = models.PriorityNormal
+	}
+	task.CreatedAt = time.Now().UTC()
+	task.Version = 1
+
+	task.StatusChangedAt = nil
+	task.StatusChangedBy = ""
+	if task.ProjectID == "" {
+		task.ProjectID = models.DefaultProjectID
+	}
+
+	_, err := q.ExecContext(ctx,
+		`INSERT INTO tasks (`+taskColumns+`, due_utc) VALUES (?, ?, ?, ?, ?, ?, '', '', ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		task.ID, task.Title, task.ActiveAt, task.Status, task.CreatedAt.Format(createdAtLayout), task.Version,
+		task.Priority, task.DueAt, task.Description, stringsJSON(task.Tags), task.ProjectID, parentValue(task.ParentID),
+		checklistJSON(task.Checklist), stringsJSON(task.BlockedBy), recurrenceJSON(task.Recurrence), dueValue(task.DueAt))
+
+	return repo.taskWriteError(ctx, q, task, err)
+}
+
+func (repo *SQLiteTaskRepo) put(ctx context.Context, q querier, id string, updatedTask *models.Task, version int64) error {
+	now := time.Now().UTC().Format(createdAtLayout)
+
+	if updatedTask.Priority == "" {
+		updatedTask.Priority = models.PriorityNormal
+	}
+	if updatedTask.ProjectID == "" {
+		updatedTask.ProjectID = models.DefaultProjectID
+	}
+
+	// SET expressions all see the row as it was, so status is the old one.
+	stored, err := scanTask(q.QueryRowContext(ctx,
+		`UPDATE tasks SET title = ?, active_at = ?, status = ?, priority = ?, due_at = ?, due_utc = ?,
+			description = ?, tags = ?, project_id = ?, parent_id = ?, checklist = ?, blocked_by = ?,
+			recurrence = ?, version = version + 1,
+			status_changed_at = CASE WHEN status = ? THEN status_changed_at ELSE ? END,
+			status_changed_by = CASE WHEN status = ? THEN status_changed_by ELSE ? END
+		WHERE id = ? AND (? = 0 OR version = ?) RETURNING `+taskColumns,
+		updatedTask.Title, updatedTask.ActiveAt, updatedTask.Status,
+		updatedTask.Priority, updatedTask.DueAt, dueValue(updatedTask.DueAt), updatedTask.Description,
+		stringsJSON(updatedTask.Tags), updatedTask.ProjectID, parentValue(updatedTask.ParentID), checklistJSON(updatedTask.Checklist),
+		stringsJSON(updatedTask.BlockedBy), recurrenceJSON(updatedTask.Recurrence),
+		updatedTask.Status, now, updatedTask.Status, updatedTask.StatusChangedBy,
+		id, version, version))
+	if errors.Is(err, sql.ErrNoRows) {
+		return repo.missOrMismatch(ctx, q, id)
+	}
+	if err != nil {
+		return repo.taskWriteError(ctx, q, updatedTask, err)
+	}
+
+	*updatedTask = *stored
+
+	return nil
+}
+
+func (repo *SQLiteTaskRepo) setStatus(ctx context.Context, q querier, id string, version int64, status, by string) (*models.Task, error) {
+	task, err := scanTask(q.QueryRowContext(ctx,
+		`UPDATE tasks SET status = ?, version = version + 1,
+			status_changed_at = CASE WHEN status = ? THEN status_changed_at ELSE ? END,
+			status_changed_by = CASE WHEN status = ? THEN status_changed_by ELSE ? END
+		WHERE id = ? AND (? = 0 OR version = ?) RETURNING `+taskColumns,
+		status, status, time.Now().UTC().Format(createdAtLayout), status, by, id, version, version))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repo.missOrMismatch(ctx, q, id)
+	}
+
+	return task, err
+}
+
+func (repo *SQLiteTaskRepo) delete(ctx context.Context, q querier, id string, version int64) error {
+	res, err := q.ExecContext(ctx,
+		`DELETE FROM tasks WHERE id = ? AND (? = 0 OR version = ?)`, id, version, version)
+	// SQLite reports the ON DELETE RESTRICT of parent_id as a trigger.
+	if isConstraintViolation(err, sqlite3.SQLITE_CONSTRAINT_TRIGGER) {
+		return models.ErrHasSubtasks
+	}
+	if err != nil {
+		return err
+	}
+
+	return repo.expectAffected(ctx, q, res, id)
+}
+
+func (repo *SQLiteTaskRepo) query(ctx context.Context, q querier, query string, args ...any) ([]*models.Task, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []*models.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// stringsJSON encodes a list for the tags or blocked_by column.
+func stringsJSON(list []string) string {
+	if len(list) == 0 {
+		return "[]"
+	}
+
+	data, _ := json.Marshal(list)
+
+	return string(data)
+}
+
+// checklistJSON encodes a checklist for the checklist column.
+func checklistJSON(items []models.ChecklistItem) string {
+	if len(items) == 0 {
+		return "[]"
+	}
+
+	data, _ := json.Marshal(items)
+
+	return string(data)
+}
+
+// parentValue is the parent_id column for parent: NULL for a top-level
+// task.
+func parentValue(parent string) sql.NullString {
+	return sql.NullString{String: parent, Valid: parent != ""}
+}
+
+// recurrenceJSON encodes a recurrence for the recurrence column.
+func recurrenceJSON(recurrence *models.Recurrence) string {
+	if recurrence == nil {
+		return ""
+	}
+
+	data, _ := json.Marshal(recurrence)
+
+	return string(data)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTask(s scanner) (*models.Task, error) {
+	var task models.Task
+	var createdAt, statusChangedAt, tags, checklist, blockedBy, recurrence string
+	var parent sql.NullString
+	if err := s.Scan(&task.ID, &task.Title, &task.ActiveAt, &task.Status, &createdAt, &task.Version,
+		&statusChangedAt, &task.StatusChangedBy, &task.Priority, &task.DueAt, &task.Description, &tags,
+		&task.ProjectID, &parent, &checklist, &blockedBy, &recurrence); err != nil {
+		return nil, err
+	}
+	task.ParentID = parent.String
+	if err := json.Unmarshal([]byte(tags), &task.Tags); err != nil {
+		return nil, fmt.Errorf("task %s: tags: %w", task.ID, err)
+	}
+	if len(task.Tags) == 0 {
+		task.Tags = nil
+	}
+	if err := json.Unmarshal([]byte(checklist), &task.Checklist); err != nil {
+		return nil, fmt.Errorf("task %s: checklist: %w", task.ID, err)
+	}
+	if len(task.Checklist) == 0 {
+		task.Checklist = nil
+	}
+	if err := json.Unmarshal([]byte(blockedBy), &task.BlockedBy); err != nil {
+		return nil, fmt.Errorf("task %s: blockedBy: %w", task.ID, err)
+	}
+	if len(task.BlockedBy) == 0 {
+		task.BlockedBy = nil
+	}
+	if recurrence != "" {
+		if err := json.Unmarshal([]byte(recurrence), &task.Recurrence); err != nil {
+			return nil, fmt.Errorf("task %s: recurrence: %w", task.ID, err)
+		}
+	}
+	task.CreatedAt = parseCreatedAt(createdAt)
+	if statusChangedAt != "" {
+		changed := parseCreatedAt(statusChangedAt)
+		task.StatusChangedAt = &changed
+	}
+
+	return &task, nil
+}
+
+// expectAffected checks that a conditional write to task id hit a row.
+func (repo *SQLiteTaskRepo) expectAffected(ctx context.Context, q querier, res sql.Result, id string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return repo.missOrMismatch(ctx, q, id)
+	}
+
+	return nil
+}
+
+// missOrMismatch explains why a conditional write to task id hit no row:
+// either the task does not exist or it is at another version.
+func (repo *SQLiteTaskRepo) missOrMismatch(ctx context.Context, q querier, id string) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM tasks WHERE id = ?)`, id).Scan(&exists); err != nil {
+		return err
+	}
+
+	if exists {
+		return models.ErrVersionMismatch
+	}
+
+	return models.ErrTaskNotFound
+}
+
+// taskWriteError translates the constraint violations of writing task. A
+// foreign key names either its project or its parent.
+func (repo *SQLiteTaskRepo) taskWriteError(ctx context.Context, q querier, task *models.Task, err error) error {
+	switch {
+	case isConstraintViolation(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE):
+		return models.ErrDuplicateTitle
+	case isConstraintViolation(err, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY):
+		var exists bool
+		if err := q.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM projects WHERE id = ?)`, task.ProjectID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return models.ErrParentNotFound
+		}
+		return models.ErrProjectNotFound
+	}
+
+	return err
+}
+
+func isConstraintViolation(err error, code int) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == code
+}
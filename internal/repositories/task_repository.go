@@ -3,32 +3,175 @@ package repositories
 import (
 	"context"
 	"errors"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/canyouhearthemusic/todo-list/internal/models"
 )
 
+// TaskRepo stores tasks, along with the projects they belong to. Post keeps
+// the status of a task, "active" if it has none, and sets its Version to 1;
+// every change increments it. Post and Put store models.PriorityNormal for a
+// task without a priority and models.DefaultProjectID for one without a
+// project, fail with models.ErrProjectNotFound if the project does not
+// exist, with models.ErrParentNotFound if the parent task does not, and with
+// models.ErrDuplicateTitle if another task of the project has the same
+// title. Delete fails with models.ErrHasSubtasks for a task that is the
+// parent of others. Put, Delete and MarkAsDone take the version the caller
+// expects to be stored: unless it is zero, they fail with
+// models.ErrVersionMismatch if the task has changed since.
+//
+// Put and MarkAsDone record when the status changes in StatusChangedAt,
+// along with the StatusChangedBy of the new task (nobody for MarkAsDone).
+// A Put keeping the status keeps the record of the last change.
+//
+// Tags counts the tasks carrying each tag, most used first. RenameTag
+// replaces tag from with to on every task carrying it, which merges the two
+// on tasks that already have to, and returns those tasks as stored. Unless
+// merge is set, it fails with models.ErrTagExists if any task carries to.
+//
+// Atomically runs fn with a TaskRepo that is a transaction: reads through
+// it see the writes made through it and no others, since other writers wait
+// until it is done, and its writes are stored together if fn returns nil
+// and not at all otherwise. Readers outside never see them half-applied.
+// Calling Atomically on that TaskRepo nests: a failing inner fn undoes its
+// own writes only.
+//
+// Batch runs ops in order in one transaction, each nested in its own, so
+// that every op sees the writes of those before it and a failed op changes
+// nothing. In atomic mode the first failure undoes every op, the others are
+// not run, and every result but its own is models.ErrBatchAborted. The
+// error is for the batch as a whole, such as a transaction that cannot be
+// stored. A wrapper around a TaskRepo overrides Batch, on itself and on its
+// transactions, so that the writes of ops go through it.
 type TaskRepo interface {
+	ProjectRepo
+
 	GetByID(ctx context.Context, id string) (*models.Task, error)
 	All(ctx context.Context) ([]*models.Task, error)
+	// List returns one page of the tasks matching q, ordered by q.Sort.
+	List(ctx context.Context, q TaskQuery) (*TaskPage, error)
 	Post(ctx context.Context, task *models.Task) error
-	Put(ctx context.Context, id string, task *models.Task) error
-	Delete(ctx context.Context, id string) error
-	MarkAsDone(ctx context.Context, id string) error
+	Put(ctx context.Context, id string, task *models.Task, version int64) error
+	Delete(ctx context.Context, id string, version int64) error
+	MarkAsDone(ctx context.Context, id string, version int64) error
+	Atomically(ctx context.Context, fn func(tx TaskRepo) error) error
+	Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error)
+	Tags(ctx context.Context) ([]models.Tag, error)
+	RenameTag(ctx context.Context, from, to string, merge bool) ([]*models.Task, error)
 }
 
+// SyncMapTaskRepo keeps tasks in memory. Tasks are copied on the way in and
+// out so callers never share a pointer with the store; writes are serialized
+// so the duplicate-title check and the store happen atomically.
 type SyncMapTaskRepo struct {
-	db sync.Map
+	db       sync.Map
+	projects sync.Map
+	// mu is held by writers, and shared by readers so that they never see
+	// a transaction half-applied.
+	mu sync.RWMutex
+	// undo is non-nil while a transaction runs and restores, last first,
+	// what it has changed so far.
+	undo []func()
 }
 
 func NewSyncMapTaskRepo() *SyncMapTaskRepo {
-	return &SyncMapTaskRepo{}
+	repo := &SyncMapTaskRepo{}
+	repo.projects.Store(models.DefaultProjectID, newDefaultProject())
+
+	return repo
 }
 
 func (repo *SyncMapTaskRepo) GetByID(ctx context.Context, id string) (*models.Task, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	return repo.getByID(id)
+}
+
+func (repo *SyncMapTaskRepo) All(ctx context.Context) ([]*models.Task, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	return repo.all(), nil
+}
+
+func (repo *SyncMapTaskRepo) List(ctx context.Context, q TaskQuery) (*TaskPage, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	return repo.list(q)
+}
+
+func (repo *SyncMapTaskRepo) Post(ctx context.Context, task *models.Task) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.post(task)
+}
+
+func (repo *SyncMapTaskRepo) Put(ctx context.Context, id string, updatedTask *models.Task, version int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.put(id, updatedTask, version)
+}
+
+func (repo *SyncMapTaskRepo) MarkAsDone(ctx context.Context, id string, version int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	_, err := repo.setStatus(id, version, "done", "")
+
+	return err
+}
+
+func (repo *SyncMapTaskRepo) Delete(ctx context.Context, id string, version int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.delete(id, version)
+}
+
+// Atomically holds the write lock while fn runs, and restores every task
+// and project fn changed if it fails.
+func (repo *SyncMapTaskRepo) Atomically(ctx context.Context, fn func(tx TaskRepo) error) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.undo = []func(){}
+	defer func() { repo.undo = nil }()
+
+	return (&syncMapTx{repo: repo}).Atomically(ctx, fn)
+}
+
+func (repo *SyncMapTaskRepo) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	return runBatch(ctx, repo, ops, atomic)
+}
+
+func (repo *SyncMapTaskRepo) Tags(ctx context.Context) ([]models.Tag, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	return repo.tags(), nil
+}
+
+func (repo *SyncMapTaskRepo) RenameTag(ctx context.Context, from, to string, merge bool) ([]*models.Task, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.retag(from, to, merge)
+}
+
+// The methods below must be called with mu held; those that only read may
+// hold it for reading.
+
+func (repo *SyncMapTaskRepo) getByID(id string) (*models.Task, error) {
 	value, ok := repo.db.Load(id)
 	if !ok {
-		return nil, errors.New("Task not found")
+		return nil, models.ErrTaskNotFound
 	}
 
 	task, ok := value.(*models.Task)
@@ -36,79 +179,404 @@ func (repo *SyncMapTaskRepo) GetByID(ctx context.Context, id string) (*models.Ta
 		return nil, errors.New("Type assertion failed")
 	}
 
-	return task, nil
+	return cloneTask(task), nil
 }
 
-func (repo *SyncMapTaskRepo) All(ctx context.Context) ([]*models.Task, error) {
+func (repo *SyncMapTaskRepo) all() []*models.Task {
 	var tasks []*models.Task
 
 	repo.db.Range(func(key, value interface{}) bool {
 		task, ok := value.(*models.Task)
 		if ok {
-			tasks = append(tasks, task)
+			tasks = append(tasks, cloneTask(task))
 		}
 
 		return true
 	})
 
-	return tasks, nil
+	return tasks
 }
 
-func (repo *SyncMapTaskRepo) Post(ctx context.Context, task *models.Task) error {
-	var found bool
+func (repo *SyncMapTaskRepo) list(q TaskQuery) (*TaskPage, error) {
+	if q.After != nil && !q.After.Matches(q.Sort) {
+		return nil, ErrInvalidCursor
+	}
+
+	var tasks []*models.Task
+
 	repo.db.Range(func(key, value interface{}) bool {
-		existingTask, ok := value.(*models.Task)
+		task, ok := value.(*models.Task)
+		if ok && q.matches(task) {
+			tasks = append(tasks, cloneTask(task))
+		}
 
-		if ok && existingTask.Title == task.Title {
-			found = true
-			return false
+		return true
+	})
+
+	sortTasks(tasks, q.Sort)
+
+	return paginate(tasks, q), nil
+}
+
+func (repo *SyncMapTaskRepo) tags() []models.Tag {
+	counts := map[string]int{}
+	repo.db.Range(func(key, value interface{}) bool {
+		if task, ok := value.(*models.Task); ok {
+			for _, tag := range task.Tags {
+				counts[tag]++
+			}
+		}
+
+		return true
+	})
+
+	return sortTags(counts)
+}
+
+func (repo *SyncMapTaskRepo) retag(from, to string, merge bool) ([]*models.Task, error) {
+	var tagged []*models.Task
+	taken := false
+	repo.db.Range(func(key, value interface{}) bool {
+		if task, ok := value.(*models.Task); ok {
+			if slices.Contains(task.Tags, from) {
+				tagged = append(tagged, task)
+			}
+			taken = taken || slices.Contains(task.Tags, to)
 		}
 
 		return true
 	})
 
-	if found {
-		return errors.New("task with the same title already exists")
+	if len(tagged) > 0 && taken && !merge {
+		return nil, models.ErrTagExists
+	}
+
+	renamed := make([]*models.Task, len(tagged))
+	for i, task := range tagged {
+		task = cloneTask(task)
+		task.Tags = renameTag(task.Tags, from, to)
+		task.Version++
+		repo.store(&repo.db, task.ID, task)
+		renamed[i] = cloneTask(task)
+	}
+
+	return renamed, nil
+}
+
+func (repo *SyncMapTaskRepo) post(task *models.Task) error {
+	if task.ProjectID == "" {
+		task.ProjectID = models.DefaultProjectID
+	}
+	if _, ok := repo.projects.Load(task.ProjectID); !ok {
+		return models.ErrProjectNotFound
+	}
+	if err := repo.checkParent(task.ParentID); err != nil {
+		return err
+	}
+	if repo.titleTaken(task.ProjectID, task.Title, "") {
+		return models.ErrDuplicateTitle
 	}
 
-	task.Status = "active"
+	if task.Status == "" {
+		task.Status = "active"
+	}
+	if task.Priority == "" {
+		task.Priority = models.PriorityNormal
+	}
+	task.CreatedAt = time.Now().UTC()
+	task.Version = 1
+	task.StatusChangedAt = nil
+	task.StatusChangedBy = ""
 
-	repo.db.Store(task.ID, task)
+	repo.store(&repo.db, task.ID, cloneTask(task))
 
 	return nil
 }
 
-func (repo *SyncMapTaskRepo) Put(ctx context.Context, id string, updatedTask *models.Task) error {
-	oldTask, err := repo.GetByID(ctx, id)
+func (repo *SyncMapTaskRepo) put(id string, updatedTask *models.Task, version int64) error {
+	oldTask, err := repo.current(id, version)
 	if err != nil {
 		return err
 	}
 
+	if updatedTask.ProjectID == "" {
+		updatedTask.ProjectID = models.DefaultProjectID
+	}
+	if _, ok := repo.projects.Load(updatedTask.ProjectID); !ok {
+		return models.ErrProjectNotFound
+	}
+	if err := repo.checkParent(updatedTask.ParentID); err != nil {
+		return err
+	}
+	if repo.titleTaken(updatedTask.ProjectID, updatedTask.Title, id) {
+		return models.ErrDuplicateTitle
+	}
+
+	if updatedTask.Priority == "" {
+		updatedTask.Priority = models.PriorityNormal
+	}
 	updatedTask.ID = oldTask.ID
+	updatedTask.CreatedAt = oldTask.CreatedAt
+	updatedTask.Version = oldTask.Version + 1
+	recordStatusChange(updatedTask, oldTask, updatedTask.StatusChangedBy)
 
-	repo.db.Store(id, updatedTask)
+	repo.store(&repo.db, id, cloneTask(updatedTask))
 
 	return nil
 }
 
-func (repo *SyncMapTaskRepo) MarkAsDone(ctx context.Context, id string) error {
-	task, err := repo.GetByID(ctx, id)
+func (repo *SyncMapTaskRepo) setStatus(id string, version int64, status, by string) (*models.Task, error) {
+	task, err := repo.current(id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	old := *task
+	task.Status = status
+	task.Version++
+	recordStatusChange(task, &old, by)
+
+	repo.store(&repo.db, id, cloneTask(task))
+
+	return task, nil
+}
+
+func (repo *SyncMapTaskRepo) delete(id string, version int64) error {
+	_, err := repo.current(id, version)
 	if err != nil {
 		return err
 	}
 
-	task.Status = "done"
+	var parent bool
+	repo.db.Range(func(key, value interface{}) bool {
+		task, ok := value.(*models.Task)
+		parent = ok && task.ParentID == id
+
+		return !parent
+	})
+	if parent {
+		return models.ErrHasSubtasks
+	}
+
+	repo.remove(&repo.db, id)
 
 	return nil
 }
 
-func (repo *SyncMapTaskRepo) Delete(ctx context.Context, id string) error {
-	_, err := repo.GetByID(ctx, id)
-	if err != nil {
+// store saves value under key in m, and inside a transaction remembers how
+// to undo it.
+func (repo *SyncMapTaskRepo) store(m *sync.Map, key string, value any) {
+	repo.remember(m, key)
+	m.Store(key, value)
+}
+
+// remove is store for deleting key from m.
+func (repo *SyncMapTaskRepo) remove(m *sync.Map, key string) {
+	repo.remember(m, key)
+	m.Delete(key)
+}
+
+func (repo *SyncMapTaskRepo) remember(m *sync.Map, key string) {
+	if repo.undo == nil {
+		return
+	}
+
+	value, ok := m.Load(key)
+	repo.undo = append(repo.undo, func() {
+		if ok {
+			m.Store(key, value)
+		} else {
+			m.Delete(key)
+		}
+	})
+}
+
+// rollback undoes the changes of the running transaction past the first
+// mark of them.
+func (repo *SyncMapTaskRepo) rollback(mark int) {
+	for i := len(repo.undo) - 1; i >= mark; i-- {
+		repo.undo[i]()
+	}
+	repo.undo = repo.undo[:mark]
+}
+
+// syncMapTx is the TaskRepo passed to the fn of SyncMapTaskRepo.Atomically,
+// which holds mu for it.
+type syncMapTx struct {
+	repo *SyncMapTaskRepo
+}
+
+func (tx *syncMapTx) GetByID(ctx context.Context, id string) (*models.Task, error) {
+	return tx.repo.getByID(id)
+}
+
+func (tx *syncMapTx) All(ctx context.Context) ([]*models.Task, error) {
+	return tx.repo.all(), nil
+}
+
+func (tx *syncMapTx) List(ctx context.Context, q TaskQuery) (*TaskPage, error) {
+	return tx.repo.list(q)
+}
+
+func (tx *syncMapTx) Post(ctx context.Context, task *models.Task) error {
+	return tx.repo.post(task)
+}
+
+func (tx *syncMapTx) Put(ctx context.Context, id string, task *models.Task, version int64) error {
+	return tx.repo.put(id, task, version)
+}
+
+func (tx *syncMapTx) MarkAsDone(ctx context.Context, id string, version int64) error {
+	_, err := tx.repo.setStatus(id, version, "done", "")
+	return err
+}
+
+func (tx *syncMapTx) Delete(ctx context.Context, id string, version int64) error {
+	return tx.repo.delete(id, version)
+}
+
+func (tx *syncMapTx) Atomically(ctx context.Context, fn func(tx TaskRepo) error) error {
+	mark := len(tx.repo.undo)
+	if err := fn(tx); err != nil {
+		tx.repo.rollback(mark)
 		return err
 	}
 
-	repo.db.Delete(id)
+	return nil
+}
+
+func (tx *syncMapTx) Batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	return runBatch(ctx, tx, ops, atomic)
+}
+
+func (tx *syncMapTx) Tags(ctx context.Context) ([]models.Tag, error) {
+	return tx.repo.tags(), nil
+}
+
+func (tx *syncMapTx) RenameTag(ctx context.Context, from, to string, merge bool) ([]*models.Task, error) {
+	return tx.repo.retag(from, to, merge)
+}
+
+func (tx *syncMapTx) GetProject(ctx context.Context, id string) (*models.Project, error) {
+	return tx.repo.getProject(id)
+}
+
+func (tx *syncMapTx) Projects(ctx context.Context) ([]*models.Project, error) {
+	return tx.repo.listProjects(), nil
+}
+
+func (tx *syncMapTx) PostProject(ctx context.Context, project *models.Project) error {
+	return tx.repo.postProject(project)
+}
+
+func (tx *syncMapTx) PutProject(ctx context.Context, id string, project *models.Project, version int64) error {
+	return tx.repo.putProject(id, project, version)
+}
+
+func (tx *syncMapTx) DeleteProject(ctx context.Context, id string, version int64) error {
+	return tx.repo.deleteProject(id, version)
+}
+
+// renameTag returns the normalized tags with from replaced by to.
+func renameTag(tags []string, from, to string) []string {
+	renamed := slices.Clone(tags)
+	for i, tag := range renamed {
+		if tag == from {
+			renamed[i] = to
+		}
+	}
+
+	return models.NormalizeTags(renamed)
+}
+
+// sortTags lists the tags counted in counts, most used first, then by name.
+func sortTags(counts map[string]int) []models.Tag {
+	tags := make([]models.Tag, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, models.Tag{Name: name, Count: count})
+	}
+
+	slices.SortFunc(tags, func(a, b models.Tag) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return tags
+}
+
+// cloneTask returns a copy of task sharing no memory with it.
+func cloneTask(task *models.Task) *models.Task {
+	copied := *task
+	copied.Tags = slices.Clone(task.Tags)
+	copied.Checklist = slices.Clone(task.Checklist)
+	copied.BlockedBy = slices.Clone(task.BlockedBy)
+	if task.Recurrence != nil {
+		recurrence := *task.Recurrence
+		copied.Recurrence = &recurrence
+	}
+
+	return &copied
+}
+
+// recordStatusChange stamps task as changed now by by if its status differs
+// from old's, and otherwise carries over the last change recorded on old.
+func recordStatusChange(task, old *models.Task, by string) {
+	if task.Status == old.Status {
+		task.StatusChangedAt = old.StatusChangedAt
+		task.StatusChangedBy = old.StatusChangedBy
+		return
+	}
+
+	now := time.Now().UTC()
+	task.StatusChangedAt = &now
+	task.StatusChangedBy = by
+}
+
+// current returns a copy of the stored task, checking it is at version
+// unless version is zero. The caller must hold mu.
+func (repo *SyncMapTaskRepo) current(id string, version int64) (*models.Task, error) {
+	task, err := repo.getByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != 0 && task.Version != version {
+		return nil, models.ErrVersionMismatch
+	}
+
+	return task, nil
+}
+
+// checkParent reports models.ErrParentNotFound unless parent is empty or
+// a stored task.
+func (repo *SyncMapTaskRepo) checkParent(parent string) error {
+	if parent == "" {
+		return nil
+	}
+
+	if _, ok := repo.db.Load(parent); !ok {
+		return models.ErrParentNotFound
+	}
 
 	return nil
 }
+
+// titleTaken reports whether a task of project other than exceptID already
+// has title.
+func (repo *SyncMapTaskRepo) titleTaken(project, title, exceptID string) bool {
+	var found bool
+	repo.db.Range(func(key, value interface{}) bool {
+		existingTask, ok := value.(*models.Task)
+
+		if ok && existingTask.ID != exceptID && existingTask.ProjectID == project && existingTask.Title == title {
+			found = true
+			return false
+		}
+
+		return true
+	})
+
+	return found
+}
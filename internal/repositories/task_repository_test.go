@@ -0,0 +1,435 @@
+package repositories_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/canyouhearthemusic/todo-list/internal/repositories"
+	"github.com/canyouhearthemusic/todo-list/internal/repositories/repotest"
+	"github.com/canyouhearthemusic/todo-list/internal/search"
+)
+
+func TestSyncMapTaskRepo(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repositories.TaskRepo {
+		return repositories.NewSyncMapTaskRepo()
+	})
+}
+
+func TestSQLiteTaskRepo(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repositories.TaskRepo {
+		repo, err := repositories.NewSQLiteTaskRepo(filepath.Join(t.TempDir(), "tasks.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { repo.Close() })
+
+		return repo
+	})
+}
+
+// The repo registers its own case folding for filters; SQLite's lower()
+// stays as it is for everything else using the driver.
+func TestSQLiteBuiltinLowerUntouched(t *testing.T) {
+	if _, err := repositories.NewSQLiteTaskRepo(":memory:"); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var lower, unicodeLower string
+	if err := db.QueryRow(`SELECT lower('ОТЧЁТ Q2'), unicode_lower('ОТЧЁТ Q2')`).Scan(&lower, &unicodeLower); err != nil {
+		t.Fatal(err)
+	}
+	if lower != "ОТЧЁТ q2" || unicodeLower != "отчёт q2" {
+		t.Errorf("lower = %q, unicode_lower = %q; want ОТЧЁТ q2 and отчёт q2", lower, unicodeLower)
+	}
+}
+
+// Upgrading makes parent_id a foreign key: top-level tasks and tasks whose
+// parent is gone become top-level, and a parent can no longer be deleted.
+func TestSQLiteParentMigration(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.db")
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE projects (
+			id TEXT PRIMARY KEY, name TEXT NOT NULL UNIQUE, description TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL, version INTEGER NOT NULL DEFAULT 1);
+		INSERT INTO projects (id, name, created_at) VALUES ('` + models.DefaultProjectID + `', 'Inbox', '');
+		CREATE TABLE tasks (
+			id TEXT PRIMARY KEY, project_id TEXT NOT NULL REFERENCES projects(id), title TEXT NOT NULL,
+			active_at TEXT NOT NULL, status TEXT NOT NULL, created_at TEXT NOT NULL DEFAULT '',
+			version INTEGER NOT NULL DEFAULT 1, status_changed_at TEXT NOT NULL DEFAULT '',
+			status_changed_by TEXT NOT NULL DEFAULT '', priority TEXT NOT NULL DEFAULT 'normal',
+			due_at TEXT NOT NULL DEFAULT '', due_utc TEXT NOT NULL DEFAULT '', description TEXT NOT NULL DEFAULT '',
+			tags TEXT NOT NULL DEFAULT '[]', parent_id TEXT NOT NULL DEFAULT '', checklist TEXT NOT NULL DEFAULT '[]',
+			blocked_by TEXT NOT NULL DEFAULT '[]', recurrence TEXT NOT NULL DEFAULT '',
+			UNIQUE (project_id, title));
+		INSERT INTO tasks (id, project_id, title, active_at, status, parent_id) VALUES
+			('child', '` + models.DefaultProjectID + `', 'child', '2024-05-06', 'active', 'parent'),
+			('parent', '` + models.DefaultProjectID + `', 'parent', '2024-05-06', 'active', ''),
+			('orphan', '` + models.DefaultProjectID + `', 'orphan', '2024-05-06', 'active', 'gone');
+		PRAGMA user_version = 11;`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := repositories.NewSQLiteTaskRepo(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	for id, want := range map[string]string{"child": "parent", "parent": "", "orphan": ""} {
+		task, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.ParentID != want {
+			t.Errorf("%s after migrating: parentId %q, want %q", id, task.ParentID, want)
+		}
+	}
+
+	if err := repo.Delete(ctx, "parent", 0); !errors.Is(err, models.ErrHasSubtasks) {
+		t.Errorf("deleting a parent after migrating: got %v, want ErrHasSubtasks", err)
+	}
+}
+
+func TestJournaledTaskRepo(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repositories.TaskRepo {
+		repo, err := repositories.NewJournaledTaskRepo(repositories.JournalOptions{
+			Dir:          t.TempDir(),
+			Fsync:        repositories.FsyncNever,
+			CompactEvery: 50,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { repo.Close() })
+
+		return repo
+	})
+}
+
+func TestIndexedTaskRepo(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repositories.TaskRepo {
+		repo, err := repositories.NewIndexedTaskRepo(context.Background(), repositories.NewSyncMapTaskRepo())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return repo
+	})
+}
+
+func TestIndexedTaskRepoSearch(t *testing.T) {
+	ctx := context.Background()
+
+	// Tasks stored before the index was built are found too.
+	store := repositories.NewSyncMapTaskRepo()
+	existing := repotest.NewTask("Годовой отчёт", "2024-05-06")
+	if err := store.Post(ctx, existing); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := repositories.NewIndexedTaskRepo(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	find := func(query string) []string {
+		t.Helper()
+
+		hits, err := repo.Search(ctx, search.ParseQuery(query), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var titles []string
+		for _, hit := range hits {
+			titles = append(titles, hit.Task.Title)
+		}
+
+		return titles
+	}
+
+	if got := find("отчет"); !reflect.DeepEqual(got, []string{"Годовой отчёт"}) {
+		t.Errorf("search for an existing task = %v", got)
+	}
+
+	posted := repotest.NewTask("Draft budget", "2024-05-06")
+	if err := repo.Post(ctx, posted); err != nil {
+		t.Fatal(err)
+	}
+	if got := find("budg"); !reflect.DeepEqual(got, []string{"Draft budget"}) {
+		t.Errorf("search after Post = %v", got)
+	}
+
+	update := &models.Task{Title: "Final budget", ActiveAt: "2024-05-06", Status: "active"}
+	if err := repo.Put(ctx, posted.ID, update, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := find("draft"); len(got) != 0 {
+		t.Errorf("search for the old title after Put = %v", got)
+	}
+	if got := find("final"); !reflect.DeepEqual(got, []string{"Final budget"}) {
+		t.Errorf("search for the new title after Put = %v", got)
+	}
+
+	// A failed write leaves the index alone.
+	if err := repo.Post(ctx, repotest.NewTask("Final budget", "2024-05-07")); err == nil {
+		t.Fatal("duplicate Post succeeded")
+	}
+	if got := find("final"); len(got) != 1 {
+		t.Errorf("search after a rejected Post = %v", got)
+	}
+
+	// Descriptions are searched too, but a match in the title ranks higher.
+	detailed := repotest.NewTask("Call the bank", "2024-05-06")
+	detailed.Description = "Ask about the **budget** overrun and the new budget limits"
+	if err := repo.Post(ctx, detailed); err != nil {
+		t.Fatal(err)
+	}
+	if got := find("budget"); !reflect.DeepEqual(got, []string{"Final budget", "Call the bank"}) {
+		t.Errorf("search matching a title and a description = %v", got)
+	}
+	if got := find("overrun"); !reflect.DeepEqual(got, []string{"Call the bank"}) {
+		t.Errorf("search for a word of a description = %v", got)
+	}
+
+	// Batches update the index with the ops that succeed.
+	quarterly := repotest.NewTask("Quarterly budget", "2024-05-06")
+	_, err = repo.Batch(ctx, []repositories.BatchOp{
+		func(tx repositories.TaskRepo) (*models.Task, error) {
+			return quarterly, tx.Post(ctx, quarterly)
+		},
+		func(tx repositories.TaskRepo) (*models.Task, error) {
+			if err := tx.Post(ctx, repotest.NewTask("Budget review", "2024-05-06")); err != nil {
+				return nil, err
+			}
+			return nil, tx.Delete(ctx, "missing", 0)
+		},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := find("quarterly"); !reflect.DeepEqual(got, []string{"Quarterly budget"}) {
+		t.Errorf("search after a batch = %v", got)
+	}
+	if got := find("review"); len(got) != 0 {
+		t.Errorf("search for the task of a failed batch op = %v", got)
+	}
+
+	if err := repo.Delete(ctx, quarterly.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, posted.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, detailed.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := find("budget"); len(got) != 0 {
+		t.Errorf("search after Delete = %v", got)
+	}
+}
+
+func TestJournaledTaskRepoReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	opts := repositories.JournalOptions{Dir: dir, Fsync: repositories.FsyncAlways}
+
+	repo, err := repositories.NewJournaledTaskRepo(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshotted := &models.Project{ID: "snapshotted", Name: "snapshotted"}
+	if err := repo.PostProject(ctx, snapshotted); err != nil {
+		t.Fatal(err)
+	}
+
+	kept := repotest.NewTask("kept", "2024-05-06")
+	gone := repotest.NewTask("gone", "2024-05-06")
+	for _, task := range []*models.Task{kept, gone} {
+		if err := repo.Post(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	journaled := &models.Project{ID: "journaled", Name: "journaled"}
+	if err := repo.PostProject(ctx, journaled); err != nil {
+		t.Fatal(err)
+	}
+	scratch := &models.Project{ID: "scratch", Name: "scratch"}
+	if err := repo.PostProject(ctx, scratch); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteProject(ctx, scratch.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.MarkAsDone(ctx, kept.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, gone.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	batched := repotest.NewTask("batched", "2024-05-06")
+	dropped := repotest.NewTask("dropped", "2024-05-06")
+	err = repo.Atomically(ctx, func(tx repositories.TaskRepo) error {
+		if err := tx.Post(ctx, batched); err != nil {
+			return err
+		}
+		if err := tx.Atomically(ctx, func(tx repositories.TaskRepo) error {
+			if err := tx.Post(ctx, dropped); err != nil {
+				return err
+			}
+			return tx.Delete(ctx, "missing", 0)
+		}); !errors.Is(err, models.ErrNotFound) {
+			return fmt.Errorf("nested delete of a missing task: %v", err)
+		}
+		return tx.MarkAsDone(ctx, batched.ID, 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batchOp := repotest.NewTask("batch op", "2024-05-06")
+	_, err = repo.Batch(ctx, []repositories.BatchOp{
+		func(tx repositories.TaskRepo) (*models.Task, error) {
+			return batchOp, tx.Post(ctx, batchOp)
+		},
+		func(tx repositories.TaskRepo) (*models.Task, error) {
+			return nil, tx.Delete(ctx, "missing", 0)
+		},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tagged := repotest.NewTask("tagged", "2024-05-06")
+	tagged.Tags = []string{"be"}
+	tagged.ProjectID = journaled.ID
+	if err := repo.Post(ctx, tagged); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.RenameTag(ctx, "be", "backend", true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen without Close, as after a crash: snapshot plus journal.
+	reopened, err := repositories.NewJournaledTaskRepo(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	got, err := reopened.GetByID(ctx, kept.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != "done" || got.Version != 2 {
+		t.Errorf("replayed status %q version %d, want %q and 2", got.Status, got.Version, "done")
+	}
+
+	if _, err := reopened.GetByID(ctx, gone.ID); err == nil {
+		t.Error("deleted task came back after replay")
+	}
+
+	got, err = reopened.GetByID(ctx, batched.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != "done" || got.Version != 2 {
+		t.Errorf("replayed batch: status %q version %d, want %q and 2", got.Status, got.Version, "done")
+	}
+
+	if _, err := reopened.GetByID(ctx, dropped.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("task of a failed nested call after replay: got %v, want ErrNotFound", err)
+	}
+
+	if _, err := reopened.GetByID(ctx, batchOp.ID); err != nil {
+		t.Errorf("task of a batch after replay: %v", err)
+	}
+
+	got, err = reopened.GetByID(ctx, tagged.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Tags, []string{"backend"}) || got.Version != 2 {
+		t.Errorf("replayed rename: tags %q version %d, want [backend] and 2", got.Tags, got.Version)
+	}
+	if got.ProjectID != journaled.ID {
+		t.Errorf("replayed task is in project %q, want %q", got.ProjectID, journaled.ID)
+	}
+
+	for _, id := range []string{snapshotted.ID, journaled.ID} {
+		if _, err := reopened.GetProject(ctx, id); err != nil {
+			t.Errorf("project %s after replay: %v", id, err)
+		}
+	}
+	if _, err := reopened.GetProject(ctx, scratch.ID); !errors.Is(err, models.ErrProjectNotFound) {
+		t.Errorf("deleted project came back after replay: %v", err)
+	}
+}
+
+func TestJournaledTaskRepoCompactionFailure(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	opts := repositories.JournalOptions{Dir: dir, Fsync: repositories.FsyncAlways, CompactEvery: 1}
+
+	repo, err := repositories.NewJournaledTaskRepo(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A directory where the snapshot is written makes compaction fail.
+	blocker := filepath.Join(dir, "snapshot.json.tmp")
+	if err := os.Mkdir(blocker, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	first := repotest.NewTask("first", "2024-05-06")
+	if err := repo.Post(ctx, first); err != nil {
+		t.Fatalf("Post with compaction failing: %v", err)
+	}
+
+	if err := os.Remove(blocker); err != nil {
+		t.Fatal(err)
+	}
+	second := repotest.NewTask("second", "2024-05-06")
+	if err := repo.Post(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := repositories.NewJournaledTaskRepo(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	for _, task := range []*models.Task{first, second} {
+		if _, err := reopened.GetByID(ctx, task.ID); err != nil {
+			t.Errorf("%s after reopening: %v", task.Title, err)
+		}
+	}
+}
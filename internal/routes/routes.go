@@ -6,6 +6,9 @@ import (
 
 	_ "github.com/canyouhearthemusic/todo-list/docs"
 	"github.com/canyouhearthemusic/todo-list/internal/handlers"
+	"github.com/canyouhearthemusic/todo-list/internal/i18n"
+	"github.com/canyouhearthemusic/todo-list/internal/idempotency"
+	"github.com/canyouhearthemusic/todo-list/internal/services"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	httpSwagger "github.com/swaggo/http-swagger"
@@ -25,15 +28,34 @@ import (
 
 // @BasePath
 
-func New() *chi.Mux {
+// Deps are the services the handlers are wired to.
+type Deps struct {
+	Tasks    *services.TaskService
+	Projects *services.ProjectService
+	Holidays *services.HolidayService
+	Messages *i18n.Bundle
+	// Idempotency keeps the responses to requests with an Idempotency-Key.
+	Idempotency *idempotency.Store
+}
+
+func New(deps Deps) *chi.Mux {
+	handlers.SetService(deps.Tasks)
+	handlers.SetProjectService(deps.Projects)
+	handlers.SetHolidayService(deps.Holidays)
+
 	r := chi.NewRouter()
 
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
 	r.Use(middleware.URLFormat)
+	r.Use(deps.Messages.Middleware)
+	r.Use(handlers.Actor)
 
-	loadRoutes(r)
+	r.NotFound(handlers.NotFound)
+	r.MethodNotAllowed(handlers.MethodNotAllowed)
+
+	loadRoutes(r, deps)
 
 	hostname := os.Getenv("HOSTNAME")
 	if hostname == "" {
@@ -48,15 +70,50 @@ func New() *chi.Mux {
 	return r
 }
 
-func loadRoutes(r *chi.Mux) {
+func loadRoutes(r *chi.Mux, deps Deps) {
 	r.Route("/api", func(api chi.Router) {
+		api.Post("/tasks:batch", handlers.BatchTasks)
+
 		api.Route("/tasks", func(tasks chi.Router) {
 			tasks.Get("/", handlers.GetAllTasks)
-			tasks.Post("/", handlers.PostTask)
+			tasks.With(handlers.Idempotent(deps.Idempotency)).Post("/", handlers.PostTask)
+			tasks.Get("/search", handlers.SearchTasks)
+			tasks.Get("/order", handlers.GetTaskOrder)
 			tasks.Get("/{id}", handlers.GetTask)
 			tasks.Put("/{id}", handlers.PutTask)
+			tasks.Patch("/{id}", handlers.PatchTask)
 			tasks.Put("/{id}/done", handlers.DoneTask)
+			tasks.Put("/{id}/reopen", handlers.ReopenTask)
+			tasks.Put("/{id}/status", handlers.PutTaskStatus)
+			tasks.Put("/{id}/project", handlers.MoveTask)
+			tasks.Get("/{id}/checklist", handlers.GetChecklist)
+			tasks.Post("/{id}/checklist", handlers.PostChecklistItem)
+			tasks.Put("/{id}/checklist/{itemId}", handlers.PutChecklistItem)
+			tasks.Delete("/{id}/checklist/{itemId}", handlers.DeleteChecklistItem)
+			tasks.Get("/{id}/blockers", handlers.GetBlockers)
+			tasks.Put("/{id}/blockers/{blockerId}", handlers.PutBlocker)
+			tasks.Delete("/{id}/blockers/{blockerId}", handlers.DeleteBlocker)
 			tasks.Delete("/{id}", handlers.DeleteTask)
 		})
+
+		api.Route("/projects", func(projects chi.Router) {
+			projects.Get("/", handlers.GetProjects)
+			projects.Post("/", handlers.PostProject)
+			projects.Get("/{id}", handlers.GetProject)
+			projects.Put("/{id}", handlers.PutProject)
+			projects.Delete("/{id}", handlers.DeleteProject)
+		})
+
+		api.Route("/tags", func(tags chi.Router) {
+			tags.Get("/", handlers.GetTags)
+			tags.Put("/{name}", handlers.RenameTag)
+			tags.Post("/{name}/merge", handlers.MergeTag)
+		})
+
+		api.Route("/holidays", func(holidays chi.Router) {
+			holidays.Get("/", handlers.GetHolidays)
+			holidays.Post("/", handlers.PostHoliday)
+			holidays.Delete("/{id}", handlers.DeleteHoliday)
+		})
 	})
 }
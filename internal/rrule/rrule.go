@@ -0,0 +1,485 @@
+// Package rrule expands recurrence rules such as
+//
+//	FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10
+//
+// as defined by RFC 5545, section 3.3.10, at the granularity of days. It
+// supports FREQ DAILY, WEEKLY, MONTHLY and YEARLY with INTERVAL, COUNT,
+// UNTIL, BYMONTH, BYMONTHDAY, BYDAY (with ordinals such as 1MO or -1FR in
+// MONTHLY and YEARLY rules) and WKST. Rules asking for times of day
+// (HOURLY and finer, BYHOUR and alike) or for BYWEEKNO, BYYEARDAY and
+// BYSETPOS are rejected.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalid means a rule is malformed or uses parts not supported here.
+var ErrInvalid = errors.New("invalid recurrence rule")
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// WeekdayNum is a BYDAY entry: a weekday, and with a non-zero N only its
+// Nth occurrence in the month or year, counting from the end if negative.
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+// Rule is a parsed recurrence rule. Until is a date, zero if unset; Count
+// is zero if unset.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByMonth    []time.Month
+	ByMonthDay []int
+	ByDay      []WeekdayNum
+	WeekStart  time.Weekday
+}
+
+// maxPeriods bounds how many days, weeks, months or years Next and
+// NextFrom look through, so that rules that never match again, such as
+// FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30, end instead of looping.
+const maxPeriods = 100000
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Parse parses a rule, with or without the RRULE: prefix. Part names and
+// values are case-insensitive.
+func Parse(s string) (*Rule, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty rule", ErrInvalid)
+	}
+
+	r := &Rule{Interval: 1, WeekStart: time.Monday}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%w: %q is not NAME=VALUE", ErrInvalid, part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: %s appears more than once", ErrInvalid, name)
+		}
+		seen[name] = true
+
+		if err := r.set(name, value); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalid, name, err)
+		}
+	}
+
+	if err := r.check(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	return r, nil
+}
+
+func (r *Rule) set(name, value string) error {
+	var err error
+	switch name {
+	case "FREQ":
+		switch f := Frequency(value); f {
+		case Daily, Weekly, Monthly, Yearly:
+			r.Freq = f
+		case "SECONDLY", "MINUTELY", "HOURLY":
+			return fmt.Errorf("%s is finer than a day", value)
+		default:
+			return fmt.Errorf("unknown frequency %q", value)
+		}
+	case "INTERVAL":
+		r.Interval, err = parseInt(value, 1, 1000)
+	case "COUNT":
+		r.Count, err = parseInt(value, 1, 100000)
+	case "UNTIL":
+		r.Until, err = parseUntil(value)
+	case "BYMONTH":
+		for _, v := range strings.Split(value, ",") {
+			month, err := parseInt(v, 1, 12)
+			if err != nil {
+				return err
+			}
+			r.ByMonth = append(r.ByMonth, time.Month(month))
+		}
+	case "BYMONTHDAY":
+		for _, v := range strings.Split(value, ",") {
+			day, err := parseInt(strings.TrimPrefix(v, "-"), 1, 31)
+			if err != nil {
+				return err
+			}
+			if strings.HasPrefix(v, "-") {
+				day = -day
+			}
+			r.ByMonthDay = append(r.ByMonthDay, day)
+		}
+	case "BYDAY":
+		for _, v := range strings.Split(value, ",") {
+			day, err := parseWeekdayNum(v)
+			if err != nil {
+				return err
+			}
+			r.ByDay = append(r.ByDay, day)
+		}
+	case "WKST":
+		day, ok := weekdays[value]
+		if !ok {
+			return fmt.Errorf("unknown weekday %q", value)
+		}
+		r.WeekStart = day
+	case "BYSECOND", "BYMINUTE", "BYHOUR":
+		return errors.New("times of day are not supported")
+	case "BYWEEKNO", "BYYEARDAY", "BYSETPOS":
+		return errors.New("not supported")
+	default:
+		return errors.New("unknown rule part")
+	}
+
+	return err
+}
+
+func (r *Rule) check() error {
+	if r.Freq == "" {
+		return errors.New("FREQ is required")
+	}
+	if r.Count != 0 && !r.Until.IsZero() {
+		return errors.New("COUNT and UNTIL cannot be combined")
+	}
+	if r.Freq == Weekly && len(r.ByMonthDay) > 0 {
+		return errors.New("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	for _, day := range r.ByDay {
+		if day.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return errors.New("BYDAY ordinals need FREQ=MONTHLY or FREQ=YEARLY")
+		}
+	}
+
+	return nil
+}
+
+func parseInt(s string, lo, hi int) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < lo || n > hi {
+		return 0, fmt.Errorf("%q is not a number from %d to %d", s, lo, hi)
+	}
+
+	return n, nil
+}
+
+// parseUntil takes a DATE or DATE-TIME value and keeps the date.
+func parseUntil(s string) (time.Time, error) {
+	date, _, _ := strings.Cut(s, "T")
+	until, err := time.Parse("20060102", date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a date like 20240531", s)
+	}
+
+	return until, nil
+}
+
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("unknown weekday %q", s)
+	}
+
+	day, ok := weekdays[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("unknown weekday %q", s)
+	}
+
+	var n int
+	if prefix := strings.TrimPrefix(s[:len(s)-2], "+"); prefix != "" {
+		abs, err := parseInt(strings.TrimPrefix(prefix, "-"), 1, 53)
+		if err != nil {
+			return WeekdayNum{}, fmt.Errorf("bad ordinal in %q", s)
+		}
+		n = abs
+		if strings.HasPrefix(prefix, "-") {
+			n = -abs
+		}
+	}
+
+	return WeekdayNum{Weekday: day, N: n}, nil
+}
+
+// String formats r in a canonical form accepted by Parse.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, month := range r.ByMonth {
+			months[i] = strconv.Itoa(int(month))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = weekdayName(day.Weekday)
+			if day.N != 0 {
+				days[i] = strconv.Itoa(day.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayName(r.WeekStart))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func weekdayName(day time.Weekday) string {
+	for name, d := range weekdays {
+		if d == day {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// Next returns the first occurrence after the date after of the series
+// that r starts on start, with its number in the series, 1 being start
+// itself. ok is false once the series has ended. Times of day are ignored.
+func (r *Rule) Next(start, after time.Time) (next time.Time, n int, ok bool) {
+	start, after = day(start), day(after)
+	if start.After(after) {
+		return start, 1, true
+	}
+
+	return r.next(start, start, 1, after)
+}
+
+// NextFrom is Next for a series known to have its nth occurrence on from.
+// It looks for the next occurrence from there rather than from start, so
+// finding it takes as long however far the series has gone.
+func (r *Rule) NextFrom(start, from time.Time, n int, after time.Time) (next time.Time, m int, ok bool) {
+	start, from, after = day(start), day(from), day(after)
+	if from.Before(start) || n < 1 {
+		return r.Next(start, after)
+	}
+
+	return r.next(start, from, n, after)
+}
+
+// next walks the periods of the series from the one holding from, its nth
+// occurrence; every period holding an occurrence is one the series goes
+// through.
+func (r *Rule) next(start, from time.Time, n int, after time.Time) (time.Time, int, bool) {
+	period := r.firstPeriod(from)
+	for i := 0; i < maxPeriods; i++ {
+		for _, date := range r.expand(period, start) {
+			if !date.After(from) {
+				continue
+			}
+			if !r.Until.IsZero() && date.After(r.Until) {
+				return time.Time{}, 0, false
+			}
+			if n++; r.Count > 0 && n > r.Count {
+				return time.Time{}, 0, false
+			}
+			if date.After(after) {
+				return date, n, true
+			}
+		}
+		period = r.nextPeriod(period)
+	}
+
+	return time.Time{}, 0, false
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// firstPeriod returns the first day of the period of r holding date.
+func (r *Rule) firstPeriod(date time.Time) time.Time {
+	switch r.Freq {
+	case Weekly:
+		return date.AddDate(0, 0, -((int(date.Weekday()) - int(r.WeekStart) + 7) % 7))
+	case Monthly:
+		return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case Yearly:
+		return time.Date(date.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return date
+}
+
+func (r *Rule) nextPeriod(period time.Time) time.Time {
+	switch r.Freq {
+	case Weekly:
+		return period.AddDate(0, 0, 7*r.Interval)
+	case Monthly:
+		return period.AddDate(0, r.Interval, 0)
+	case Yearly:
+		return period.AddDate(r.Interval, 0, 0)
+	}
+
+	return period.AddDate(0, 0, r.Interval)
+}
+
+// expand returns the dates of the period starting on period that r
+// selects, in order. Parts that would expand a period of start's own
+// frequency default to start, as RFC 5545 has it.
+func (r *Rule) expand(period, start time.Time) []time.Time {
+	var dates []time.Time
+
+	switch r.Freq {
+	case Daily:
+		dates = []time.Time{period}
+	case Weekly:
+		for i := 0; i < 7; i++ {
+			date := period.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && date.Weekday() == start.Weekday() || hasWeekday(r.ByDay, date.Weekday()) {
+				dates = append(dates, date)
+			}
+		}
+	case Monthly:
+		dates = r.expandMonth(period, start)
+	case Yearly:
+		switch {
+		case len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0 && len(r.ByDay) > 0:
+			// BYDAY alone spans the whole year, ordinals included.
+			dates = byDay(period, period.AddDate(1, 0, 0), r.ByDay)
+		case len(r.ByMonth) == 0 && len(r.ByMonthDay) > 0:
+			for month := 0; month < 12; month++ {
+				dates = append(dates, r.expandMonth(period.AddDate(0, month, 0), start)...)
+			}
+		default:
+			months := r.ByMonth
+			if len(months) == 0 {
+				months = []time.Month{start.Month()}
+			}
+			for _, month := range months {
+				dates = append(dates, r.expandMonth(time.Date(period.Year(), month, 1, 0, 0, 0, 0, time.UTC), start)...)
+			}
+		}
+	}
+
+	dates = slices.DeleteFunc(dates, func(date time.Time) bool { return !r.matches(date) })
+	slices.SortFunc(dates, func(a, b time.Time) int { return a.Compare(b) })
+
+	return slices.CompactFunc(dates, time.Time.Equal)
+}
+
+// expandMonth returns the dates of the month starting on first that
+// BYMONTHDAY and BYDAY select, or start's day of the month if neither is
+// set.
+func (r *Rule) expandMonth(first, start time.Time) []time.Time {
+	next := first.AddDate(0, 1, 0)
+	last := next.AddDate(0, 0, -1).Day()
+
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		if start.Day() > last {
+			return nil
+		}
+		return []time.Time{first.AddDate(0, 0, start.Day()-1)}
+	}
+
+	if len(r.ByMonthDay) == 0 {
+		return byDay(first, next, r.ByDay)
+	}
+
+	var dates []time.Time
+	for _, d := range r.ByMonthDay {
+		if d < 0 {
+			d = last + 1 + d
+		}
+		if d >= 1 && d <= last {
+			dates = append(dates, first.AddDate(0, 0, d-1))
+		}
+	}
+
+	return dates
+}
+
+// byDay returns the dates from from up to but excluding to that days
+// select.
+func byDay(from, to time.Time, days []WeekdayNum) []time.Time {
+	var dates []time.Time
+	for _, wd := range days {
+		var all []time.Time
+		for date := from; date.Before(to); date = date.AddDate(0, 0, 1) {
+			if date.Weekday() == wd.Weekday {
+				all = append(all, date)
+			}
+		}
+
+		switch {
+		case wd.N == 0:
+			dates = append(dates, all...)
+		case wd.N > 0 && wd.N <= len(all):
+			dates = append(dates, all[wd.N-1])
+		case wd.N < 0 && -wd.N <= len(all):
+			dates = append(dates, all[len(all)+wd.N])
+		}
+	}
+
+	return dates
+}
+
+func hasWeekday(days []WeekdayNum, weekday time.Weekday) bool {
+	return slices.ContainsFunc(days, func(wd WeekdayNum) bool { return wd.Weekday == weekday })
+}
+
+// matches applies the parts of r that limit rather than expand the dates
+// of a period.
+func (r *Rule) matches(date time.Time) bool {
+	if len(r.ByMonth) > 0 && !slices.Contains(r.ByMonth, date.Month()) {
+		return false
+	}
+
+	if len(r.ByDay) > 0 && (r.Freq == Daily || len(r.ByMonthDay) > 0) && !hasWeekday(r.ByDay, date.Weekday()) {
+		return false
+	}
+
+	if len(r.ByMonthDay) > 0 && r.Freq == Daily {
+		last := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		if !slices.ContainsFunc(r.ByMonthDay, func(d int) bool { return d == date.Day() || last+1+d == date.Day() }) {
+			return false
+		}
+	}
+
+	return true
+}
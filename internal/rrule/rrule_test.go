@@ -0,0 +1,194 @@
+package rrule_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/rrule"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+// occurrences lists up to limit occurrences of rule starting on start,
+// checking that NextFrom, resuming from each one, agrees with Next.
+func occurrences(t *testing.T, rule, start string, limit int) string {
+	t.Helper()
+
+	r, err := rrule.Parse(rule)
+	if err != nil {
+		t.Fatalf("Parse(%s): %v", rule, err)
+	}
+
+	var dates []string
+	after := date(start).AddDate(0, 0, -1)
+	for want := 1; want <= limit; want++ {
+		next, n, ok := r.Next(date(start), after)
+		if want > 1 {
+			resumed, m, resumedOK := r.NextFrom(date(start), after, want-1, after)
+			if !resumed.Equal(next) || m != n || resumedOK != ok {
+				t.Errorf("%s: NextFrom(%s, #%d) = %s #%d %v, want %s #%d %v", rule, after.Format("2006-01-02"), want-1,
+					resumed.Format("2006-01-02"), m, resumedOK, next.Format("2006-01-02"), n, ok)
+			}
+		}
+		if !ok {
+			break
+		}
+		if n != want {
+			t.Errorf("%s: occurrence %s numbered %d, want %d", rule, next.Format("2006-01-02"), n, want)
+		}
+		dates = append(dates, next.Format("2006-01-02"))
+		after = next
+	}
+
+	return strings.Join(dates, " ")
+}
+
+// Mostly examples from RFC 5545, section 3.8.5.3.
+func TestNext(t *testing.T) {
+	tests := []struct {
+		rule, start string
+		limit       int
+		want        string
+	}{
+		{"FREQ=DAILY;COUNT=3", "1997-09-02", 10, "1997-09-02 1997-09-03 1997-09-04"},
+		{"FREQ=DAILY;INTERVAL=10;COUNT=3", "1997-09-02", 10, "1997-09-02 1997-09-12 1997-09-22"},
+		{"FREQ=DAILY;UNTIL=19970905T000000Z", "1997-09-02", 10, "1997-09-02 1997-09-03 1997-09-04 1997-09-05"},
+		{"FREQ=WEEKLY;COUNT=3", "1997-09-02", 10, "1997-09-02 1997-09-09 1997-09-16"},
+		// UNTIL is inclusive, and dates have no time of day to exclude it.
+		{"FREQ=WEEKLY;UNTIL=19971007;WKST=SU;BYDAY=TU,TH", "1997-09-02", 20,
+			"1997-09-02 1997-09-04 1997-09-09 1997-09-11 1997-09-16 1997-09-18 1997-09-23 1997-09-25 1997-09-30 1997-10-02 1997-10-07"},
+		{"FREQ=WEEKLY;INTERVAL=2;WKST=SU;BYDAY=MO,WE,FR", "1997-09-01", 6,
+			"1997-09-01 1997-09-03 1997-09-05 1997-09-15 1997-09-17 1997-09-19"},
+		{"FREQ=MONTHLY;COUNT=4;BYDAY=1FR", "1997-09-05", 10, "1997-09-05 1997-10-03 1997-11-07 1997-12-05"},
+		{"FREQ=MONTHLY;COUNT=6;BYDAY=-2MO", "1997-09-22", 10, "1997-09-22 1997-10-20 1997-11-17 1997-12-22 1998-01-19 1998-02-16"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-3", "1997-09-28", 3, "1997-09-28 1997-10-29 1997-11-28"},
+		{"FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", "1997-09-02", 4, "1997-09-02 1998-02-13 1998-03-13 1998-11-13"},
+		{"FREQ=YEARLY;COUNT=3;BYMONTH=6,7", "1997-06-10", 10, "1997-06-10 1997-07-10 1998-06-10"},
+		{"FREQ=YEARLY;BYDAY=20MO", "1997-05-19", 3, "1997-05-19 1998-05-18 1999-05-17"},
+		{"FREQ=YEARLY;BYMONTH=3;BYDAY=TH", "1997-03-13", 4, "1997-03-13 1997-03-20 1997-03-27 1998-03-05"},
+		{"FREQ=DAILY;INTERVAL=2", "1997-09-02", 3, "1997-09-02 1997-09-04 1997-09-06"},
+		{"FREQ=YEARLY;UNTIL=20000131T140000Z;BYMONTH=1;BYDAY=SU,MO,TU,WE,TH,FR,SA", "1998-01-01", 3,
+			"1998-01-01 1998-01-02 1998-01-03"},
+		{"FREQ=DAILY;UNTIL=20000131T140000Z;BYMONTH=1", "1999-01-30", 4, "1999-01-30 1999-01-31 2000-01-01 2000-01-02"},
+		{"FREQ=WEEKLY;INTERVAL=2;WKST=SU", "1997-09-02", 3, "1997-09-02 1997-09-16 1997-09-30"},
+		{"FREQ=WEEKLY;COUNT=10;WKST=SU;BYDAY=TU,TH", "1997-09-02", 20,
+			"1997-09-02 1997-09-04 1997-09-09 1997-09-11 1997-09-16 1997-09-18 1997-09-23 1997-09-25 1997-09-30 1997-10-02"},
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=8;WKST=SU;BYDAY=TU,TH", "1997-09-02", 20,
+			"1997-09-02 1997-09-04 1997-09-16 1997-09-18 1997-09-30 1997-10-02 1997-10-14 1997-10-16"},
+		{"FREQ=MONTHLY;UNTIL=19971224T000000Z;BYDAY=1FR", "1997-09-05", 10, "1997-09-05 1997-10-03 1997-11-07 1997-12-05"},
+		{"FREQ=MONTHLY;INTERVAL=2;COUNT=10;BYDAY=1SU,-1SU", "1997-09-07", 20,
+			"1997-09-07 1997-09-28 1997-11-02 1997-11-30 1998-01-04 1998-01-25 1998-03-01 1998-03-29 1998-05-03 1998-05-31"},
+		{"FREQ=MONTHLY;COUNT=10;BYMONTHDAY=2,15", "1997-09-02", 20,
+			"1997-09-02 1997-09-15 1997-10-02 1997-10-15 1997-11-02 1997-11-15 1997-12-02 1997-12-15 1998-01-02 1998-01-15"},
+		{"FREQ=MONTHLY;COUNT=10;BYMONTHDAY=1,-1", "1997-09-30", 20,
+			"1997-09-30 1997-10-01 1997-10-31 1997-11-01 1997-11-30 1997-12-01 1997-12-31 1998-01-01 1998-01-31 1998-02-01"},
+		{"FREQ=MONTHLY;INTERVAL=18;COUNT=10;BYMONTHDAY=10,11,12,13,14,15", "1997-09-10", 20,
+			"1997-09-10 1997-09-11 1997-09-12 1997-09-13 1997-09-14 1997-09-15 1999-03-10 1999-03-11 1999-03-12 1999-03-13"},
+		{"FREQ=MONTHLY;INTERVAL=2;BYDAY=TU", "1997-09-02", 6, "1997-09-02 1997-09-09 1997-09-16 1997-09-23 1997-09-30 1997-11-04"},
+		{"FREQ=YEARLY;INTERVAL=2;COUNT=10;BYMONTH=1,2,3", "1997-03-10", 20,
+			"1997-03-10 1999-01-10 1999-02-10 1999-03-10 2001-01-10 2001-02-10 2001-03-10 2003-01-10 2003-02-10 2003-03-10"},
+		{"FREQ=YEARLY;BYDAY=TH;BYMONTH=6,7,8", "1997-06-05", 6, "1997-06-05 1997-06-12 1997-06-19 1997-06-26 1997-07-03 1997-07-10"},
+		{"FREQ=MONTHLY;BYDAY=SA;BYMONTHDAY=7,8,9,10,11,12,13", "1997-09-13", 5,
+			"1997-09-13 1997-10-11 1997-11-08 1997-12-13 1998-01-10"},
+		{"FREQ=YEARLY;INTERVAL=4;BYMONTH=11;BYDAY=TU;BYMONTHDAY=2,3,4,5,6,7,8", "1996-11-05", 3, "1996-11-05 2000-11-07 2004-11-02"},
+		{"FREQ=MONTHLY;BYMONTHDAY=15,30;COUNT=5", "2007-01-15", 10, "2007-01-15 2007-01-30 2007-02-15 2007-03-15 2007-03-30"},
+		// WKST decides which week a day belongs to, and so which weeks an
+		// INTERVAL skips.
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO", "1997-08-05", 10, "1997-08-05 1997-08-10 1997-08-19 1997-08-24"},
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU", "1997-08-05", 10, "1997-08-05 1997-08-17 1997-08-19 1997-08-31"},
+		// A 31st that a month lacks is skipped, not moved.
+		{"FREQ=MONTHLY", "2024-01-31", 4, "2024-01-31 2024-03-31 2024-05-31 2024-07-31"},
+		{"FREQ=YEARLY", "2024-02-29", 2, "2024-02-29 2028-02-29"},
+		// A rule that never matches ends instead of looping.
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", "2024-01-01", 3, "2024-01-01"},
+	}
+
+	for _, tt := range tests {
+		if got := occurrences(t, tt.rule, tt.start, tt.limit); got != tt.want {
+			t.Errorf("%s from %s:\n got %s\nwant %s", tt.rule, tt.start, got, tt.want)
+		}
+	}
+}
+
+func TestNextSkipsToAfter(t *testing.T) {
+	r, err := rrule.Parse("FREQ=WEEKLY;BYDAY=MO;COUNT=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2024-05-06 is a Monday.
+	next, n, ok := r.Next(date("2024-05-06"), date("2024-05-22"))
+	if !ok || !next.Equal(date("2024-05-27")) || n != 4 {
+		t.Errorf("Next after 2024-05-22 = %s #%d %v, want 2024-05-27 #4", next.Format("2006-01-02"), n, ok)
+	}
+
+	if _, _, ok := r.Next(date("2024-05-06"), date("2024-06-03")); ok {
+		t.Error("Next after the fifth occurrence: want the series to have ended")
+	}
+}
+
+// Resuming a series far from its start takes as long as near it.
+func TestNextFromLongSeries(t *testing.T) {
+	r, err := rrule.Parse("FREQ=DAILY;COUNT=100000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := date("2000-01-01")
+	from := start.AddDate(0, 0, 99998)
+	next, n, ok := r.NextFrom(start, from, 99999, from)
+	if !ok || !next.Equal(from.AddDate(0, 0, 1)) || n != 100000 {
+		t.Errorf("NextFrom the 99999th = %s #%d %v, want the day after as #100000", next.Format("2006-01-02"), n, ok)
+	}
+	if _, _, ok := r.NextFrom(start, next, n, next); ok {
+		t.Error("NextFrom the last occurrence: want the series to have ended")
+	}
+}
+
+func TestParse(t *testing.T) {
+	valid := map[string]string{
+		"rrule:freq=weekly;byday=mo,we":           "FREQ=WEEKLY;BYDAY=MO,WE",
+		"FREQ=MONTHLY;BYDAY=+1MO,-1FR;INTERVAL=2": "FREQ=MONTHLY;INTERVAL=2;BYDAY=1MO,-1FR",
+		"FREQ=YEARLY;UNTIL=20301231;WKST=SU":      "FREQ=YEARLY;UNTIL=20301231;WKST=SU",
+	}
+	for rule, want := range valid {
+		r, err := rrule.Parse(rule)
+		if err != nil {
+			t.Errorf("Parse(%s): %v", rule, err)
+			continue
+		}
+		if got := r.String(); got != want {
+			t.Errorf("Parse(%s).String() = %s, want %s", rule, got, want)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"BYDAY=MO",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;BYHOUR=9",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=3;UNTIL=20300101",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=YEARLY;BYSETPOS=1",
+		"FREQ=DAILY;COLOR=red",
+		"FREQ",
+	}
+	for _, rule := range invalid {
+		if _, err := rrule.Parse(rule); !errors.Is(err, rrule.ErrInvalid) {
+			t.Errorf("Parse(%q): got %v, want ErrInvalid", rule, err)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+// Package search is an in-memory inverted index with BM25 ranking, prefix
+// matching and highlighted snippets.
+//
+// Text is split into runs of letters and digits in any script and folded to
+// lower case, with Cyrillic ё folded to е, so "Отчёт" is found by "отчет".
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Field is a piece of a document's text. Terms in a field with a higher
+// Weight count for more when ranking.
+type Field struct {
+	Name   string
+	Text   string
+	Weight float64
+}
+
+// Hit is a matching document and its relevance; higher is better.
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// Index is safe for concurrent use.
+type Index struct {
+	mu sync.RWMutex
+	// postings maps a term to the weighted frequency of the term in each
+	// document containing it.
+	postings map[string]map[string]float64
+	// terms holds the keys of postings in order, for prefix lookups.
+	terms       []string
+	docs        map[string]*doc
+	totalLength float64
+}
+
+type doc struct {
+	length float64
+	terms  []string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings: map[string]map[string]float64{},
+		docs:     map[string]*doc{},
+	}
+}
+
+// Set indexes the document id, replacing any previous version of it.
+func (idx *Index) Set(id string, fields ...Field) {
+	freqs := map[string]float64{}
+	var length float64
+	for _, f := range fields {
+		weight := f.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		for _, tok := range tokenize([]rune(f.Text)) {
+			freqs[tok.term] += weight
+			length += weight
+		}
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+
+	d := &doc{length: length}
+	for term, freq := range freqs {
+		postings, ok := idx.postings[term]
+		if !ok {
+			postings = map[string]float64{}
+			idx.postings[term] = postings
+			idx.insertTerm(term)
+		}
+		postings[id] = freq
+		d.terms = append(d.terms, term)
+	}
+
+	idx.docs[id] = d
+	idx.totalLength += length
+}
+
+// Remove drops the document id from the index.
+func (idx *Index) Remove(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+}
+
+func (idx *Index) remove(id string) {
+	d, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+
+	for _, term := range d.terms {
+		postings := idx.postings[term]
+		delete(postings, id)
+		if len(postings) == 0 {
+			delete(idx.postings, term)
+			idx.deleteTerm(term)
+		}
+	}
+
+	delete(idx.docs, id)
+	idx.totalLength -= d.length
+}
+
+func (idx *Index) insertTerm(term string) {
+	i := sort.SearchStrings(idx.terms, term)
+	idx.terms = append(idx.terms, "")
+	copy(idx.terms[i+1:], idx.terms[i:])
+	idx.terms[i] = term
+}
+
+func (idx *Index) deleteTerm(term string) {
+	i := sort.SearchStrings(idx.terms, term)
+	if i < len(idx.terms) && idx.terms[i] == term {
+		idx.terms = append(idx.terms[:i], idx.terms[i+1:]...)
+	}
+}
+
+// BM25 parameters.
+const (
+	k1 = 1.2
+	b  = 0.75
+	// prefixWeight scales the score of a term that only matches a query
+	// term as a prefix, so "report" ranks an exact "report" above "reports".
+	prefixWeight = 0.5
+)
+
+// Search returns the documents containing every term of q, either exactly
+// or as a prefix, best first. A limit of zero means no limit.
+func (idx *Index) Search(q Query, limit int) []Hit {
+	if len(q.terms) == 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	n := float64(len(idx.docs))
+	avgLength := idx.totalLength / math.Max(n, 1)
+
+	var scores map[string]float64
+	for _, qt := range q.terms {
+		// Each document scores by its best expansion of the query term. The
+		// idf is that of the query term itself, i.e. of all documents
+		// matching any expansion, so that a rare longer word does not
+		// outrank an exact match.
+		terms := idx.expand(qt)
+
+		matching := map[string]bool{}
+		for _, term := range terms {
+			for id := range idx.postings[term] {
+				matching[id] = true
+			}
+		}
+		df := float64(len(matching))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+		best := map[string]float64{}
+		for _, term := range terms {
+			weight := 1.0
+			if term != qt {
+				weight = prefixWeight
+			}
+
+			for id, tf := range idx.postings[term] {
+				norm := tf + k1*(1-b+b*idx.docs[id].length/avgLength)
+				score := weight * idf * tf * (k1 + 1) / norm
+				if score > best[id] {
+					best[id] = score
+				}
+			}
+		}
+
+		if scores == nil {
+			scores = best
+			continue
+		}
+
+		// Keep only documents matching every term so far.
+		for id := range scores {
+			if s, ok := best[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits
+}
+
+// expand returns the indexed terms starting with prefix.
+func (idx *Index) expand(prefix string) []string {
+	var terms []string
+	for i := sort.SearchStrings(idx.terms, prefix); i < len(idx.terms) && strings.HasPrefix(idx.terms[i], prefix); i++ {
+		terms = append(terms, idx.terms[i])
+	}
+
+	return terms
+}
+
+// Query is a parsed search query.
+type Query struct {
+	terms []string
+}
+
+// ParseQuery splits s into terms the way indexed text is split.
+func ParseQuery(s string) Query {
+	var q Query
+	seen := map[string]bool{}
+	for _, tok := range tokenize([]rune(s)) {
+		if !seen[tok.term] {
+			seen[tok.term] = true
+			q.terms = append(q.terms, tok.term)
+		}
+	}
+
+	return q
+}
+
+// Empty reports whether q has no terms, e.g. because s was only punctuation.
+func (q Query) Empty() bool {
+	return len(q.terms) == 0
+}
+
+// matches reports whether term matches one of q's terms, exactly or as a
+// prefix.
+func (q Query) matches(term string) bool {
+	for _, qt := range q.terms {
+		if strings.HasPrefix(term, qt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type token struct {
+	term       string
+	start, end int // rune offsets in the original text
+}
+
+func tokenize(text []rune) []token {
+	var tokens []token
+
+	for i := 0; i < len(text); {
+		if !isWordRune(text[i]) {
+			i++
+			continue
+		}
+
+		start := i
+		var term strings.Builder
+		for ; i < len(text) && isWordRune(text[i]); i++ {
+			term.WriteRune(fold(text[i]))
+		}
+
+		tokens = append(tokens, token{term: term.String(), start: start, end: i})
+	}
+
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+func fold(r rune) rune {
+	r = unicode.ToLower(r)
+	if r == 'ё' {
+		return 'е'
+	}
+
+	return r
+}
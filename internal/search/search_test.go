@@ -0,0 +1,110 @@
+package search_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/canyouhearthemusic/todo-list/internal/search"
+)
+
+func newIndex(docs map[string]string) *search.Index {
+	idx := search.NewIndex()
+	for id, text := range docs {
+		idx.Set(id, search.Field{Name: "title", Text: text})
+	}
+
+	return idx
+}
+
+func ids(hits []search.Hit) []string {
+	out := []string{}
+	for _, hit := range hits {
+		out = append(out, hit.ID)
+	}
+
+	return out
+}
+
+func TestSearch(t *testing.T) {
+	idx := newIndex(map[string]string{
+		"report":    "Write quarterly report",
+		"reports":   "File reports",
+		"cyrillic":  "Годовой ОТЧЁТ для банка",
+		"groceries": "Buy groceries",
+		"mixed":     "Отчёт: report in two languages",
+	})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// Exact matches outrank prefix matches; shorter documents win ties.
+		{"report", []string{"report", "mixed", "reports"}},
+		{"rep", []string{"reports", "report", "mixed"}},
+		{"отчет", []string{"cyrillic", "mixed"}},
+		{"ГОДОВ отч", []string{"cyrillic"}},
+		{"report отчёт", []string{"mixed"}},
+		{"groceries milk", []string{}},
+		{"!!!", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := ids(idx.Search(search.ParseQuery(tt.query), 0)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	if got := ids(idx.Search(search.ParseQuery("rep"), 2)); len(got) != 2 {
+		t.Errorf("Search with limit 2 returned %v", got)
+	}
+}
+
+func TestSetReplacesAndRemoveDrops(t *testing.T) {
+	idx := newIndex(map[string]string{"1": "draft plan"})
+
+	idx.Set("1", search.Field{Name: "title", Text: "final budget"})
+	if got := ids(idx.Search(search.ParseQuery("draft"), 0)); len(got) != 0 {
+		t.Errorf("old text still matches after Set: %v", got)
+	}
+	if got := ids(idx.Search(search.ParseQuery("budg"), 0)); !reflect.DeepEqual(got, []string{"1"}) {
+		t.Errorf("new text does not match after Set: %v", got)
+	}
+
+	idx.Remove("1")
+	if got := ids(idx.Search(search.ParseQuery("budget"), 0)); len(got) != 0 {
+		t.Errorf("removed document still matches: %v", got)
+	}
+}
+
+func TestFieldWeights(t *testing.T) {
+	idx := search.NewIndex()
+	idx.Set("title", search.Field{Name: "title", Text: "invoice", Weight: 3}, search.Field{Name: "body", Text: "pay it"})
+	idx.Set("body", search.Field{Name: "title", Text: "pay it", Weight: 3}, search.Field{Name: "body", Text: "invoice"})
+
+	if got := ids(idx.Search(search.ParseQuery("invoice"), 0)); !reflect.DeepEqual(got, []string{"title", "body"}) {
+		t.Errorf("Search = %v, want the title match first", got)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		query, text string
+		width       int
+		want        string
+	}{
+		{"отчет", "Годовой ОТЧЁТ", 0, "Годовой <mark>ОТЧЁТ</mark>"},
+		{"rep q", "Write quarterly report & reports", 0, "Write <mark>quarterly</mark> <mark>report</mark> &amp; <mark>reports</mark>"},
+		{"<b>", "use <b>bold</b>", 0, "use &lt;<mark>b</mark>&gt;<mark>bold</mark>&lt;/<mark>b</mark>&gt;"},
+		{"missing", "nothing here", 0, ""},
+		{
+			"needle", "one two three four five six seven eight needle nine ten eleven twelve", 24,
+			"…eight <mark>needle</mark> nine ten…",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := search.ParseQuery(tt.query).Snippet(tt.text, tt.width); got != tt.want {
+			t.Errorf("Snippet(%q, %q) = %q, want %q", tt.query, tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package search
+
+import (
+	"html"
+	"strings"
+)
+
+const (
+	MarkStart = "<mark>"
+	MarkEnd   = "</mark>"
+	ellipsis  = "…"
+)
+
+// Snippet returns up to width runes of text around the first term matching
+// q, with every matching term wrapped in MarkStart and MarkEnd. The rest of
+// the text is HTML-escaped, so the snippet is safe to render as HTML. It
+// returns "" if nothing in text matches.
+func (q Query) Snippet(text string, width int) string {
+	runes := []rune(text)
+	tokens := tokenize(runes)
+
+	first := -1
+	for i, tok := range tokens {
+		if q.matches(tok.term) {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	from, to := 0, len(runes)
+	if width > 0 && len(runes) > width {
+		// Show a little context before the first match, starting on a word.
+		from = tokens[first].start - width/4
+		if from <= 0 {
+			from = 0
+		} else {
+			for _, tok := range tokens {
+				if tok.start >= from {
+					from = tok.start
+					break
+				}
+			}
+		}
+
+		to = from + width
+		if to >= len(runes) {
+			to = len(runes)
+		} else {
+			// End on a word too, but never before the first match.
+			end := tokens[first].end
+			for _, tok := range tokens[first:] {
+				if tok.end > to {
+					break
+				}
+				end = tok.end
+			}
+			to = end
+		}
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString(ellipsis)
+	}
+
+	pos := from
+	for _, tok := range tokens {
+		if tok.start < from || tok.end > to || !q.matches(tok.term) {
+			continue
+		}
+
+		b.WriteString(html.EscapeString(string(runes[pos:tok.start])))
+		b.WriteString(MarkStart)
+		b.WriteString(html.EscapeString(string(runes[tok.start:tok.end])))
+		b.WriteString(MarkEnd)
+		pos = tok.end
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:to])))
+
+	if to < len(runes) {
+		b.WriteString(ellipsis)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,17 @@
+package services
+
+import "context"
+
+type actorKey struct{}
+
+// WithActor returns a context naming who makes the request. Status changes
+// made with it are recorded as made by actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor stored by WithActor, or "" if none.
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
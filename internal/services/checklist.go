@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/google/uuid"
+)
+
+// GetChecklist returns the checklist of task id along with the task.
+func (ts *TaskService) GetChecklist(ctx context.Context, id string) ([]models.ChecklistItem, *models.Task, error) {
+	task, err := ts.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	checklist := task.Checklist
+	if checklist == nil {
+		checklist = []models.ChecklistItem{}
+	}
+
+	return checklist, task, nil
+}
+
+// AddChecklistItem appends an item to the checklist of task id if pre
+// holds, returning the item and the changed task.
+func (ts *TaskService) AddChecklistItem(ctx context.Context, id string, req models.ChecklistItemRequest, pre Precondition) (*models.ChecklistItem, *models.Task, error) {
+	item := models.ChecklistItem{ID: uuid.New().String(), Text: strings.TrimSpace(req.Text), Done: req.Done}
+	if err := item.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	task, err := ts.editChecklist(ctx, id, pre, func(checklist []models.ChecklistItem) ([]models.ChecklistItem, error) {
+		if len(checklist) >= models.MaxChecklistItems {
+			verr := &models.ValidationError{}
+			verr.Add("checklist", fmt.Sprintf("a checklist can have at most %d items", models.MaxChecklistItems))
+			return nil, verr
+		}
+
+		return append(checklist, item), nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &item, task, nil
+}
+
+// PutChecklistItem replaces the text and state of item itemID of task id
+// if pre holds, returning the item and the changed task.
+func (ts *TaskService) PutChecklistItem(ctx context.Context, id, itemID string, req models.ChecklistItemRequest, pre Precondition) (*models.ChecklistItem, *models.Task, error) {
+	item := models.ChecklistItem{ID: itemID, Text: strings.TrimSpace(req.Text), Done: req.Done}
+	if err := item.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	task, err := ts.editChecklist(ctx, id, pre, func(checklist []models.ChecklistItem) ([]models.ChecklistItem, error) {
+		i := checklistIndex(checklist, itemID)
+		if i < 0 {
+			return nil, models.ErrChecklistItemNotFound
+		}
+		checklist[i] = item
+
+		return checklist, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &item, task, nil
+}
+
+// DeleteChecklistItem removes item itemID from the checklist of task id if
+// pre holds, returning the changed task.
+func (ts *TaskService) DeleteChecklistItem(ctx context.Context, id, itemID string, pre Precondition) (*models.Task, error) {
+	return ts.editChecklist(ctx, id, pre, func(checklist []models.ChecklistItem) ([]models.ChecklistItem, error) {
+		i := checklistIndex(checklist, itemID)
+		if i < 0 {
+			return nil, models.ErrChecklistItemNotFound
+		}
+
+		return slices.Delete(checklist, i, i+1), nil
+	})
+}
+
+// editChecklist stores the checklist edit makes of a copy of the checklist
+// of task id, if pre holds. The write is conditional on the version edited.
+func (ts *TaskService) editChecklist(ctx context.Context, id string, pre Precondition, edit func([]models.ChecklistItem) ([]models.ChecklistItem, error)) (*models.Task, error) {
+	current, err := ts.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pre.check(current); err != nil {
+		return nil, err
+	}
+
+	checklist, err := edit(slices.Clone(current.Checklist))
+	if err != nil {
+		return nil, err
+	}
+
+	task := *current
+	task.Checklist = checklist
+	if err := ts.repo.Put(ctx, id, &task, current.Version); err != nil {
+		return nil, err
+	}
+
+	return &task, nil
+}
+
+// newChecklist gives the items of a new task fresh IDs and trimmed texts.
+func newChecklist(items []models.ChecklistItem) []models.ChecklistItem {
+	if len(items) == 0 {
+		return nil
+	}
+
+	checklist := make([]models.ChecklistItem, len(items))
+	for i, item := range items {
+		checklist[i] = models.ChecklistItem{ID: uuid.New().String(), Text: strings.TrimSpace(item.Text), Done: item.Done}
+	}
+
+	return checklist
+}
+
+func checklistIndex(checklist []models.ChecklistItem, id string) int {
+	return slices.IndexFunc(checklist, func(item models.ChecklistItem) bool {
+		return item.ID == id
+	})
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/canyouhearthemusic/todo-list/internal/repositories"
+)
+
+// What marking a task done does while some of the tasks it is blocked by
+// are unfinished, that is not closed.
+const (
+	// BlockersBlock refuses it with models.ErrBlocked.
+	BlockersBlock = "block"
+	// BlockersWarn lets it happen; see UnfinishedBlockers.
+	BlockersWarn = "warn"
+)
+
+// orderSort breaks ties between tasks TaskOrder could put next.
+var orderSort = repositories.Sort{{Field: "priority", Desc: true}, {Field: "dueAt"}, {Field: "activeAt"}}
+
+// GetBlockers returns the tasks task id is blocked by, in the order they
+// were added, along with the task. Deleted blockers are left out.
+func (ts *TaskService) GetBlockers(ctx context.Context, id string) ([]*models.Task, *models.Task, error) {
+	task, err := ts.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blockers := []*models.Task{}
+	for _, blockerID := range task.BlockedBy {
+		blocker, err := ts.repo.GetByID(ctx, blockerID)
+		if errors.Is(err, models.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		blockers = append(blockers, blocker)
+	}
+
+	return blockers, task, nil
+}
+
+// AddBlocker makes task id blocked by task blocker if pre holds, unless
+// that would make a cycle. Adding a blocker twice changes nothing.
+func (ts *TaskService) AddBlocker(ctx context.Context, id, blocker string, pre Precondition) (*models.Task, error) {
+	return ts.editBlockers(ctx, id, pre, func(tx *TaskService, blockedBy []string) ([]string, error) {
+		if slices.Contains(blockedBy, blocker) {
+			return blockedBy, nil
+		}
+
+		if len(blockedBy) >= models.MaxBlockers {
+			verr := &models.ValidationError{}
+			verr.Add("blockedBy", fmt.Sprintf("a task can be blocked by at most %d tasks", models.MaxBlockers))
+			return nil, verr
+		}
+
+		if err := tx.checkBlockers(ctx, id, []string{blocker}); err != nil {
+			return nil, err
+		}
+
+		return append(blockedBy, blocker), nil
+	})
+}
+
+// RemoveBlocker stops task id from being blocked by task blocker if pre
+// holds.
+func (ts *TaskService) RemoveBlocker(ctx context.Context, id, blocker string, pre Precondition) (*models.Task, error) {
+	return ts.editBlockers(ctx, id, pre, func(tx *TaskService, blockedBy []string) ([]string, error) {
+		i := slices.Index(blockedBy, blocker)
+		if i < 0 {
+			return nil, models.ErrBlockerNotFound
+		}
+
+		return slices.Delete(blockedBy, i, i+1), nil
+	})
+}
+
+// editBlockers is editChecklist for the blockers of task id. An edit that
+// changes nothing leaves the task and its version as they are. The edit
+// runs inside atomically, so a cycle it checks for cannot be closed by
+// another write meanwhile.
+func (ts *TaskService) editBlockers(ctx context.Context, id string, pre Precondition, edit func(tx *TaskService, blockedBy []string) ([]string, error)) (*models.Task, error) {
+	var task *models.Task
+	err := ts.atomically(ctx, func(tx *TaskService) error {
+		current, err := tx.repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := pre.check(current); err != nil {
+			return err
+		}
+
+		blockedBy, err := edit(tx, slices.Clone(current.BlockedBy))
+		if err != nil {
+			return err
+		}
+
+		task = current
+		if slices.Equal(blockedBy, current.BlockedBy) {
+			return nil
+		}
+
+		edited := *current
+		edited.BlockedBy = blockedBy
+		task = &edited
+
+		return tx.repo.Put(ctx, id, task, current.Version)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// UnfinishedBlockers returns the blockers of task that are not closed yet
+// if task is done, which only happens with BlockersWarn.
+func (ts *TaskService) UnfinishedBlockers(ctx context.Context, task *models.Task) ([]*models.Task, error) {
+	if task.Status != ts.workflow.Done() {
+		return nil, nil
+	}
+
+	return ts.unfinished(ctx, task.BlockedBy)
+}
+
+// unfinished returns the tasks among ids that exist and are not closed.
+func (ts *TaskService) unfinished(ctx context.Context, ids []string) ([]*models.Task, error) {
+	var tasks []*models.Task
+	for _, id := range ids {
+		task, err := ts.repo.GetByID(ctx, id)
+		if errors.Is(err, models.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !ts.workflow.Closed(task.Status) {
+			tasks = append(tasks, task)
+		}
+	}
+
+	return tasks, nil
+}
+
+// checkDone reports models.ErrBlocked if task moves from current to done
+// while some of its blockers are unfinished, unless BlockersWarn is set.
+func (ts *TaskService) checkDone(ctx context.Context, current, task *models.Task) error {
+	if ts.blockers == BlockersWarn || task.Status != ts.workflow.Done() || current.Status == task.Status {
+		return nil
+	}
+
+	unfinished, err := ts.unfinished(ctx, current.BlockedBy)
+	if err != nil || len(unfinished) == 0 {
+		return err
+	}
+
+	ids := make([]string, len(unfinished))
+	for i, blocker := range unfinished {
+		ids[i] = blocker.ID
+	}
+
+	return fmt.Errorf("%w: %s", models.ErrBlocked, strings.Join(ids, ", "))
+}
+
+// checkBlockers checks that the task stored as id can be blocked by
+// blockers: they exist, and none of them is the task itself or blocked by
+// it, directly or through other tasks.
+func (ts *TaskService) checkBlockers(ctx context.Context, id string, blockers []string) error {
+	verr := &models.ValidationError{}
+
+	for _, blocker := range blockers {
+		if blocker == id {
+			verr.Add("blockedBy", "a task cannot block itself")
+			continue
+		}
+
+		if _, err := ts.repo.GetByID(ctx, blocker); errors.Is(err, models.ErrNotFound) {
+			verr.Add("blockedBy", fmt.Sprintf("blocking task %q not found", blocker))
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		cycle, err := ts.dependsOn(ctx, blocker, id)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			verr.Add("blockedBy", fmt.Sprintf("task %q is already blocked by this task, directly or through other tasks", blocker))
+		}
+	}
+
+	return verr.Err()
+}
+
+// dependsOn reports whether task id is blocked by task on, directly or
+// through other tasks.
+func (ts *TaskService) dependsOn(ctx context.Context, id, on string) (bool, error) {
+	seen := map[string]bool{id: true}
+	for pending := []string{id}; len(pending) > 0; {
+		task, err := ts.repo.GetByID(ctx, pending[len(pending)-1])
+		pending = pending[:len(pending)-1]
+		if errors.Is(err, models.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+
+		for _, blocker := range task.BlockedBy {
+			if blocker == on {
+				return true, nil
+			}
+			if !seen[blocker] {
+				seen[blocker] = true
+				pending = append(pending, blocker)
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// normalizeBlockers drops empty and repeated IDs.
+func normalizeBlockers(ids []string) []string {
+	var blockers []string
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" && !slices.Contains(blockers, id) {
+			blockers = append(blockers, id)
+		}
+	}
+
+	return blockers
+}
+
+// TaskOrder returns the open tasks of project, or of every project if it
+// is empty, in an order to work on them: every task comes after the open
+// tasks it is blocked by. Among the tasks that could come next, the most
+// urgent, then the soonest due, then the earliest active goes first.
+// Blockers outside the listed tasks are not waited for.
+func (ts *TaskService) TaskOrder(ctx context.Context, project string) ([]*models.Task, error) {
+	if project != "" {
+		if _, err := ts.repo.GetProject(ctx, project); err != nil {
+			return nil, err
+		}
+	}
+
+	page, err := ts.repo.List(ctx, repositories.TaskQuery{ProjectID: project, Statuses: ts.workflow.Open(), Sort: orderSort})
+	if err != nil {
+		return nil, err
+	}
+	tasks := page.Tasks
+
+	index := make(map[string]int, len(tasks))
+	for i, task := range tasks {
+		index[task.ID] = i
+	}
+
+	// waiting counts the listed blockers of each task not placed yet, and
+	// unblocks lists the tasks each one blocks.
+	waiting := make([]int, len(tasks))
+	unblocks := make([][]int, len(tasks))
+	for i, task := range tasks {
+		for _, blocker := range task.BlockedBy {
+			if j, ok := index[blocker]; ok {
+				waiting[i]++
+				unblocks[j] = append(unblocks[j], i)
+			}
+		}
+	}
+
+	// ready holds, in listing order, the tasks whose blockers are placed.
+	var ready []int
+	for i := range tasks {
+		if waiting[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	order := make([]*models.Task, 0, len(tasks))
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		order = append(order, tasks[i])
+
+		for _, j := range unblocks[i] {
+			if waiting[j]--; waiting[j] == 0 {
+				k, _ := slices.BinarySearch(ready, j)
+				ready = slices.Insert(ready, k, j)
+			}
+		}
+	}
+
+	if len(order) < len(tasks) {
+		return nil, &models.BlockerCycleError{IDs: cycle(tasks, waiting, unblocks)}
+	}
+
+	return order, nil
+}
+
+// cycle returns, sorted, the IDs of the tasks TaskOrder could not place
+// that are on a cycle of blockers or between two: the unplaced tasks left
+// once those that block no other unplaced task are dropped, repeatedly.
+func cycle(tasks []*models.Task, waiting []int, unblocks [][]int) []string {
+	left := make(map[int]bool)
+	for i := range tasks {
+		if waiting[i] > 0 {
+			left[i] = true
+		}
+	}
+
+	for dropped := true; dropped; {
+		dropped = false
+		for i := range left {
+			if !slices.ContainsFunc(unblocks[i], func(j int) bool { return left[j] }) {
+				delete(left, i)
+				dropped = true
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(left))
+	for i := range left {
+		ids = append(ids, tasks[i].ID)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+
+	"github.com/canyouhearthemusic/todo-list/internal/calendar"
+)
+
+type HolidayService struct {
+	calendar *calendar.Calendar
+}
+
+func NewHolidayService(c *calendar.Calendar) *HolidayService {
+	return &HolidayService{
+		calendar: c,
+	}
+}
+
+// GetHolidays lists holidays observed in region (the default region if
+// empty) during year, or all stored holidays when year is zero.
+func (hs *HolidayService) GetHolidays(ctx context.Context, region string, year int) []*calendar.PublicHoliday {
+	if region == "" {
+		region = hs.calendar.DefaultRegion()
+	}
+
+	return hs.calendar.Holidays(region, year)
+}
+
+func (hs *HolidayService) PostHoliday(ctx context.Context, holiday *calendar.PublicHoliday) error {
+	if err := holiday.Validate(); err != nil {
+		return err
+	}
+
+	return hs.calendar.AddCustom(holiday)
+}
+
+func (hs *HolidayService) DeleteHoliday(ctx context.Context, id string) error {
+	return hs.calendar.DeleteCustom(id)
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+)
+
+// Precondition carries the entity tags of a conditional request. A nil list
+// means the corresponding header was absent.
+type Precondition struct {
+	// IfMatch lists tags, or "*", one of which the task must have. Tags are
+	// compared strongly, so weak tags never match.
+	IfMatch []string
+	// IfNoneMatch lists tags, or "*", none of which the task may have. Tags
+	// are compared weakly.
+	IfNoneMatch []string
+}
+
+func (p Precondition) empty() bool {
+	return p.IfMatch == nil && p.IfNoneMatch == nil
+}
+
+// versioned is a task or a project.
+type versioned interface {
+	ETag() string
+}
+
+// check reports models.ErrPreconditionFailed unless p holds for entity.
+func (p Precondition) check(entity versioned) error {
+	etag := entity.ETag()
+
+	if p.IfMatch != nil && !containsTag(p.IfMatch, etag, false) {
+		return fmt.Errorf("%w: If-Match does not match the current ETag %s", models.ErrPreconditionFailed, etag)
+	}
+
+	if p.IfNoneMatch != nil && containsTag(p.IfNoneMatch, etag, true) {
+		return fmt.Errorf("%w: If-None-Match matches the current ETag %s", models.ErrPreconditionFailed, etag)
+	}
+
+	return nil
+}
+
+// NotModified reports whether a read conditioned on p may answer 304 Not
+// Modified for entity.
+func (p Precondition) NotModified(entity versioned) bool {
+	return p.IfNoneMatch != nil && containsTag(p.IfNoneMatch, entity.ETag(), true)
+}
+
+func containsTag(tags []string, etag string, weak bool) bool {
+	for _, tag := range tags {
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expectedVersion evaluates p against the stored task and returns the
+// version a conditional write must find, so that a change racing with the
+// check is caught by the repository. It returns 0, an unconditional write,
+// if p is empty.
+func (ts *TaskService) expectedVersion(ctx context.Context, id string, p Precondition) (int64, error) {
+	if p.empty() {
+		return 0, nil
+	}
+
+	task, err := ts.repo.GetByID(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := p.check(task); err != nil {
+		return 0, err
+	}
+
+	return task.Version, nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/canyouhearthemusic/todo-list/internal/repositories"
+)
+
+type ProjectService struct {
+	repo repositories.ProjectRepo
+}
+
+func NewProjectService(repo repositories.ProjectRepo) *ProjectService {
+	return &ProjectService{
+		repo: repo,
+	}
+}
+
+// GetProjects lists every project, oldest first.
+func (ps *ProjectService) GetProjects(ctx context.Context) ([]*models.Project, error) {
+	projects, err := ps.repo.Projects(ctx)
+	if projects == nil && err == nil {
+		projects = []*models.Project{}
+	}
+
+	return projects, err
+}
+
+func (ps *ProjectService) GetProject(ctx context.Context, id string) (*models.Project, error) {
+	return ps.repo.GetProject(ctx, id)
+}
+
+// PostProject stores a new project, which must already be valid and have
+// an ID.
+func (ps *ProjectService) PostProject(ctx context.Context, project *models.Project) error {
+	project.Name = strings.TrimSpace(project.Name)
+
+	return ps.repo.PostProject(ctx, project)
+}
+
+// PutProject renames and redescribes project id if pre holds.
+func (ps *ProjectService) PutProject(ctx context.Context, id string, project *models.Project, pre Precondition) error {
+	version, err := ps.expectedVersion(ctx, id, pre)
+	if err != nil {
+		return err
+	}
+
+	project.Name = strings.TrimSpace(project.Name)
+
+	return ps.repo.PutProject(ctx, id, project, version)
+}
+
+// DeleteProject deletes project id if pre holds. Only empty projects other
+// than the default one can be deleted; move or delete their tasks first.
+func (ps *ProjectService) DeleteProject(ctx context.Context, id string, pre Precondition) error {
+	version, err := ps.expectedVersion(ctx, id, pre)
+	if err != nil {
+		return err
+	}
+
+	return ps.repo.DeleteProject(ctx, id, version)
+}
+
+// expectedVersion is TaskService.expectedVersion for projects.
+func (ps *ProjectService) expectedVersion(ctx context.Context, id string, p Precondition) (int64, error) {
+	if p.empty() {
+		return 0, nil
+	}
+
+	project, err := ps.repo.GetProject(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := p.check(project); err != nil {
+		return 0, err
+	}
+
+	return project.Version, nil
+}
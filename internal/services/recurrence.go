@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/calendar"
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/canyouhearthemusic/todo-list/internal/rrule"
+	"github.com/google/uuid"
+)
+
+// Where the next occurrence of a recurring task goes if its rule puts it on
+// a weekend or holiday, as classified for listings.
+const (
+	// NonWorkingKeep leaves it there.
+	NonWorkingKeep = "keep"
+	// NonWorkingNext moves it to the next workday.
+	NonWorkingNext = "next"
+	// NonWorkingPrevious moves it to the previous workday, unless that is
+	// not after the occurrence just completed.
+	NonWorkingPrevious = "previous"
+	// NonWorkingSkip drops it in favor of the next one the rule gives on a
+	// workday.
+	NonWorkingSkip = "skip"
+)
+
+const (
+	// maxShift bounds how many days an occurrence is moved looking for a
+	// workday; past it, the occurrence stays where the rule put it.
+	maxShift = 31
+	// maxSkipped bounds how many occurrences NonWorkingSkip drops in a row
+	// looking for one on a workday; a rule that gives none within it is
+	// refused.
+	maxSkipped = 1000
+)
+
+// startRecurrence canonicalizes the rule of task, if it repeats, and fills
+// in where in its series the task is: a rule current repeats by already
+// carries on that series, any other starts one at the activeAt of task.
+// With NonWorkingSkip, a new rule must give an occurrence on a workday
+// after the first, unless its series ends first.
+func (ts *TaskService) startRecurrence(current *models.Recurrence, task *models.Task) error {
+	if task.Recurrence == nil {
+		return nil
+	}
+
+	rule, err := rrule.Parse(task.Recurrence.Rule)
+	if err != nil {
+		verr := &models.ValidationError{}
+		verr.Add("recurrence", err.Error())
+		return verr
+	}
+
+	if current != nil && current.Rule == rule.String() {
+		recurrence := *current
+		task.Recurrence = &recurrence
+		return nil
+	}
+
+	task.Recurrence = &models.Recurrence{
+		Rule:       rule.String(),
+		Start:      task.ActiveAt,
+		Occurrence: 1,
+		Scheduled:  task.ActiveAt,
+	}
+
+	if ts.nonWorkingDays == NonWorkingSkip {
+		if _, err := ts.nextOccurrence(task); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// completesOccurrence reports whether storing task in place of current
+// marks an occurrence of a recurring task done.
+func (ts *TaskService) completesOccurrence(current, task *models.Task) bool {
+	return task.Recurrence != nil && task.Status == ts.workflow.Done() && current.Status != task.Status
+}
+
+// completeOccurrence stores task, an occurrence being marked done, in place
+// of current along with the next occurrence, if the series goes on. The
+// done occurrence gets its activeAt appended to its title, which the next
+// one takes over, and stops repeating.
+func (ts *TaskService) completeOccurrence(ctx context.Context, current, task *models.Task) error {
+	next, err := ts.nextOccurrence(task)
+	if err != nil {
+		return err
+	}
+	if next == nil {
+		return ts.repo.Put(ctx, current.ID, task, current.Version)
+	}
+
+	// Titles of recurring tasks are validated to leave room for the date,
+	// but one stored before that was may not.
+	title := models.OccurrenceTitle(task.Title, task.ActiveAt)
+	if len(title) > models.MaxTitleLength {
+		verr := &models.ValidationError{}
+		verr.Add("title", fmt.Sprintf("title %q of a done occurrence exceeds %d characters", title, models.MaxTitleLength))
+		return verr
+	}
+
+	task.Title = title
+	task.Recurrence = nil
+
+	return ts.atomically(ctx, func(tx *TaskService) error {
+		if err := tx.repo.Put(ctx, current.ID, task, current.Version); err != nil {
+			return err
+		}
+
+		return tx.repo.Post(ctx, next)
+	})
+}
+
+// nextOccurrence returns the occurrence of a recurring task that follows
+// task, or nil if its series has ended, looking for it from where task is
+// in the series rather than from its start. It is active on the date the rule
+// gives it, moved off weekends and holidays as configured, and due as long
+// after that as task is. Its checklist starts over; subtasks and blockers
+// are not carried over.
+func (ts *TaskService) nextOccurrence(task *models.Task) (*models.Task, error) {
+	recurrence := task.Recurrence
+
+	rule, err := rrule.Parse(recurrence.Rule)
+	if err != nil {
+		return nil, fmt.Errorf("task %s: %w", task.ID, err)
+	}
+
+	start, err := time.Parse(models.DateLayout, recurrence.Start)
+	if err != nil {
+		return nil, fmt.Errorf("task %s: recurrence start: %w", task.ID, err)
+	}
+
+	activeAt, err := time.Parse(models.DateLayout, task.ActiveAt)
+	if err != nil {
+		return nil, fmt.Errorf("task %s: activeAt: %w", task.ID, err)
+	}
+
+	// An occurrence moved or postponed past its scheduled date is followed
+	// by the first one after where it ended up.
+	after := activeAt
+	if scheduled, err := time.Parse(models.DateLayout, recurrence.Scheduled); err == nil && scheduled.After(after) {
+		after = scheduled
+	}
+
+	// A recurrence stored before Scheduled and Occurrence were has neither,
+	// and NextFrom starts over from start.
+	from, _ := time.Parse(models.DateLayout, recurrence.Scheduled)
+
+	date, n, ok := rule.NextFrom(start, from, recurrence.Occurrence, after)
+	for skipped := 0; ok; skipped++ {
+		active, kept := ts.occurrenceDate(date, activeAt)
+		if !kept {
+			if skipped == maxSkipped {
+				verr := &models.ValidationError{}
+				verr.Add("recurrence", fmt.Sprintf("rule gives no occurrence on a workday within %d occurrences to skip to", maxSkipped))
+				return nil, verr
+			}
+			date, n, ok = rule.NextFrom(start, date, n, date)
+			continue
+		}
+
+		days := int(active.Sub(activeAt).Hours() / 24)
+
+		checklist := slices.Clone(task.Checklist)
+		for i := range checklist {
+			checklist[i].Done = false
+		}
+
+		return &models.Task{
+			ID:          uuid.New().String(),
+			Title:       task.Title,
+			ActiveAt:    active.Format(models.DateLayout),
+			Status:      ts.workflow.Initial(),
+			ProjectID:   task.ProjectID,
+			ParentID:    task.ParentID,
+			Description: task.Description,
+			Tags:        slices.Clone(task.Tags),
+			Checklist:   newChecklist(checklist),
+			Priority:    task.Priority,
+			DueAt:       shiftDue(task.DueAt, days),
+			Recurrence: &models.Recurrence{
+				Rule:       recurrence.Rule,
+				Start:      recurrence.Start,
+				Occurrence: n,
+				Scheduled:  date.Format(models.DateLayout),
+			},
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// occurrenceDate returns the date an occurrence the rule puts on date is
+// active at, given that the one before it was active at done, or false if
+// NonWorkingSkip drops it.
+func (ts *TaskService) occurrenceDate(date, done time.Time) (time.Time, bool) {
+	if ts.calendar.Classify(date, "").Type == calendar.Workday {
+		return date, true
+	}
+
+	var step int
+	switch ts.nonWorkingDays {
+	case NonWorkingNext:
+		step = 1
+	case NonWorkingPrevious:
+		step = -1
+	case NonWorkingSkip:
+		return date, false
+	default:
+		return date, true
+	}
+
+	shifted := date
+	for i := 0; i < maxShift; i++ {
+		shifted = shifted.AddDate(0, 0, step)
+		if !shifted.After(done) {
+			break
+		}
+		if ts.calendar.Classify(shifted, "").Type == calendar.Workday {
+			return shifted, true
+		}
+	}
+
+	return date, true
+}
+
+// shiftDue moves the RFC 3339 timestamp due by days, keeping its offset.
+func shiftDue(due string, days int) string {
+	t, err := time.Parse(time.RFC3339, due)
+	if err != nil {
+		return due
+	}
+
+	return t.AddDate(0, 0, days).Format(time.RFC3339)
+}
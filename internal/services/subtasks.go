@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/canyouhearthemusic/todo-list/internal/repositories"
+)
+
+// checkParent checks that the task stored as id can be a subtask of parent:
+// the parent exists, is neither the task nor one of its subtasks, and the
+// task with its subtasks stays within the nesting limit under it. An empty
+// parent makes a top-level task.
+func (ts *TaskService) checkParent(ctx context.Context, id, parent string) error {
+	if parent == "" {
+		return nil
+	}
+
+	height, err := ts.height(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	verr := &models.ValidationError{}
+
+	// depth counts the ancestors the task gets under parent.
+	depth := 0
+	for ancestor := parent; ancestor != ""; {
+		if ancestor == id {
+			verr.Add("parentId", "a task cannot be a subtask of itself or of one of its subtasks")
+			return verr
+		}
+
+		if depth++; depth+height > ts.maxDepth {
+			verr.Add("parentId", fmt.Sprintf("tasks nest at most %d levels deep", ts.maxDepth))
+			return verr
+		}
+
+		task, err := ts.repo.GetByID(ctx, ancestor)
+		if errors.Is(err, models.ErrNotFound) {
+			verr.Add("parentId", fmt.Sprintf("parent task %q not found", ancestor))
+			return verr
+		}
+		if err != nil {
+			return err
+		}
+		ancestor = task.ParentID
+	}
+
+	return nil
+}
+
+// height counts the levels of the subtree of task id, 1 for a task without
+// subtasks. It stops counting past the nesting limit.
+func (ts *TaskService) height(ctx context.Context, id string) (int, error) {
+	height := 1
+	for level := []string{id}; height <= ts.maxDepth; height++ {
+		page, err := ts.repo.List(ctx, repositories.TaskQuery{ParentIDs: level})
+		if err != nil {
+			return 0, err
+		}
+		if len(page.Tasks) == 0 {
+			break
+		}
+
+		level = level[:0]
+		for _, task := range page.Tasks {
+			level = append(level, task.ID)
+		}
+	}
+
+	return height, nil
+}
+
+// subtasksOf returns the subtasks of tasks in every status, by parent ID.
+func (ts *TaskService) subtasksOf(ctx context.Context, tasks []*models.Task) (map[string][]*models.Task, error) {
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+
+	page, err := ts.repo.List(ctx, repositories.TaskQuery{ParentIDs: ids})
+	if err != nil {
+		return nil, err
+	}
+
+	subtasks := make(map[string][]*models.Task)
+	for _, task := range page.Tasks {
+		subtasks[task.ParentID] = append(subtasks[task.ParentID], task)
+	}
+
+	return subtasks, nil
+}
+
+// progress sums up the subtasks and checklist of task, nil if it has
+// neither.
+func (ts *TaskService) progress(task *models.Task, subtasks []*models.Task) *models.Progress {
+	if len(subtasks) == 0 && len(task.Checklist) == 0 {
+		return nil
+	}
+
+	p := &models.Progress{ChecklistTotal: len(task.Checklist)}
+	for _, item := range task.Checklist {
+		if item.Done {
+			p.ChecklistDone++
+		}
+	}
+
+	for _, subtask := range subtasks {
+		switch {
+		case subtask.Status == ts.workflow.Done():
+			p.SubtasksDone++
+			p.SubtasksTotal++
+		case !ts.workflow.Closed(subtask.Status):
+			p.SubtasksTotal++
+		}
+	}
+
+	if total := p.SubtasksTotal + p.ChecklistTotal; total > 0 {
+		p.Percent = (p.SubtasksDone + p.ChecklistDone) * 100 / total
+	}
+
+	return p
+}
+
+// completeParent marks task id done once all of its subtasks are closed,
+// which in turn may complete its own parent. A parent that is closed
+// already, that the workflow does not let reach done from its status, or
+// that is blocked is left as it is. It runs inside the transaction of the
+// write that closed the last subtask.
+func (ts *TaskService) completeParent(ctx context.Context, id string) error {
+	parent, err := ts.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if ts.workflow.Closed(parent.Status) || !ts.workflow.Allows(parent.Status, ts.workflow.Done()) {
+		return nil
+	}
+
+	subtasks, err := ts.subtasksOf(ctx, []*models.Task{parent})
+	if err != nil {
+		return err
+	}
+
+	for _, subtask := range subtasks[parent.ID] {
+		if !ts.workflow.Closed(subtask.Status) {
+			return nil
+		}
+	}
+
+	_, err = ts.DoneTask(ctx, id, Precondition{})
+	if errors.Is(err, models.ErrConflict) {
+		return nil
+	}
+
+	return err
+}
@@ -2,67 +2,1082 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/canyouhearthemusic/todo-list/internal/calendar"
+	"github.com/canyouhearthemusic/todo-list/internal/filter"
+	"github.com/canyouhearthemusic/todo-list/internal/i18n"
+	"github.com/canyouhearthemusic/todo-list/internal/markdown"
 	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/canyouhearthemusic/todo-list/internal/patch"
 	"github.com/canyouhearthemusic/todo-list/internal/repositories"
+	"github.com/canyouhearthemusic/todo-list/internal/search"
+	"github.com/canyouhearthemusic/todo-list/internal/workflow"
+	"github.com/google/uuid"
 )
 
 type TaskService struct {
 	repo repositories.TaskRepo
+	// searcher is repo if it supports full-text search, otherwise nil.
+	searcher repositories.TaskSearcher
+	calendar *calendar.Calendar
+	messages *i18n.Bundle
+	workflow *workflow.Workflow
+	now      func() time.Time
+	// maxDepth limits how deep subtasks nest.
+	maxDepth int
+	// completeParents marks a parent done along with its last subtask.
+	completeParents bool
+	// blockers is BlockersBlock or BlockersWarn.
+	blockers string
+	// nonWorkingDays is NonWorkingKeep, NonWorkingNext, NonWorkingPrevious
+	// or NonWorkingSkip.
+	nonWorkingDays string
 }
 
-func New(repo repositories.TaskRepo) *TaskService {
-	return &TaskService{
-		repo: repo,
+type Option func(*TaskService)
+
+// WithCalendar sets the calendar used to classify activeAt dates.
+// Defaults to a Saturday/Sunday weekend without holidays.
+func WithCalendar(c *calendar.Calendar) Option {
+	return func(ts *TaskService) {
+		ts.calendar = c
 	}
 }
 
-func (ts *TaskService) GetAllTasks(ctx context.Context, status string) ([]*models.Task, error) {
-	tasks, err := ts.repo.All(ctx)
+// WithMessages sets the catalogs used for display labels.
+func WithMessages(b *i18n.Bundle) Option {
+	return func(ts *TaskService) {
+		ts.messages = b
+	}
+}
+
+// WithWorkflow sets the statuses tasks move through. Defaults to
+// workflow.Default.
+func WithWorkflow(w *workflow.Workflow) Option {
+	return func(ts *TaskService) {
+		ts.workflow = w
+	}
+}
+
+// WithClock sets the source of the current time, which decides what is
+// overdue. Defaults to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(ts *TaskService) {
+		ts.now = now
+	}
+}
+
+// WithMaxDepth sets how deep tasks nest, counting top-level tasks as depth
+// 1. Defaults to models.DefaultMaxTaskDepth.
+func WithMaxDepth(depth int) Option {
+	return func(ts *TaskService) {
+		ts.maxDepth = depth
+	}
+}
+
+// WithParentAutoComplete makes marking a subtask done, by any request, also
+// mark its parent done once all of its subtasks are closed, and so on up the
+// tree.
+func WithParentAutoComplete(enabled bool) Option {
+	return func(ts *TaskService) {
+		ts.completeParents = enabled
+	}
+}
+
+// WithBlockerPolicy sets what marking a task done does while tasks it is
+// blocked by are unfinished: BlockersBlock, the default, or BlockersWarn.
+func WithBlockerPolicy(policy string) Option {
+	return func(ts *TaskService) {
+		ts.blockers = policy
+	}
+}
+
+// WithNonWorkingDays sets where the next occurrence of a recurring task
+// goes if its rule puts it on a weekend or holiday: NonWorkingNext, the
+// default, NonWorkingPrevious, NonWorkingSkip or NonWorkingKeep.
+func WithNonWorkingDays(policy string) Option {
+	return func(ts *TaskService) {
+		ts.nonWorkingDays = policy
+	}
+}
+
+func New(repo repositories.TaskRepo, opts ...Option) *TaskService {
+	ts := &TaskService{
+		repo:           repo,
+		calendar:       calendar.New(),
+		workflow:       workflow.Default(),
+		now:            time.Now,
+		maxDepth:       models.DefaultMaxTaskDepth,
+		blockers:       BlockersBlock,
+		nonWorkingDays: NonWorkingNext,
+	}
+
+	for _, opt := range opts {
+		opt(ts)
+	}
+
+	ts.searcher, _ = repo.(repositories.TaskSearcher)
+
+	if ts.messages == nil {
+		// The catalogs are embedded, so failing to load them is a bug in
+		// the build rather than something to recover from.
+		messages, err := i18n.Load("ru")
+		if err != nil {
+			panic(fmt.Sprintf("load embedded message catalogs: %v", err))
+		}
+		ts.messages = messages
+	}
+
+	return ts
+}
+
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+	// DefaultSort orders listings when ListOptions.Sort is empty.
+	DefaultSort = "activeAt"
+)
+
+type ListOptions struct {
+	// Project keeps only the tasks of this project; empty lists tasks of
+	// every project.
+	Project string
+	// Parent keeps only the subtasks of this task.
+	Parent string
+	// Status is a comma-separated list of workflow statuses. It defaults to
+	// the open ones unless Filter is set, in which case tasks of any status
+	// are listed.
+	Status string
+	// Filter is an expression in the language of package filter, e.g.
+	// activeAt>=2024-05-01 AND title~"report".
+	Filter string
+	// Overdue keeps only tasks past their due time that are not closed.
+	Overdue bool
+	// TagsAny, TagsAll and TagsNone are comma-separated tags: tasks must
+	// have at least one of TagsAny, all of TagsAll and none of TagsNone.
+	TagsAny  string
+	TagsAll  string
+	TagsNone string
+	// Region selects the holiday calendar; empty means the default region.
+	Region string
+	// Decorate prefixes the display title of tasks falling on a weekend or
+	// holiday with a label in the language stored in the context.
+	Decorate bool
+	// Render, if RenderHTML, adds the description rendered as sanitized
+	// HTML to every task.
+	Render string
+	// Sort is a comma-separated list of fields, each optionally prefixed
+	// with "-" for descending order; DefaultSort if empty. Ties are broken
+	// by ID.
+	Sort string
+
+	// Limit is the page size, DefaultPageSize if zero.
+	Limit  int
+	Offset int
+	// Cursor continues a previous listing from its NextCursor.
+	Cursor string
+}
+
+type TaskList struct {
+	Tasks []*models.TaskView
+	// Total counts all matching tasks, not just this page.
+	Total int
+	// NextCursor points past the last task of this page; empty on the last page.
+	NextCursor string
+}
+
+// GetAllTasks lists one page of tasks matching opts. Presentation fields
+// are computed on views, so listing never changes the stored tasks.
+func (ts *TaskService) GetAllTasks(ctx context.Context, opts ListOptions) (*TaskList, error) {
+	q, err := ts.taskQuery(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	var filteredTasks []*models.Task
+	if q.ProjectID != "" {
+		if _, err := ts.repo.GetProject(ctx, q.ProjectID); err != nil {
+			return nil, err
+		}
+	}
 
-	for _, task := range tasks {
-		if task.Status == status {
-			filteredTasks = append(filteredTasks, task)
+	// Fetch one extra task to learn whether another page follows.
+	q.Limit++
+	page, err := ts.repo.List(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	q.Limit--
+
+	tasks := page.Tasks
+	list := &TaskList{Total: page.Total}
+	if len(tasks) > q.Limit {
+		tasks = tasks[:q.Limit]
+		list.NextCursor = repositories.CursorAfter(tasks[len(tasks)-1], q.Sort).Encode()
+	}
+
+	subtasks, err := ts.subtasksOf(ctx, tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	lang := i18n.LanguageFrom(ctx)
+	now := ts.now()
+
+	list.Tasks = make([]*models.TaskView, len(tasks))
+	for i, task := range tasks {
+		list.Tasks[i] = ts.newTaskView(task, opts, lang, now)
+		list.Tasks[i].Progress = ts.progress(task, subtasks[task.ID])
+	}
+
+	return list, nil
+}
+
+func (ts *TaskService) taskQuery(opts ListOptions) (repositories.TaskQuery, error) {
+	q := repositories.TaskQuery{
+		ProjectID: opts.Project,
+		Offset:    opts.Offset,
+		Limit:     opts.Limit,
+	}
+
+	if opts.Parent != "" {
+		q.ParentIDs = []string{opts.Parent}
+	}
+
+	verr := &models.ValidationError{}
+
+	switch {
+	case opts.Status != "":
+		for _, status := range strings.Split(opts.Status, ",") {
+			status = strings.TrimSpace(status)
+			if !ts.workflow.Valid(status) {
+				verr.Add("status", ts.unknownStatus(status))
+				continue
+			}
+			q.Statuses = append(q.Statuses, status)
 		}
+	case opts.Filter == "":
+		q.Statuses = ts.workflow.Open()
 	}
 
-	sort.Slice(filteredTasks, func(i, j int) bool {
-		return filteredTasks[i].ID < filteredTasks[j].ID
-	})
+	// Closed tasks are never overdue.
+	if opts.Overdue {
+		now := ts.now()
+		q.DueBefore = &now
+
+		if opts.Status == "" {
+			q.Statuses = ts.workflow.Open()
+		} else if q.Statuses = slices.DeleteFunc(q.Statuses, ts.workflow.Closed); len(q.Statuses) == 0 {
+			verr.Add("overdue", "only tasks in open statuses can be overdue")
+		}
+	}
+
+	q.AnyTags = parseTags(verr, "tagsAny", opts.TagsAny)
+	q.AllTags = parseTags(verr, "tagsAll", opts.TagsAll)
+	q.NoTags = parseTags(verr, "tagsNone", opts.TagsNone)
+
+	expr, err := filter.Parse(opts.Filter, repositories.TaskFilterSchema)
+	if err != nil {
+		verr.Add("filter", err.Error())
+	}
+	q.Filter = expr
+
+	if opts.Render != "" && opts.Render != RenderHTML {
+		verr.Add("render", unknownRender(opts.Render))
+	}
+
+	if opts.Sort == "" {
+		opts.Sort = DefaultSort
+	}
+	order, err := repositories.ParseSort(opts.Sort)
+	if err != nil {
+		verr.Add("sort", err.Error())
+	}
+	q.Sort = order
+
+	if q.Limit == 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit < 0 || q.Limit > MaxPageSize {
+		verr.Add("limit", fmt.Sprintf("limit must be between 1 and %d", MaxPageSize))
+	}
+
+	if q.Offset < 0 {
+		verr.Add("offset", "offset mustn't be negative")
+	}
+
+	if opts.Cursor != "" {
+		cursor, err := repositories.DecodeCursor(opts.Cursor)
+		switch {
+		case err != nil:
+			verr.Add("cursor", err.Error())
+		case !cursor.Matches(q.Sort):
+			verr.Add("cursor", "cursor was issued for a different sort")
+		}
+		q.After = cursor
+	}
+
+	return q, verr.Err()
+}
+
+// parseTags splits a comma-separated list of tags and normalizes it,
+// reporting invalid tags as errors of field.
+func parseTags(verr *models.ValidationError, field, list string) []string {
+	if list == "" {
+		return nil
+	}
+
+	tags := strings.Split(list, ",")
+	for _, tag := range tags {
+		if err := models.ValidateTag(tag); err != nil {
+			verr.Add(field, err.Error())
+		}
+	}
+
+	return models.NormalizeTags(tags)
+}
+
+func (ts *TaskService) newTaskView(task *models.Task, opts ListOptions, lang string, now time.Time) *models.TaskView {
+	view := &models.TaskView{
+		Task:         *task,
+		DayType:      string(calendar.Workday),
+		DisplayTitle: task.Title,
+		Overdue:      ts.overdue(task, now),
+	}
+
+	if opts.Render == RenderHTML {
+		view.DescriptionHTML = markdown.HTML(task.Description)
+	}
+
+	activeDate, err := time.Parse(models.DateLayout, task.ActiveAt)
+	if err != nil {
+		return view
+	}
+
+	day := ts.calendar.Classify(activeDate, opts.Region)
+	view.IsWeekend = day.IsWeekend
+	view.DayType = string(day.Type)
+
+	var label string
+	switch day.Type {
+	case calendar.Holiday:
+		view.Holiday = day.Holiday.Name
+		label = i18n.LabelHoliday
+	case calendar.Weekend:
+		label = i18n.LabelWeekend
+	}
+
+	if opts.Decorate && label != "" {
+		view.DisplayTitle = ts.messages.T(lang, label) + " - " + task.Title
+	}
+
+	return view
+}
+
+// RenderHTML is the render mode producing descriptions as sanitized HTML.
+const RenderHTML = "html"
+
+func unknownRender(render string) string {
+	return fmt.Sprintf("unknown render mode %q, want %q", render, RenderHTML)
+}
+
+// TaskDetail returns task with its progress and, if render is RenderHTML,
+// its Markdown description rendered; render may also be empty.
+func (ts *TaskService) TaskDetail(ctx context.Context, task *models.Task, render string) (*models.TaskDetail, error) {
+	if render != "" && render != RenderHTML {
+		verr := &models.ValidationError{}
+		verr.Add("render", unknownRender(render))
+		return nil, verr
+	}
+
+	subtasks, err := ts.subtasksOf(ctx, []*models.Task{task})
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &models.TaskDetail{Task: *task, Progress: ts.progress(task, subtasks[task.ID])}
+	if render == RenderHTML {
+		detail.DescriptionHTML = markdown.HTML(task.Description)
+	}
+
+	return detail, nil
+}
+
+// overdue reports whether task is past its due time at now and still open.
+func (ts *TaskService) overdue(task *models.Task, now time.Time) bool {
+	if task.DueAt == "" || ts.workflow.Closed(task.Status) {
+		return false
+	}
+
+	due, err := time.Parse(time.RFC3339, task.DueAt)
+
+	return err == nil && due.Before(now)
+}
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+	// snippetWidth is the length of highlight snippets, in characters.
+	snippetWidth = 160
+)
+
+// ErrSearchUnavailable is a models.ErrNotImplemented: the configured
+// storage has no full-text index.
+var ErrSearchUnavailable = fmt.Errorf("%w: full-text search is not available for this storage", models.ErrNotImplemented)
+
+// SearchTasks finds tasks whose titles and descriptions together contain
+// every word of query, or words starting with them, most relevant first.
+func (ts *TaskService) SearchTasks(ctx context.Context, query string, limit int) ([]*models.TaskSearchResult, error) {
+	if ts.searcher == nil {
+		return nil, ErrSearchUnavailable
+	}
+
+	verr := &models.ValidationError{}
 
-	for _, task := range filteredTasks {
-		activeDate, _ := time.Parse("2006-01-02", task.ActiveAt)
-		if activeDate.Weekday() == time.Saturday || activeDate.Weekday() == time.Sunday {
-			task.Title = "ВЫХОДНОЙ - " + task.Title
+	q := search.ParseQuery(query)
+	if q.Empty() {
+		verr.Add("q", "query must contain at least one word")
+	}
+
+	if limit == 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit < 0 || limit > MaxSearchLimit {
+		verr.Add("limit", fmt.Sprintf("limit must be between 1 and %d", MaxSearchLimit))
+	}
+
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	hits, err := ts.searcher.Search(ctx, q, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*models.TaskSearchResult, len(hits))
+	for i, hit := range hits {
+		results[i] = &models.TaskSearchResult{
+			Task:       *hit.Task,
+			Score:      hit.Score,
+			Highlights: map[string]string{},
+		}
+
+		if snippet := q.Snippet(hit.Task.Title, snippetWidth); snippet != "" {
+			results[i].Highlights["title"] = snippet
+		}
+		if snippet := q.Snippet(hit.Task.Description, snippetWidth); snippet != "" {
+			results[i].Highlights["description"] = snippet
 		}
 	}
 
-	return filteredTasks, nil
+	return results, nil
 }
 
 func (ts *TaskService) GetTask(ctx context.Context, id string) (*models.Task, error) {
 	return ts.repo.GetByID(ctx, id)
 }
 
+// PostTask stores a new task in the initial status of the workflow, in the
+// default project unless it names another. Its checklist items get fresh
+// IDs, the tasks it is blocked by must exist, and if it repeats, it is the
+// first occurrence of its series.
 func (ts *TaskService) PostTask(ctx context.Context, task *models.Task) error {
-	return ts.repo.Post(ctx, task)
+	task.Status = ts.workflow.Initial()
+	task.Tags = models.NormalizeTags(task.Tags)
+	task.Checklist = newChecklist(task.Checklist)
+	task.BlockedBy = normalizeBlockers(task.BlockedBy)
+	if err := ts.startRecurrence(nil, task); err != nil {
+		return err
+	}
+
+	return ts.atomically(ctx, func(tx *TaskService) error {
+		if err := tx.checkParent(ctx, task.ID, task.ParentID); err != nil {
+			return err
+		}
+		if err := tx.checkBlockers(ctx, task.ID, task.BlockedBy); err != nil {
+			return err
+		}
+
+		return tx.repo.Post(ctx, task)
+	})
+}
+
+// PutTask replaces task id if pre holds. An empty status keeps the current
+// one; any other must be reachable from it. An empty project or parent
+// keeps the current one too.
+func (ts *TaskService) PutTask(ctx context.Context, id string, task *models.Task, pre Precondition) error {
+	return ts.atomically(ctx, func(tx *TaskService) error {
+		current, err := tx.repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := pre.check(current); err != nil {
+			return err
+		}
+
+		if task.ParentID == "" {
+			task.ParentID = current.ParentID
+		}
+
+		return tx.replace(ctx, current, task)
+	})
+}
+
+// TransitionTask moves task id to status if pre holds and the workflow
+// allows it, recording the actor of ctx as having made the change. Moving
+// a task to the status it has is a no-op.
+func (ts *TaskService) TransitionTask(ctx context.Context, id, status string, pre Precondition) (*models.Task, error) {
+	var task *models.Task
+	err := ts.atomically(ctx, func(tx *TaskService) error {
+		current, err := tx.repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := pre.check(current); err != nil {
+			return err
+		}
+
+		if !tx.workflow.Valid(status) {
+			verr := &models.ValidationError{}
+			verr.Add("status", tx.unknownStatus(status))
+			return verr
+		}
+
+		task = current
+		if current.Status == status {
+			return nil
+		}
+
+		changed := *current
+		changed.Status = status
+		task = &changed
+
+		return tx.replace(ctx, current, task)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// MoveTask moves task id to project if pre holds. Its title must not be
+// taken in that project yet.
+func (ts *TaskService) MoveTask(ctx context.Context, id, project string, pre Precondition) (*models.Task, error) {
+	if project == "" {
+		verr := &models.ValidationError{}
+		verr.Add("projectId", "projectId mustn't be empty")
+		return nil, verr
+	}
+
+	var task *models.Task
+	err := ts.atomically(ctx, func(tx *TaskService) error {
+		current, err := tx.repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := pre.check(current); err != nil {
+			return err
+		}
+
+		task = current
+		if current.ProjectID == project {
+			return nil
+		}
+
+		moved := *current
+		moved.ProjectID = project
+		task = &moved
+
+		return tx.replace(ctx, current, task)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// ReopenTask moves task id back to the initial status of the workflow.
+func (ts *TaskService) ReopenTask(ctx context.Context, id string, pre Precondition) (*models.Task, error) {
+	return ts.TransitionTask(ctx, id, ts.workflow.Initial(), pre)
+}
+
+// replace stores task in place of current, checking its status change
+// against the workflow and its blockers, and its parent, if changed,
+// against the nesting rules. The checklist and blockers are kept as they
+// are. Marking an occurrence of a recurring task done creates the next one
+// along with it, and marking a subtask done may complete its parent; see
+// WithParentAutoComplete. It must run inside atomically, with current read
+// there, so that what it checks still holds when it writes.
+func (ts *TaskService) replace(ctx context.Context, current, task *models.Task) error {
+	if err := ts.checkTransition(current.Status, task); err != nil {
+		return err
+	}
+	if err := ts.checkDone(ctx, current, task); err != nil {
+		return err
+	}
+
+	if task.ParentID != current.ParentID {
+		if err := ts.checkParent(ctx, current.ID, task.ParentID); err != nil {
+			return err
+		}
+	}
+
+	task.Checklist = current.Checklist
+	task.BlockedBy = current.BlockedBy
+	if err := ts.startRecurrence(current.Recurrence, task); err != nil {
+		return err
+	}
+
+	task.StatusChangedBy = ActorFrom(ctx)
+	task.Tags = models.NormalizeTags(task.Tags)
+	if task.ProjectID == "" {
+		task.ProjectID = current.ProjectID
+	}
+
+	var err error
+	if ts.completesOccurrence(current, task) {
+		err = ts.completeOccurrence(ctx, current, task)
+	} else {
+		err = ts.repo.Put(ctx, current.ID, task, current.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	if ts.completeParents && task.ParentID != "" && task.Status == ts.workflow.Done() && current.Status != task.Status {
+		return ts.completeParent(ctx, task.ParentID)
+	}
+
+	return nil
+}
+
+// atomically runs fn with a copy of ts that reads and writes through one
+// repository transaction: fn sees its own writes, no other write gets in
+// between, and if fn fails none of its writes are kept. Calls nest.
+func (ts *TaskService) atomically(ctx context.Context, fn func(tx *TaskService) error) error {
+	return ts.repo.Atomically(ctx, func(repo repositories.TaskRepo) error {
+		tx := *ts
+		tx.repo = repo
+
+		return fn(&tx)
+	})
+}
+
+// checkTransition defaults the status of task to from and checks that the
+// workflow allows moving from there to it.
+func (ts *TaskService) checkTransition(from string, task *models.Task) error {
+	if task.Status == "" {
+		task.Status = from
+	}
+
+	if !ts.workflow.Valid(task.Status) {
+		verr := &models.ValidationError{}
+		verr.Add("status", ts.unknownStatus(task.Status))
+		return verr
+	}
+
+	if !ts.workflow.Allows(from, task.Status) {
+		return fmt.Errorf("%w: cannot move a task from %q to %q", models.ErrTransitionNotAllowed, from, task.Status)
+	}
+
+	return nil
+}
+
+func (ts *TaskService) unknownStatus(status string) string {
+	return fmt.Sprintf("unknown status %q, must be one of %s", status, strings.Join(ts.workflow.Statuses(), ", "))
+}
+
+// MigrateStatuses moves stored tasks whose status the workflow does not
+// know to the status migrate maps it to, and returns how many it moved. If
+// some of those statuses are not mapped, it moves none and reports them
+// instead: such tasks could neither be listed by default nor change status.
+func (ts *TaskService) MigrateStatuses(ctx context.Context, migrate map[string]string) (int, error) {
+	tasks, err := ts.repo.All(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var stale []*models.Task
+	var unknown []string
+	for _, task := range tasks {
+		if ts.workflow.Valid(task.Status) {
+			continue
+		}
+		if to, ok := migrate[task.Status]; !ok || !ts.workflow.Valid(to) {
+			if !slices.Contains(unknown, task.Status) {
+				unknown = append(unknown, task.Status)
+			}
+			continue
+		}
+		stale = append(stale, task)
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return 0, fmt.Errorf("tasks have statuses the workflow does not know: %s; map them to statuses of the workflow under workflow.migrate",
+			strings.Join(unknown, ", "))
+	}
+
+	for i, task := range stale {
+		task.Status = migrate[task.Status]
+		task.StatusChangedBy = ActorFrom(ctx)
+		if err := ts.repo.Put(ctx, task.ID, task, task.Version); err != nil {
+			return i, fmt.Errorf("migrate task %s: %w", task.ID, err)
+		}
+	}
+
+	return len(stale), nil
+}
+
+type PatchFormat int
+
+const (
+	// MergePatch is a JSON Merge Patch (RFC 7396).
+	MergePatch PatchFormat = iota
+	// JSONPatch is a JSON Patch (RFC 6902).
+	JSONPatch
+)
+
+// PatchTask applies p to the title, activeAt, projectId, parentId, status,
+// description, tags, priority, dueAt and recurrence of task id if pre
+// holds, validates the result and stores it.
+// The write is conditional on the version the patch was applied to, so a
+// concurrent change is reported as models.ErrVersionMismatch rather than
+// lost.
+func (ts *TaskService) PatchTask(ctx context.Context, id string, format PatchFormat, p []byte, pre Precondition) (*models.Task, error) {
+	var task *models.Task
+	err := ts.atomically(ctx, func(tx *TaskService) error {
+		current, err := tx.repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := pre.check(current); err != nil {
+			return err
+		}
+
+		task, err = applyPatch(current, format, p)
+		if err != nil {
+			return err
+		}
+
+		return tx.replace(ctx, current, task)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// applyPatch applies p to the patchable fields of current and returns the
+// validated result.
+func applyPatch(current *models.Task, format PatchFormat, p []byte) (*models.Task, error) {
+	var recurrence *models.RecurrenceRequest
+	if current.Recurrence != nil {
+		recurrence = &models.RecurrenceRequest{Rule: current.Recurrence.Rule}
+	}
+
+	doc, err := json.Marshal(models.TaskPatch{
+		Title:       current.Title,
+		ActiveAt:    current.ActiveAt,
+		ProjectID:   current.ProjectID,
+		ParentID:    current.ParentID,
+		Status:      current.Status,
+		Description: current.Description,
+		Tags:        current.Tags,
+		Priority:    current.Priority,
+		DueAt:       current.DueAt,
+		Recurrence:  recurrence,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+	switch format {
+	case MergePatch:
+		patched, err = patch.Merge(doc, p)
+	case JSONPatch:
+		patched, err = patch.Apply(doc, p)
+	default:
+		return nil, fmt.Errorf("unknown patch format %d", format)
+	}
+	switch {
+	case errors.Is(err, patch.ErrTestFailed):
+		return nil, fmt.Errorf("%w: %v", models.ErrConflict, err)
+	case err != nil:
+		verr := &models.ValidationError{}
+		verr.Add("patch", err.Error())
+		return nil, verr
+	}
+
+	task, err := decodePatchedTask(patched)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := task.Validate(); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// decodePatchedTask reads a patched models.TaskPatch document, rejecting
+// fields that are not part of it.
+func decodePatchedTask(data []byte) (*models.Task, error) {
+	verr := &models.ValidationError{}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		verr.Add("patch", "the patched task must be a JSON object")
+		return nil, verr
+	}
+
+	var task models.Task
+	targets := map[string]any{
+		"title":       &task.Title,
+		"activeAt":    &task.ActiveAt,
+		"projectId":   &task.ProjectID,
+		"parentId":    &task.ParentID,
+		"status":      &task.Status,
+		"description": &task.Description,
+		"tags":        &task.Tags,
+		"priority":    &task.Priority,
+		"dueAt":       &task.DueAt,
+		"recurrence":  &task.Recurrence,
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		target, ok := targets[name]
+		if !ok {
+			verr.Add(name, name+" cannot be patched")
+			continue
+		}
+
+		if err := json.Unmarshal(fields[name], target); err != nil {
+			switch target.(type) {
+			case *[]string:
+				verr.Add(name, name+" must be an array of strings")
+			case **models.Recurrence:
+				verr.Add(name, name+` must be an object with a rule, or null`)
+			default:
+				verr.Add(name, name+" must be a string")
+			}
+		}
+	}
+
+	return &task, verr.Err()
+}
+
+// DoneTask moves task id to the done status of the workflow if pre holds,
+// as TransitionTask does. Done with an occurrence of a recurring task, it
+// creates the next one in the same transaction, and done with a subtask, it
+// may complete its parent; see WithParentAutoComplete.
+func (ts *TaskService) DoneTask(ctx context.Context, id string, pre Precondition) (*models.Task, error) {
+	return ts.TransitionTask(ctx, id, ts.workflow.Done(), pre)
+}
+
+// MaxBatchSize bounds the number of operations in a batch.
+const MaxBatchSize = 100
+
+// BatchTasks applies the operations of req in order, through
+// TaskRepo.Batch, each as the single-task call it stands for would: create
+// as PostTask, update as PutTask, done as DoneTask and delete as
+// DeleteTask. Every operation sees the tasks as the ones before it left
+// them. A failed operation changes nothing; in an atomic batch it undoes the
+// operations before it too, and those after it are not tried. Malformed
+// operations abort an atomic batch before anything is tried.
+func (ts *TaskService) BatchTasks(ctx context.Context, req models.BatchRequest) ([]repositories.BatchResult, error) {
+	if n := len(req.Operations); n == 0 || n > MaxBatchSize {
+		verr := &models.ValidationError{}
+		verr.Add("operations", fmt.Sprintf("a batch must have between 1 and %d operations", MaxBatchSize))
+		return nil, verr
+	}
+
+	errs := make([]error, len(req.Operations))
+	malformed := false
+	for i, op := range req.Operations {
+		errs[i] = checkBatchOp(op)
+		malformed = malformed || errs[i] != nil
+	}
+
+	if req.Atomic && malformed {
+		results := make([]repositories.BatchResult, len(req.Operations))
+		for i, err := range errs {
+			if err == nil {
+				err = models.ErrBatchAborted
+			}
+			results[i].Err = err
+		}
+		return results, nil
+	}
+
+	ops := make([]repositories.BatchOp, len(req.Operations))
+	for i, op := range req.Operations {
+		if err := errs[i]; err != nil {
+			ops[i] = func(repositories.TaskRepo) (*models.Task, error) { return nil, err }
+			continue
+		}
+
+		ops[i] = func(repo repositories.TaskRepo) (*models.Task, error) {
+			tx := *ts
+			tx.repo = repo
+			return tx.applyBatchOp(ctx, op)
+		}
+	}
+
+	return ts.repo.Batch(ctx, ops, req.Atomic)
+}
+
+// checkBatchOp checks that op has the fields its kind takes and that its
+// task is valid.
+func checkBatchOp(op models.BatchOperation) error {
+	verr := &models.ValidationError{}
+
+	switch op.Op {
+	case "create", "update":
+		if op.Task == nil {
+			verr.Add("task", op.Op+" needs a task")
+		} else if err := op.Task.Validate(); err != nil {
+			var taskErr *models.ValidationError
+			if !errors.As(err, &taskErr) {
+				return err
+			}
+			verr.Fields = append(verr.Fields, taskErr.Fields...)
+		}
+	case "done", "delete":
+	default:
+		verr.Add("op", `op must be "create", "update", "done" or "delete"`)
+	}
+
+	switch op.Op {
+	case "create":
+		if op.ID != "" {
+			verr.Add("id", "create takes no id")
+		}
+		if op.Version != 0 {
+			verr.Add("version", "create takes no version")
+		}
+	case "update", "done", "delete":
+		if op.ID == "" {
+			verr.Add("id", op.Op+" needs an id")
+		}
+	}
+
+	return verr.Err()
+}
+
+// applyBatchOp applies op, checked by checkBatchOp, and returns the task
+// as stored, nil after a delete. Tasks created by a batch get a fresh ID.
+func (ts *TaskService) applyBatchOp(ctx context.Context, op models.BatchOperation) (*models.Task, error) {
+	var pre Precondition
+	if op.Version != 0 {
+		pre.IfMatch = []string{strconv.Quote(strconv.FormatInt(op.Version, 10))}
+	}
+
+	switch op.Op {
+	case "create":
+		task := *op.Task
+		task.ID = uuid.New().String()
+		if err := ts.PostTask(ctx, &task); err != nil {
+			return nil, err
+		}
+		return &task, nil
+	case "update":
+		task := *op.Task
+		if err := ts.PutTask(ctx, op.ID, &task, pre); err != nil {
+			return nil, err
+		}
+		return &task, nil
+	case "done":
+		return ts.DoneTask(ctx, op.ID, pre)
+	default:
+		return nil, ts.DeleteTask(ctx, op.ID, pre)
+	}
+}
+
+// DeleteTask deletes task id if pre holds and it has no subtasks, which the
+// repository enforces.
+func (ts *TaskService) DeleteTask(ctx context.Context, id string, pre Precondition) error {
+	version, err := ts.expectedVersion(ctx, id, pre)
+	if err != nil {
+		return err
+	}
+
+	return ts.repo.Delete(ctx, id, version)
 }
 
-func (ts *TaskService) PutTask(ctx context.Context, id string, task *models.Task) error {
-	return ts.repo.Put(ctx, id, task)
+// ListTags counts the tasks carrying each tag, most used first.
+func (ts *TaskService) ListTags(ctx context.Context) ([]models.Tag, error) {
+	return ts.repo.Tags(ctx)
 }
 
-func (ts *TaskService) DoneTask(ctx context.Context, id string) error {
-	return ts.repo.MarkAsDone(ctx, id)
+// RenameTag renames tag from to a tag that is not in use yet.
+func (ts *TaskService) RenameTag(ctx context.Context, from, to string) (*models.Tag, error) {
+	return ts.retag(ctx, from, to, "name", false)
 }
 
-func (ts *TaskService) DeleteTask(ctx context.Context, id string) error {
-	return ts.repo.Delete(ctx, id)
+// MergeTag replaces tag from with into on every task, into being in use or
+// not. Tasks carrying both keep one.
+func (ts *TaskService) MergeTag(ctx context.Context, from, into string) (*models.Tag, error) {
+	return ts.retag(ctx, from, into, "into", true)
+}
+
+// retag replaces from with to, which is reported as field if invalid, and
+// returns to with its new usage count.
+func (ts *TaskService) retag(ctx context.Context, from, to, field string, merge bool) (*models.Tag, error) {
+	from, to = models.NormalizeTag(from), models.NormalizeTag(to)
+
+	verr := &models.ValidationError{}
+	if err := models.ValidateTag(to); err != nil {
+		verr.Add(field, err.Error())
+	} else if to == from {
+		verr.Add(field, "the new tag must differ from the current one")
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	retagged, err := ts.repo.RenameTag(ctx, from, to, merge)
+	if err != nil {
+		return nil, err
+	}
+	if len(retagged) == 0 {
+		return nil, models.ErrTagNotFound
+	}
+
+	tags, err := ts.repo.Tags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	renamed := &models.Tag{Name: to}
+	for _, tag := range tags {
+		if tag.Name == to {
+			renamed.Count = tag.Count
+		}
+	}
+
+	return renamed, nil
 }
@@ -0,0 +1,1209 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/canyouhearthemusic/todo-list/internal/calendar"
+	"github.com/canyouhearthemusic/todo-list/internal/models"
+	"github.com/canyouhearthemusic/todo-list/internal/repositories"
+	"github.com/canyouhearthemusic/todo-list/internal/services"
+	"github.com/canyouhearthemusic/todo-list/internal/workflow"
+)
+
+func TestGetAllTasksDoesNotMutateTitles(t *testing.T) {
+	ctx := context.Background()
+	ts := services.New(repositories.NewSyncMapTaskRepo())
+
+	// 2024-05-04 is a Saturday.
+	task := &models.Task{ID: "1", Title: "groceries", ActiveAt: "2024-05-04"}
+	if err := ts.PostTask(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		list, err := ts.GetAllTasks(ctx, services.ListOptions{Status: "active", Decorate: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list.Tasks) != 1 {
+			t.Fatalf("GetAllTasks returned %d tasks, want 1", len(list.Tasks))
+		}
+
+		view := list.Tasks[0]
+		if view.Title != "groceries" {
+			t.Errorf("listing %d: title = %q, want the raw title", i, view.Title)
+		}
+		if !view.IsWeekend {
+			t.Errorf("listing %d: isWeekend = false for a Saturday", i)
+		}
+		if view.DisplayTitle != "ВЫХОДНОЙ - groceries" {
+			t.Errorf("listing %d: displayTitle = %q", i, view.DisplayTitle)
+		}
+	}
+
+	got, err := ts.GetTask(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "groceries" {
+		t.Errorf("GetTask title = %q after listing, want %q", got.Title, "groceries")
+	}
+}
+
+func TestPreconditions(t *testing.T) {
+	ctx := context.Background()
+	ts := services.New(repositories.NewSyncMapTaskRepo())
+
+	task := &models.Task{ID: "1", Title: "edit me", ActiveAt: "2024-05-06"}
+	if err := ts.PostTask(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+
+	put := func(pre services.Precondition) error {
+		return ts.PutTask(ctx, "1", &models.Task{Title: "edit me", ActiveAt: "2024-05-07", Status: "active"}, pre)
+	}
+
+	tests := []struct {
+		name string
+		pre  services.Precondition
+		ok   bool
+	}{
+		{"unconditional", services.Precondition{}, true},
+		{"If-Match current", services.Precondition{IfMatch: []string{`"2"`}}, true},
+		{"If-Match stale", services.Precondition{IfMatch: []string{`"1"`}}, false},
+		{"If-Match list", services.Precondition{IfMatch: []string{`"1"`, `"3"`}}, true},
+		{"If-Match weak", services.Precondition{IfMatch: []string{`W/"4"`}}, false},
+		{"If-Match any", services.Precondition{IfMatch: []string{"*"}}, true},
+		{"If-None-Match stale", services.Precondition{IfNoneMatch: []string{`"1"`}}, true},
+		{"If-None-Match current weak", services.Precondition{IfNoneMatch: []string{`W/"6"`}}, false},
+		{"If-None-Match any", services.Precondition{IfNoneMatch: []string{"*"}}, false},
+	}
+
+	for _, tt := range tests {
+		err := put(tt.pre)
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, models.ErrPreconditionFailed) {
+			t.Errorf("%s: got %v, want ErrPreconditionFailed", tt.name, err)
+		}
+	}
+
+	// Preconditions are ignored for a missing task, which is just not found.
+	err := ts.DeleteTask(ctx, "missing", services.Precondition{IfMatch: []string{`"1"`}})
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("conditional Delete of a missing task: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestTransitionTask(t *testing.T) {
+	ctx := services.WithActor(context.Background(), "alice")
+	ts := services.New(repositories.NewSyncMapTaskRepo())
+
+	task := &models.Task{ID: "1", Title: "ship it", ActiveAt: "2024-05-06"}
+	if err := ts.PostTask(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+
+	done, err := ts.DoneTask(ctx, "1", services.Precondition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != "done" || done.StatusChangedBy != "alice" || done.StatusChangedAt == nil {
+		t.Errorf("after DoneTask: %+v", *done)
+	}
+
+	again, err := ts.DoneTask(ctx, "1", services.Precondition{})
+	if err != nil || again.Version != done.Version {
+		t.Errorf("DoneTask of a done task: version %d, %v; want a no-op", again.Version, err)
+	}
+
+	reopened, err := ts.ReopenTask(services.WithActor(ctx, "bob"), "1", services.Precondition{IfMatch: []string{done.ETag()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Status != "active" || reopened.StatusChangedBy != "bob" {
+		t.Errorf("after ReopenTask: %+v", *reopened)
+	}
+
+	// A PUT without a status keeps the current one and its record.
+	if err := ts.PutTask(ctx, "1", &models.Task{Title: "ship it now", ActiveAt: "2024-05-06"}, services.Precondition{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ts.GetTask(ctx, "1"); got.Status != "active" || got.StatusChangedBy != "bob" {
+		t.Errorf("after a PUT without status: %+v", *got)
+	}
+
+	if _, err := ts.TransitionTask(ctx, "1", "archived", services.Precondition{}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("transition to an unknown status: got %v, want a validation error", err)
+	}
+
+	oneWay, err := workflow.New(workflow.Definition{
+		Initial:     "active",
+		Done:        "done",
+		Transitions: map[string][]string{"active": {"done"}, "done": nil},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts = services.New(repositories.NewSyncMapTaskRepo(), services.WithWorkflow(oneWay))
+	if err := ts.PostTask(ctx, &models.Task{ID: "1", Title: "final", ActiveAt: "2024-05-06"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.DoneTask(ctx, "1", services.Precondition{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ts.ReopenTask(ctx, "1", services.Precondition{}); !errors.Is(err, models.ErrTransitionNotAllowed) {
+		t.Errorf("reopen in a one-way workflow: got %v, want ErrTransitionNotAllowed", err)
+	}
+	err = ts.PutTask(ctx, "1", &models.Task{Title: "final", ActiveAt: "2024-05-06", Status: "active"}, services.Precondition{})
+	if !errors.Is(err, models.ErrConflict) {
+		t.Errorf("PUT reopening in a one-way workflow: got %v, want a conflict", err)
+	}
+}
+
+func TestPatchTask(t *testing.T) {
+	ctx := context.Background()
+	ts := services.New(repositories.NewSyncMapTaskRepo())
+
+	task := &models.Task{ID: "1", Title: "draft", ActiveAt: "2024-05-06"}
+	if err := ts.PostTask(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.DoneTask(ctx, "1", services.Precondition{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unlike PUT, a patch leaves the fields it does not mention alone.
+	got, err := ts.PatchTask(ctx, "1", services.MergePatch, []byte(`{"title":"final"}`), services.Precondition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "final" || got.Status != "done" || got.ActiveAt != "2024-05-06" || got.Version != 3 {
+		t.Errorf("after merge patch: %+v", *got)
+	}
+
+	got, err = ts.PatchTask(ctx, "1", services.JSONPatch,
+		[]byte(`[{"op":"test","path":"/title","value":"final"},{"op":"replace","path":"/status","value":"active"}]`),
+		services.Precondition{IfMatch: []string{`"3"`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != "active" || got.Title != "final" {
+		t.Errorf("after JSON patch: %+v", *got)
+	}
+
+	tests := []struct {
+		name   string
+		format services.PatchFormat
+		patch  string
+		want   error
+	}{
+		{"read-only field", services.MergePatch, `{"id":"2"}`, models.ErrValidation},
+		{"invalid result", services.MergePatch, `{"activeAt":"tomorrow"}`, models.ErrValidation},
+		{"invalid status", services.JSONPatch, `[{"op":"replace","path":"/status","value":"lost"}]`, models.ErrValidation},
+		{"wrong type", services.MergePatch, `{"title":7}`, models.ErrValidation},
+		{"not an object", services.MergePatch, `"title"`, models.ErrValidation},
+		{"missing path", services.JSONPatch, `[{"op":"remove","path":"/owner"}]`, models.ErrValidation},
+		{"failed test", services.JSONPatch, `[{"op":"test","path":"/title","value":"draft"}]`, models.ErrConflict},
+	}
+
+	for _, tt := range tests {
+		if _, err := ts.PatchTask(ctx, "1", tt.format, []byte(tt.patch), services.Precondition{}); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	if got, _ := ts.GetTask(ctx, "1"); got.Title != "final" || got.Status != "active" || got.Version != 4 {
+		t.Errorf("rejected patches changed the task: %+v", *got)
+	}
+}
+
+func TestBatchTasks(t *testing.T) {
+	ctx := context.Background()
+	ts := services.New(repositories.NewSyncMapTaskRepo())
+
+	task := &models.Task{ID: "1", Title: "existing", ActiveAt: "2024-05-06"}
+	if err := ts.PostTask(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+
+	ops := []models.BatchOperation{
+		{Op: "create", Task: &models.Task{Title: "new", ActiveAt: "2024-05-07"}},
+		{Op: "create", Task: &models.Task{Title: "bad date", ActiveAt: "tomorrow"}},
+		{Op: "done", ID: "1", Version: 1},
+		{Op: "archive", ID: "1"},
+	}
+
+	results, err := ts.BatchTasks(ctx, models.BatchRequest{Operations: ops})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if results[0].Err != nil || results[0].Task == nil || results[0].Task.ID == "" {
+		t.Errorf("create: got %+v, want a task with a fresh ID", results[0])
+	}
+	for _, i := range []int{1, 3} {
+		if !errors.Is(results[i].Err, models.ErrValidation) {
+			t.Errorf("op %d: got error %v, want a validation error", i, results[i].Err)
+		}
+	}
+	if results[2].Err != nil || results[2].Task.Status != "done" {
+		t.Errorf("done: got %+v", results[2])
+	}
+
+	// An invalid operation aborts an atomic batch before anything is
+	// written.
+	ops = []models.BatchOperation{
+		{Op: "create", Task: &models.Task{Title: "never", ActiveAt: "2024-05-07"}},
+		{Op: "update", Task: &models.Task{Title: "no id", ActiveAt: "2024-05-07"}},
+	}
+
+	results, err = ts.BatchTasks(ctx, models.BatchRequest{Atomic: true, Operations: ops})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(results[0].Err, models.ErrBatchAborted) || !errors.Is(results[1].Err, models.ErrValidation) {
+		t.Errorf("atomic batch results = %v, %v", results[0].Err, results[1].Err)
+	}
+
+	list, err := ts.GetAllTasks(ctx, services.ListOptions{Filter: `title="never"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 0 {
+		t.Error("aborted batch created a task")
+	}
+
+	if _, err := ts.BatchTasks(ctx, models.BatchRequest{}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("empty batch: got %v, want a validation error", err)
+	}
+}
+
+// Every operation of a batch sees what the ones before it did.
+func TestBatchTasksInOrder(t *testing.T) {
+	repos := map[string]func(t *testing.T) repositories.TaskRepo{
+		"syncmap": func(t *testing.T) repositories.TaskRepo {
+			return repositories.NewSyncMapTaskRepo()
+		},
+		"sqlite": func(t *testing.T) repositories.TaskRepo {
+			repo, err := repositories.NewSQLiteTaskRepo(filepath.Join(t.TempDir(), "tasks.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { repo.Close() })
+			return repo
+		},
+	}
+
+	for name, newRepo := range repos {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			ts := services.New(newRepo(t))
+
+			for _, task := range []*models.Task{
+				{ID: "parent", Title: "parent", ActiveAt: "2024-05-06"},
+				{ID: "child", Title: "child", ActiveAt: "2024-05-06", ParentID: "parent"},
+				{ID: "first", Title: "first", ActiveAt: "2024-05-06"},
+				{ID: "second", Title: "second", ActiveAt: "2024-05-06", BlockedBy: []string{"first"}},
+			} {
+				if err := ts.PostTask(ctx, task); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// The last operation fails, so an atomic batch undoes the others.
+			ops := []models.BatchOperation{
+				{Op: "delete", ID: "child"},
+				{Op: "delete", ID: "parent"},
+				{Op: "done", ID: "first"},
+				{Op: "done", ID: "second"},
+				{Op: "done", ID: "missing"},
+			}
+			results, err := ts.BatchTasks(ctx, models.BatchRequest{Atomic: true, Operations: ops})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, result := range results[:4] {
+				if !errors.Is(result.Err, models.ErrBatchAborted) {
+					t.Errorf("atomic op %d: got %v, want ErrBatchAborted", i, result.Err)
+				}
+			}
+			if !errors.Is(results[4].Err, models.ErrNotFound) {
+				t.Errorf("atomic op 4: got %v, want ErrNotFound", results[4].Err)
+			}
+			for _, id := range []string{"parent", "child", "first", "second"} {
+				if task, err := ts.GetTask(ctx, id); err != nil || task.Version != 1 {
+					t.Errorf("%s after an aborted batch: %+v, %v", id, task, err)
+				}
+			}
+
+			results, err = ts.BatchTasks(ctx, models.BatchRequest{Operations: ops[:4]})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, result := range results {
+				if result.Err != nil {
+					t.Errorf("op %d (%s %s): %v", i, ops[i].Op, ops[i].ID, result.Err)
+				}
+			}
+			if _, err := ts.GetTask(ctx, "parent"); !errors.Is(err, models.ErrNotFound) {
+				t.Errorf("parent after the batch: got %v, want ErrNotFound", err)
+			}
+			if task, _ := ts.GetTask(ctx, "second"); task == nil || task.Status != "done" {
+				t.Errorf("second after the batch: %+v", task)
+			}
+		})
+	}
+}
+
+func TestCustomWorkflow(t *testing.T) {
+	ctx := context.Background()
+
+	flow, err := workflow.New(workflow.Definition{
+		Initial: "todo",
+		Done:    "done",
+		Closed:  []string{"cancelled"},
+		Transitions: map[string][]string{
+			"todo":        {"in_progress", "cancelled"},
+			"in_progress": {"review", "done"},
+			"review":      {"in_progress", "done"},
+			"done":        {"todo"},
+			"cancelled":   {"todo"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := services.New(repositories.NewSyncMapTaskRepo(), services.WithWorkflow(flow))
+
+	for _, id := range []string{"1", "2", "3", "4"} {
+		if err := ts.PostTask(ctx, &models.Task{ID: id, Title: "task " + id, ActiveAt: "2024-05-06"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, _ := ts.GetTask(ctx, "1"); got.Status != "todo" {
+		t.Errorf("new task status = %q, want the initial todo", got.Status)
+	}
+
+	for id, status := range map[string]string{"2": "in_progress", "3": "cancelled"} {
+		if _, err := ts.TransitionTask(ctx, id, status, services.Precondition{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := ts.TransitionTask(ctx, "4", "review", services.Precondition{}); !errors.Is(err, models.ErrTransitionNotAllowed) {
+		t.Errorf("todo to review: got %v, want ErrTransitionNotAllowed", err)
+	}
+	if _, err := ts.DoneTask(ctx, "4", services.Precondition{}); !errors.Is(err, models.ErrTransitionNotAllowed) {
+		t.Errorf("todo to done: got %v, want ErrTransitionNotAllowed", err)
+	}
+
+	tests := []struct {
+		status string
+		want   int
+	}{
+		{"", 3},
+		{"in_progress", 1},
+		{"todo,cancelled", 3},
+	}
+
+	for _, tt := range tests {
+		list, err := ts.GetAllTasks(ctx, services.ListOptions{Status: tt.status})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if list.Total != tt.want {
+			t.Errorf("status %q: listed %d tasks, want %d", tt.status, list.Total, tt.want)
+		}
+	}
+
+	if _, err := ts.GetAllTasks(ctx, services.ListOptions{Status: "todo,active"}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("listing an unknown status: got %v, want a validation error", err)
+	}
+}
+
+func TestMigrateStatuses(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewSyncMapTaskRepo()
+
+	// Tasks stored under the default workflow.
+	def := services.New(repo)
+	for _, id := range []string{"1", "2"} {
+		if err := def.PostTask(ctx, &models.Task{ID: id, Title: "task " + id, ActiveAt: "2024-05-06"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := def.DoneTask(ctx, "2", services.Precondition{}); err != nil {
+		t.Fatal(err)
+	}
+
+	flow, err := workflow.New(workflow.Definition{
+		Initial:     "todo",
+		Done:        "finished",
+		Transitions: map[string][]string{"todo": {"finished"}, "finished": {"todo"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := services.New(repo, services.WithWorkflow(flow))
+
+	if _, err := ts.MigrateStatuses(ctx, map[string]string{"active": "todo"}); err == nil || !strings.Contains(err.Error(), "done") {
+		t.Errorf("MigrateStatuses leaving done unmapped: got %v, want an error naming done", err)
+	}
+	if task, _ := ts.GetTask(ctx, "1"); task.Status != "active" {
+		t.Errorf("a refused migration moved task 1 to %q", task.Status)
+	}
+
+	n, err := ts.MigrateStatuses(ctx, map[string]string{"active": "todo", "done": "finished"})
+	if err != nil || n != 2 {
+		t.Fatalf("MigrateStatuses = %d, %v; want 2 tasks moved", n, err)
+	}
+	if _, err := ts.DoneTask(ctx, "1", services.Precondition{}); err != nil {
+		t.Errorf("DoneTask after migrating: %v", err)
+	}
+	if n, err := ts.MigrateStatuses(ctx, nil); err != nil || n != 0 {
+		t.Errorf("MigrateStatuses again = %d, %v; want nothing to do", n, err)
+	}
+}
+
+func TestOverdue(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	ts := services.New(repositories.NewSyncMapTaskRepo(), services.WithClock(func() time.Time { return now }))
+
+	tasks := map[string]string{
+		"late":   "2024-05-10T16:00:00+05:00",
+		"later":  "2024-05-10T12:00:00-04:00",
+		"undue":  "",
+		"closed": "2024-05-01T00:00:00Z",
+	}
+	for title, dueAt := range tasks {
+		task := &models.Task{ID: title, Title: title, ActiveAt: "2024-05-06", DueAt: dueAt}
+		if err := ts.PostTask(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := ts.DoneTask(ctx, "closed", services.Precondition{}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := ts.GetAllTasks(ctx, services.ListOptions{Filter: `title!=""`, Sort: "dueAt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, view := range list.Tasks {
+		if want := view.Title == "late"; view.Overdue != want {
+			t.Errorf("%s: overdue = %t, want %t", view.Title, view.Overdue, want)
+		}
+	}
+
+	list, err = ts.GetAllTasks(ctx, services.ListOptions{Overdue: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Tasks) != 1 || list.Tasks[0].Title != "late" {
+		t.Errorf("overdue listing = %+v, want only late", list.Tasks)
+	}
+
+	if _, err := ts.GetAllTasks(ctx, services.ListOptions{Overdue: true, Status: "done"}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("overdue done tasks: got %v, want a validation error", err)
+	}
+
+	got, err := ts.PatchTask(ctx, "undue", services.MergePatch, []byte(`{"priority":"urgent","dueAt":"2024-05-09T09:00:00+05:00"}`), services.Precondition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Priority != models.PriorityUrgent || got.DueAt != "2024-05-09T09:00:00+05:00" {
+		t.Errorf("after patch: %+v", *got)
+	}
+
+	for _, p := range []string{`{"priority":"asap"}`, `{"dueAt":"2024-05-09"}`} {
+		if _, err := ts.PatchTask(ctx, "undue", services.MergePatch, []byte(p), services.Precondition{}); !errors.Is(err, models.ErrValidation) {
+			t.Errorf("patch %s: got %v, want a validation error", p, err)
+		}
+	}
+}
+
+func TestDescription(t *testing.T) {
+	ctx := context.Background()
+	ts := services.New(repositories.NewSyncMapTaskRepo())
+
+	task := &models.Task{ID: "1", Title: "notes", ActiveAt: "2024-05-06", Description: "See **this** <b>now</b>"}
+	if err := ts.PostTask(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+
+	const want = "<p>See <strong>this</strong> &lt;b&gt;now&lt;/b&gt;</p>\n"
+
+	rendered, err := ts.TaskDetail(ctx, task, services.RenderHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered.DescriptionHTML != want || rendered.Description != task.Description {
+		t.Errorf("TaskDetail = %+v", *rendered)
+	}
+
+	if _, err := ts.SearchTasks(ctx, "notes", 10); !errors.Is(err, models.ErrNotImplemented) {
+		t.Errorf("SearchTasks without a full-text index: got %v, want ErrNotImplemented", err)
+	}
+
+	list, err := ts.GetAllTasks(ctx, services.ListOptions{Render: services.RenderHTML})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := list.Tasks[0].DescriptionHTML; got != want {
+		t.Errorf("rendered listing: descriptionHtml = %q", got)
+	}
+
+	list, err = ts.GetAllTasks(ctx, services.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := list.Tasks[0].DescriptionHTML; got != "" {
+		t.Errorf("plain listing: descriptionHtml = %q, want none", got)
+	}
+
+	if _, err := ts.GetAllTasks(ctx, services.ListOptions{Render: "pdf"}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("listing with an unknown render mode: got %v, want a validation error", err)
+	}
+	if _, err := ts.TaskDetail(ctx, task, "text"); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("TaskDetail with an unknown mode: got %v, want a validation error", err)
+	}
+
+	long := &models.Task{Title: "long", ActiveAt: "2024-05-06", Description: strings.Repeat("ж", models.MaxDescriptionLength+1)}
+	if err := long.Validate(); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Validate of an overlong description: got %v, want a validation error", err)
+	}
+	long.Description = long.Description[2:]
+	if err := long.Validate(); err != nil {
+		t.Errorf("Validate of a description at the limit: %v", err)
+	}
+}
+
+func TestTags(t *testing.T) {
+	ctx := context.Background()
+	ts := services.New(repositories.NewSyncMapTaskRepo())
+
+	a := &models.Task{ID: "a", Title: "a", ActiveAt: "2024-05-06", Tags: []string{" Backend", "be", "backend"}}
+	b := &models.Task{ID: "b", Title: "b", ActiveAt: "2024-05-06", Tags: []string{"be", "urgent"}}
+	for _, task := range []*models.Task{a, b} {
+		if err := ts.PostTask(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := strings.Join(a.Tags, ","); got != "backend,be" {
+		t.Errorf("PostTask stored tags %q, want them normalized", got)
+	}
+
+	list, err := ts.GetAllTasks(ctx, services.ListOptions{TagsAll: "BE, urgent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Tasks) != 1 || list.Tasks[0].ID != "b" {
+		t.Errorf("tagsAll listing = %v, want b", list.Tasks)
+	}
+	if _, err := ts.GetAllTasks(ctx, services.ListOptions{TagsNone: "a b"}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("listing with an invalid tag: got %v, want a validation error", err)
+	}
+
+	if _, err := ts.RenameTag(ctx, "be", "backend"); !errors.Is(err, models.ErrConflict) {
+		t.Errorf("RenameTag onto a tag in use: got %v, want a conflict", err)
+	}
+	if _, err := ts.RenameTag(ctx, "missing", "other"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("RenameTag of an unused tag: got %v, want not found", err)
+	}
+	if _, err := ts.MergeTag(ctx, "be", "be"); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("MergeTag into itself: got %v, want a validation error", err)
+	}
+
+	tag, err := ts.MergeTag(ctx, "be", "Backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *tag != (models.Tag{Name: "backend", Count: 2}) {
+		t.Errorf("MergeTag = %+v, want backend used twice", *tag)
+	}
+
+	if tag, err = ts.RenameTag(ctx, "urgent", "asap"); err != nil {
+		t.Fatal(err)
+	}
+	if *tag != (models.Tag{Name: "asap", Count: 1}) {
+		t.Errorf("RenameTag = %+v, want asap used once", *tag)
+	}
+
+	patched, err := ts.PatchTask(ctx, "b", services.MergePatch, []byte(`{"tags":["Later","asap"]}`), services.Precondition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(patched.Tags, ","); got != "asap,later" {
+		t.Errorf("patched tags %q, want asap,later", got)
+	}
+	if _, err := ts.PatchTask(ctx, "b", services.MergePatch, []byte(`{"tags":"x"}`), services.Precondition{}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("patching tags with a string: got %v, want a validation error", err)
+	}
+}
+
+func TestProjects(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewSyncMapTaskRepo()
+	ts := services.New(repo)
+	ps := services.NewProjectService(repo)
+
+	home := &models.Project{ID: "home", Name: "  home "}
+	if err := ps.PostProject(ctx, home); err != nil {
+		t.Fatal(err)
+	}
+	if home.Name != "home" {
+		t.Errorf("PostProject stored name %q, want it trimmed", home.Name)
+	}
+
+	task := &models.Task{ID: "1", Title: "groceries", ActiveAt: "2024-05-06"}
+	if err := ts.PostTask(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := ts.MoveTask(ctx, "1", "home", services.Precondition{IfMatch: []string{task.ETag()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.ProjectID != "home" || moved.Version != task.Version+1 {
+		t.Errorf("MoveTask = project %q version %d", moved.ProjectID, moved.Version)
+	}
+	if _, err := ts.MoveTask(ctx, "1", "nowhere", services.Precondition{}); !errors.Is(err, models.ErrProjectNotFound) {
+		t.Errorf("MoveTask to a missing project: got %v, want ErrProjectNotFound", err)
+	}
+
+	// A PUT without projectId keeps the task where it is.
+	if err := ts.PutTask(ctx, "1", &models.Task{Title: "groceries", ActiveAt: "2024-05-07"}, services.Precondition{}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := ts.GetAllTasks(ctx, services.ListOptions{Project: "home"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Tasks) != 1 || list.Tasks[0].ActiveAt != "2024-05-07" {
+		t.Errorf("project listing = %v, want the updated task", list.Tasks)
+	}
+	if _, err := ts.GetAllTasks(ctx, services.ListOptions{Project: "nowhere"}); !errors.Is(err, models.ErrProjectNotFound) {
+		t.Errorf("listing a missing project: got %v, want ErrProjectNotFound", err)
+	}
+
+	if err := ps.DeleteProject(ctx, "home", services.Precondition{IfMatch: []string{`"7"`}}); !errors.Is(err, models.ErrPreconditionFailed) {
+		t.Errorf("DeleteProject with a stale ETag: got %v, want ErrPreconditionFailed", err)
+	}
+	if err := ps.DeleteProject(ctx, "home", services.Precondition{}); !errors.Is(err, models.ErrConflict) {
+		t.Errorf("DeleteProject with tasks: got %v, want a conflict", err)
+	}
+}
+
+func TestSubtasks(t *testing.T) {
+	ctx := context.Background()
+	ts := services.New(repositories.NewSyncMapTaskRepo(), services.WithMaxDepth(3), services.WithParentAutoComplete(true))
+
+	post := func(id, parent string) {
+		t.Helper()
+		if err := ts.PostTask(ctx, &models.Task{ID: id, Title: id, ActiveAt: "2024-05-06", ParentID: parent}); err != nil {
+			t.Fatalf("PostTask(%s): %v", id, err)
+		}
+	}
+	post("root", "")
+	post("child", "root")
+	post("leaf", "child")
+	post("sibling", "root")
+	post("loose", "")
+
+	for _, tt := range []struct{ id, parent string }{
+		{"fourth", "leaf"},
+		{"orphan", "missing"},
+	} {
+		task := &models.Task{ID: tt.id, Title: tt.id, ActiveAt: "2024-05-06", ParentID: tt.parent}
+		if err := ts.PostTask(ctx, task); !errors.Is(err, models.ErrValidation) {
+			t.Errorf("PostTask under %s: got %v, want a validation error", tt.parent, err)
+		}
+	}
+
+	// Moving root under its own subtask would make a cycle; moving it under
+	// loose would nest leaf four levels deep.
+	for _, parent := range []string{"root", "leaf", "loose"} {
+		p := `{"parentId":"` + parent + `"}`
+		if _, err := ts.PatchTask(ctx, "root", services.MergePatch, []byte(p), services.Precondition{}); !errors.Is(err, models.ErrValidation) {
+			t.Errorf("patch %s: got %v, want a validation error", p, err)
+		}
+	}
+	if _, err := ts.PatchTask(ctx, "sibling", services.MergePatch, []byte(`{"parentId":"loose"}`), services.Precondition{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.PatchTask(ctx, "sibling", services.MergePatch, []byte(`{"parentId":"root"}`), services.Precondition{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A PUT without parentId keeps the task under its parent.
+	if err := ts.PutTask(ctx, "leaf", &models.Task{Title: "leaf", ActiveAt: "2024-05-07"}, services.Precondition{}); err != nil {
+		t.Fatal(err)
+	}
+	if leaf, _ := ts.GetTask(ctx, "leaf"); leaf.ParentID != "child" {
+		t.Errorf("leaf parentId after PUT = %q, want child", leaf.ParentID)
+	}
+
+	if err := ts.DeleteTask(ctx, "child", services.Precondition{}); !errors.Is(err, models.ErrHasSubtasks) {
+		t.Errorf("DeleteTask with subtasks: got %v, want ErrHasSubtasks", err)
+	}
+
+	list, err := ts.GetAllTasks(ctx, services.ListOptions{Parent: "root", Sort: "title"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Tasks) != 2 || list.Tasks[0].ID != "child" || list.Tasks[1].ID != "sibling" {
+		t.Fatalf("subtasks of root = %v", list.Tasks)
+	}
+	if p := list.Tasks[0].Progress; p == nil || p.SubtasksTotal != 1 || p.Percent != 0 {
+		t.Errorf("progress of child = %+v, want 0 of 1 subtasks", p)
+	}
+	if p := list.Tasks[1].Progress; p != nil {
+		t.Errorf("progress of sibling = %+v, want none", p)
+	}
+
+	// Any write marking the last open subtask done completes the parent.
+	if _, err := ts.PatchTask(ctx, "leaf", services.MergePatch, []byte(`{"status":"done"}`), services.Precondition{}); err != nil {
+		t.Fatal(err)
+	}
+	child, _ := ts.GetTask(ctx, "child")
+	root, _ := ts.GetTask(ctx, "root")
+	if child.Status != "done" || root.Status != "active" {
+		t.Errorf("after the last leaf is done: child %s, root %s, want done and active", child.Status, root.Status)
+	}
+
+	detail, err := ts.TaskDetail(ctx, root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := detail.Progress; p == nil || p.SubtasksDone != 1 || p.SubtasksTotal != 2 || p.Percent != 50 {
+		t.Errorf("progress of root = %+v, want 1 of 2 subtasks", p)
+	}
+
+	results, err := ts.BatchTasks(ctx, models.BatchRequest{Operations: []models.BatchOperation{{Op: "done", ID: "sibling"}}})
+	if err != nil || results[0].Err != nil {
+		t.Fatalf("batch done of sibling: %v, %+v", err, results)
+	}
+	if root, _ := ts.GetTask(ctx, "root"); root.Status != "done" {
+		t.Errorf("root after all subtasks are done: %s, want done", root.Status)
+	}
+}
+
+func TestChecklist(t *testing.T) {
+	ctx := context.Background()
+	ts := services.New(repositories.NewSyncMapTaskRepo())
+
+	task := &models.Task{ID: "1", Title: "trip", ActiveAt: "2024-05-06", Checklist: []models.ChecklistItem{{ID: "mine", Text: " tickets "}}}
+	if err := ts.PostTask(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	if item := task.Checklist[0]; item.ID == "mine" || item.Text != "tickets" {
+		t.Errorf("posted checklist item = %+v, want a fresh ID and trimmed text", item)
+	}
+
+	item, changed, err := ts.AddChecklistItem(ctx, "1", models.ChecklistItemRequest{Text: "hotel"}, services.Precondition{IfMatch: []string{task.ETag()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.Version != task.Version+1 {
+		t.Errorf("version after adding an item = %d, want %d", changed.Version, task.Version+1)
+	}
+	if _, _, err := ts.AddChecklistItem(ctx, "1", models.ChecklistItemRequest{Text: "late"}, services.Precondition{IfMatch: []string{task.ETag()}}); !errors.Is(err, models.ErrPreconditionFailed) {
+		t.Errorf("AddChecklistItem with a stale ETag: got %v, want ErrPreconditionFailed", err)
+	}
+	if _, _, err := ts.AddChecklistItem(ctx, "1", models.ChecklistItemRequest{Text: "  "}, services.Precondition{}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("AddChecklistItem without text: got %v, want a validation error", err)
+	}
+
+	if _, _, err := ts.PutChecklistItem(ctx, "1", item.ID, models.ChecklistItemRequest{Text: "hotel", Done: true}, services.Precondition{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ts.PutChecklistItem(ctx, "1", "missing", models.ChecklistItemRequest{Text: "x"}, services.Precondition{}); !errors.Is(err, models.ErrChecklistItemNotFound) {
+		t.Errorf("PutChecklistItem of a missing item: got %v, want ErrChecklistItemNotFound", err)
+	}
+
+	// A PUT of the task leaves its checklist alone.
+	if err := ts.PutTask(ctx, "1", &models.Task{Title: "trip", ActiveAt: "2024-05-07"}, services.Precondition{}); err != nil {
+		t.Fatal(err)
+	}
+
+	checklist, current, err := ts.GetChecklist(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checklist) != 2 || checklist[1].Text != "hotel" || !checklist[1].Done {
+		t.Errorf("checklist = %+v", checklist)
+	}
+
+	detail, err := ts.TaskDetail(ctx, current, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := detail.Progress; p == nil || p.ChecklistDone != 1 || p.ChecklistTotal != 2 || p.Percent != 50 {
+		t.Errorf("progress = %+v, want 1 of 2 items", p)
+	}
+
+	if _, err := ts.DeleteChecklistItem(ctx, "1", item.ID, services.Precondition{}); err != nil {
+		t.Fatal(err)
+	}
+	if checklist, _, _ := ts.GetChecklist(ctx, "1"); len(checklist) != 1 {
+		t.Errorf("checklist after deleting an item = %+v", checklist)
+	}
+}
+
+func TestBlockers(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewSyncMapTaskRepo()
+	ts := services.New(repo)
+
+	post := func(id, priority string, blockedBy ...string) {
+		t.Helper()
+		task := &models.Task{ID: id, Title: id, ActiveAt: "2024-05-06", Priority: priority, BlockedBy: blockedBy}
+		if err := ts.PostTask(ctx, task); err != nil {
+			t.Fatalf("PostTask(%s): %v", id, err)
+		}
+	}
+	post("design", "normal")
+	post("build", "urgent", "design", "design")
+	post("test", "normal", "build")
+	post("docs", "high")
+
+	if task, _ := ts.GetTask(ctx, "build"); len(task.BlockedBy) != 1 {
+		t.Errorf("build blockedBy = %v, want design once", task.BlockedBy)
+	}
+	if err := ts.PostTask(ctx, &models.Task{ID: "x", Title: "x", ActiveAt: "2024-05-06", BlockedBy: []string{"missing"}}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("PostTask blocked by a missing task: got %v, want a validation error", err)
+	}
+
+	for _, blocker := range []string{"design", "test", "build"} {
+		if _, err := ts.AddBlocker(ctx, "design", blocker, services.Precondition{}); !errors.Is(err, models.ErrValidation) {
+			t.Errorf("AddBlocker(design, %s): got %v, want a validation error", blocker, err)
+		}
+	}
+
+	order, err := ts.TaskOrder(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, task := range order {
+		ids = append(ids, task.ID)
+	}
+	if got, want := strings.Join(ids, ","), "docs,design,build,test"; got != want {
+		t.Errorf("TaskOrder = %s, want %s", got, want)
+	}
+
+	if _, err := ts.DoneTask(ctx, "build", services.Precondition{}); !errors.Is(err, models.ErrBlocked) {
+		t.Errorf("DoneTask with an unfinished blocker: got %v, want ErrBlocked", err)
+	}
+	if _, err := ts.DoneTask(ctx, "design", services.Precondition{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.DoneTask(ctx, "build", services.Precondition{}); err != nil {
+		t.Errorf("DoneTask once its blocker is done: %v", err)
+	}
+
+	task, err := ts.AddBlocker(ctx, "docs", "test", services.Precondition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := ts.AddBlocker(ctx, "docs", "test", services.Precondition{}); again.Version != task.Version {
+		t.Errorf("adding a blocker twice changed the version from %d to %d", task.Version, again.Version)
+	}
+
+	// With BlockersWarn the task can be done; the unfinished blockers are
+	// reported instead.
+	warn := services.New(repo, services.WithBlockerPolicy(services.BlockersWarn))
+	done, err := warn.DoneTask(ctx, "docs", services.Precondition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blockers, _ := warn.UnfinishedBlockers(ctx, done); len(blockers) != 1 || blockers[0].ID != "test" {
+		t.Errorf("UnfinishedBlockers = %v, want test", blockers)
+	}
+
+	if _, err := ts.RemoveBlocker(ctx, "docs", "design", services.Precondition{}); !errors.Is(err, models.ErrBlockerNotFound) {
+		t.Errorf("RemoveBlocker of a task not blocking: got %v, want ErrBlockerNotFound", err)
+	}
+	if _, err := ts.RemoveBlocker(ctx, "docs", "test", services.Precondition{}); err != nil {
+		t.Fatal(err)
+	}
+	if blockers, _, _ := ts.GetBlockers(ctx, "docs"); len(blockers) != 0 {
+		t.Errorf("blockers after removing the last one: %v", blockers)
+	}
+
+	// A cycle stored without the checks is reported with the tasks on it,
+	// not those merely waiting for it.
+	post("spec", "normal")
+	post("review", "normal", "spec")
+	spec, _ := repo.GetByID(ctx, "spec")
+	spec.BlockedBy = []string{"review"}
+	if err := repo.Put(ctx, "spec", spec, 0); err != nil {
+		t.Fatal(err)
+	}
+	post("release", "normal", "review")
+	var cycle *models.BlockerCycleError
+	if _, err := ts.TaskOrder(ctx, ""); !errors.As(err, &cycle) || !errors.Is(err, models.ErrConflict) {
+		t.Fatalf("TaskOrder with a cycle: got %v, want a BlockerCycleError", err)
+	}
+	if got := strings.Join(cycle.IDs, ","); got != "review,spec" {
+		t.Errorf("tasks on the cycle = %s, want review,spec", got)
+	}
+}
+
+// slowWrites widens the window between checking a change and storing it,
+// for writes made outside Atomically.
+type slowWrites struct {
+	repositories.TaskRepo
+}
+
+func (r slowWrites) Put(ctx context.Context, id string, task *models.Task, version int64) error {
+	time.Sleep(5 * time.Millisecond)
+	return r.TaskRepo.Put(ctx, id, task, version)
+}
+
+// Two tasks made to block each other at the same time cannot both succeed.
+func TestAddBlockerConcurrent(t *testing.T) {
+	ctx := context.Background()
+	ts := services.New(slowWrites{repositories.NewSyncMapTaskRepo()})
+
+	for i := 0; i < 20; i++ {
+		a, b := fmt.Sprintf("a%d", i), fmt.Sprintf("b%d", i)
+		for _, id := range []string{a, b} {
+			if err := ts.PostTask(ctx, &models.Task{ID: id, Title: id, ActiveAt: "2024-05-06"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for j, pair := range [][2]string{{a, b}, {b, a}} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[j] = ts.AddBlocker(ctx, pair[0], pair[1], services.Precondition{})
+			}()
+		}
+		wg.Wait()
+
+		if errs[0] == nil && errs[1] == nil {
+			t.Fatalf("%s and %s were both made to block each other", a, b)
+		}
+	}
+}
+
+func TestRecurrence(t *testing.T) {
+	ctx := context.Background()
+	cal := calendar.New(calendar.WithHolidays(&calendar.PublicHoliday{Date: "2024-05-09", Name: "Victory Day"}))
+	ts := services.New(repositories.NewSyncMapTaskRepo(), services.WithCalendar(cal))
+
+	// open returns the only open task, the current occurrence.
+	open := func() *models.Task {
+		t.Helper()
+		list, err := ts.GetAllTasks(ctx, services.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Tasks) != 1 {
+			t.Fatalf("%d open tasks, want 1", len(list.Tasks))
+		}
+		return &list.Tasks[0].Task
+	}
+
+	if err := ts.PostTask(ctx, &models.Task{ID: "x", Title: "x", ActiveAt: "2024-05-02", Recurrence: &models.Recurrence{Rule: "FREQ=HOURLY"}}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("PostTask with an hourly rule: got %v, want a validation error", err)
+	}
+
+	// Thursdays, the first on 2024-05-02; the second is a holiday.
+	err := ts.PostTask(ctx, &models.Task{
+		ID:         "standup",
+		Title:      "standup",
+		ActiveAt:   "2024-05-02",
+		DueAt:      "2024-05-02T18:00:00+05:00",
+		Checklist:  []models.ChecklistItem{{Text: "notes", Done: true}},
+		Recurrence: &models.Recurrence{Rule: "rrule:freq=weekly;count=3;byday=th", Occurrence: 7},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.Recurrence{Rule: "FREQ=WEEKLY;COUNT=3;BYDAY=TH", Start: "2024-05-02", Occurrence: 1, Scheduled: "2024-05-02"}
+	if task, _ := ts.GetTask(ctx, "standup"); *task.Recurrence != want {
+		t.Errorf("recurrence = %+v, want %+v", *task.Recurrence, want)
+	}
+
+	for _, p := range []string{`{"recurrence":"weekly"}`, `{"recurrence":{"rule":"FREQ=WEEKLY;BYHOUR=9"}}`} {
+		if _, err := ts.PatchTask(ctx, "standup", services.MergePatch, []byte(p), services.Precondition{}); !errors.Is(err, models.ErrValidation) {
+			t.Errorf("PatchTask(%s): got %v, want a validation error", p, err)
+		}
+	}
+	// The same rule written differently carries on the series.
+	patched, err := ts.PatchTask(ctx, "standup", services.MergePatch, []byte(`{"title":"standup","recurrence":{"rule":"FREQ=WEEKLY;BYDAY=TH;COUNT=3"}}`), services.Precondition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *patched.Recurrence != want {
+		t.Errorf("recurrence after patching in the same rule = %+v, want %+v", *patched.Recurrence, want)
+	}
+
+	// Marking it done in a batch creates the next occurrence as DoneTask
+	// does.
+	results, err := ts.BatchTasks(ctx, models.BatchRequest{Atomic: true, Operations: []models.BatchOperation{{Op: "done", ID: "standup"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err != nil {
+		t.Fatalf("batch done of a recurring task: %v", results[0].Err)
+	}
+	done := results[0].Task
+	if done.Title != "standup (2024-05-02)" || done.Recurrence != nil {
+		t.Errorf("done occurrence %q repeating by %+v, want it renamed and not repeating", done.Title, done.Recurrence)
+	}
+
+	next := open()
+	want = models.Recurrence{Rule: want.Rule, Start: "2024-05-02", Occurrence: 2, Scheduled: "2024-05-09"}
+	if next.Title != "standup" || next.ActiveAt != "2024-05-10" || *next.Recurrence != want {
+		t.Errorf("second occurrence %q on %s by %+v, want standup on 2024-05-10 by %+v", next.Title, next.ActiveAt, *next.Recurrence, want)
+	}
+	if next.DueAt != "2024-05-10T18:00:00+05:00" {
+		t.Errorf("second occurrence due %s, want 2024-05-10T18:00:00+05:00", next.DueAt)
+	}
+	if len(next.Checklist) != 1 || next.Checklist[0].Done {
+		t.Errorf("second occurrence checklist %+v, want notes not done", next.Checklist)
+	}
+
+	// The occurrence after the one moved to Friday is on the next Thursday.
+	if _, err := ts.DoneTask(ctx, next.ID, services.Precondition{}); err != nil {
+		t.Fatal(err)
+	}
+	last := open()
+	if last.ActiveAt != "2024-05-16" || last.Recurrence.Occurrence != 3 {
+		t.Errorf("third occurrence #%d on %s, want #3 on 2024-05-16", last.Recurrence.Occurrence, last.ActiveAt)
+	}
+
+	// COUNT=3 ends the series.
+	done, err = ts.DoneTask(ctx, last.ID, services.Precondition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Title != "standup" {
+		t.Errorf("last occurrence renamed to %q", done.Title)
+	}
+	if list, _ := ts.GetAllTasks(ctx, services.ListOptions{}); len(list.Tasks) != 0 {
+		t.Errorf("%d open tasks after the last occurrence, want none", len(list.Tasks))
+	}
+
+	patched, err = ts.PatchTask(ctx, last.ID, services.MergePatch, []byte(`{"recurrence":null}`), services.Precondition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Recurrence != nil {
+		t.Errorf("recurrence after patching it to null = %+v", *patched.Recurrence)
+	}
+}
+
+// An occurrence whose title has no room for its date is not marked done.
+func TestRecurrenceLongTitle(t *testing.T) {
+	ctx := context.Background()
+	ts := services.New(repositories.NewSyncMapTaskRepo())
+
+	title := strings.Repeat("x", models.MaxTitleLength-5)
+	task := &models.Task{Title: title, ActiveAt: "2024-05-02", Recurrence: &models.Recurrence{Rule: "FREQ=DAILY"}}
+	if err := task.Validate(); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Validate with a %d-character recurring title: got %v, want a validation error", len(title), err)
+	}
+
+	task.ID = "long"
+	if err := ts.PostTask(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.DoneTask(ctx, "long", services.Precondition{}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("DoneTask: got %v, want a validation error", err)
+	}
+	if stored, _ := ts.GetTask(ctx, "long"); stored.Status == "done" || stored.Title != title {
+		t.Errorf("after the failed DoneTask: %q in %s, want it unchanged", stored.Title, stored.Status)
+	}
+	if list, _ := ts.GetAllTasks(ctx, services.ListOptions{}); len(list.Tasks) != 1 {
+		t.Errorf("%d open tasks, want only the one not done", len(list.Tasks))
+	}
+}
+
+func TestRecurrenceNonWorkingDays(t *testing.T) {
+	ctx := context.Background()
+
+	// Wednesdays and Saturdays from Wednesday 2024-05-01; completing the
+	// first puts the next on Saturday 2024-05-04.
+	tests := []struct {
+		policy, activeAt string
+		occurrence       int
+	}{
+		{services.NonWorkingNext, "2024-05-06", 2},
+		{services.NonWorkingPrevious, "2024-05-03", 2},
+		{services.NonWorkingSkip, "2024-05-08", 3},
+		{services.NonWorkingKeep, "2024-05-04", 2},
+	}
+
+	for _, tt := range tests {
+		ts := services.New(repositories.NewSyncMapTaskRepo(), services.WithNonWorkingDays(tt.policy))
+
+		task := &models.Task{ID: "gym", Title: "gym", ActiveAt: "2024-05-01", Recurrence: &models.Recurrence{Rule: "FREQ=WEEKLY;BYDAY=WE,SA"}}
+		if err := ts.PostTask(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ts.DoneTask(ctx, "gym", services.Precondition{}); err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := ts.GetAllTasks(ctx, services.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Tasks) != 1 {
+			t.Fatalf("%s: %d open tasks, want 1", tt.policy, len(list.Tasks))
+		}
+		if next := list.Tasks[0]; next.ActiveAt != tt.activeAt || next.Recurrence.Occurrence != tt.occurrence {
+			t.Errorf("%s: next occurrence #%d on %s, want #%d on %s", tt.policy, next.Recurrence.Occurrence, next.ActiveAt, tt.occurrence, tt.activeAt)
+		}
+	}
+}
+
+func TestRecurrenceSkipNoWorkday(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewSyncMapTaskRepo()
+	skip := services.New(repo, services.WithNonWorkingDays(services.NonWorkingSkip))
+
+	weekends := &models.Task{ID: "chores", Title: "chores", ActiveAt: "2024-05-04", Recurrence: &models.Recurrence{Rule: "FREQ=WEEKLY;BYDAY=SA,SU"}}
+	if err := skip.PostTask(ctx, weekends); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("PostTask with a weekend-only rule: got %v, want a validation error", err)
+	}
+
+	// A series that ends before reaching a workday is fine.
+	once := &models.Task{ID: "once", Title: "once", ActiveAt: "2024-05-04", Recurrence: &models.Recurrence{Rule: "FREQ=WEEKLY;COUNT=2;BYDAY=SA,SU"}}
+	if err := skip.PostTask(ctx, once); err != nil {
+		t.Errorf("PostTask with a weekend-only rule that ends: %v", err)
+	}
+
+	// A rule stored under another policy is refused when done rather than
+	// ending its series.
+	keep := services.New(repo)
+	weekends.Recurrence = &models.Recurrence{Rule: "FREQ=WEEKLY;BYDAY=SA,SU"}
+	if err := keep.PostTask(ctx, weekends); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := skip.DoneTask(ctx, "chores", services.Precondition{}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("DoneTask of a weekend-only rule: got %v, want a validation error", err)
+	}
+	if stored, _ := skip.GetTask(ctx, "chores"); stored.Status == "done" {
+		t.Error("chores done after the failed DoneTask")
+	}
+}
@@ -0,0 +1,147 @@
+// Package workflow defines the statuses a task can be in and the
+// transitions allowed between them.
+package workflow
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	Active = "active"
+	Done   = "done"
+)
+
+// Definition describes a workflow, typically as read from the config.
+type Definition struct {
+	// Initial is the status of new and reopened tasks.
+	Initial string
+	// Done is the status PUT /api/tasks/{id}/done moves a task to.
+	Done string
+	// Closed lists the statuses of finished tasks, which listings leave
+	// out by default. Done is always closed.
+	Closed []string
+	// Transitions maps every status to the statuses it may move to.
+	Transitions map[string][]string
+}
+
+// Workflow is a set of statuses and the transitions allowed between them.
+// It is immutable and safe for concurrent use.
+type Workflow struct {
+	initial     string
+	done        string
+	closed      map[string]bool
+	transitions map[string]map[string]bool
+}
+
+// New checks d and builds its workflow. Every status named anywhere in d
+// must be a key of d.Transitions.
+func New(d Definition) (*Workflow, error) {
+	w := &Workflow{
+		initial:     d.Initial,
+		done:        d.Done,
+		closed:      map[string]bool{d.Done: true},
+		transitions: make(map[string]map[string]bool, len(d.Transitions)),
+	}
+
+	for from := range d.Transitions {
+		w.transitions[from] = make(map[string]bool)
+	}
+
+	for from, targets := range d.Transitions {
+		for _, to := range targets {
+			if !w.Valid(to) {
+				return nil, fmt.Errorf("transition from %q to unknown status %q", from, to)
+			}
+			w.transitions[from][to] = true
+		}
+	}
+
+	if !w.Valid(d.Initial) {
+		return nil, fmt.Errorf("unknown initial status %q", d.Initial)
+	}
+	if !w.Valid(d.Done) {
+		return nil, fmt.Errorf("unknown done status %q", d.Done)
+	}
+
+	for _, status := range d.Closed {
+		if !w.Valid(status) {
+			return nil, fmt.Errorf("unknown closed status %q", status)
+		}
+		w.closed[status] = true
+	}
+
+	if w.closed[d.Initial] {
+		return nil, fmt.Errorf("initial status %q cannot be closed", d.Initial)
+	}
+
+	return w, nil
+}
+
+// Default is the workflow used unless another is configured: new tasks are
+// active, active tasks get done and done tasks may be reopened.
+func Default() *Workflow {
+	w, _ := New(Definition{
+		Initial: Active,
+		Done:    Done,
+		Transitions: map[string][]string{
+			Active: {Done},
+			Done:   {Active},
+		},
+	})
+
+	return w
+}
+
+// Initial is the status of new tasks, and of reopened ones.
+func (w *Workflow) Initial() string {
+	return w.initial
+}
+
+// Done is the status of completed tasks.
+func (w *Workflow) Done() string {
+	return w.done
+}
+
+// Valid reports whether status belongs to the workflow.
+func (w *Workflow) Valid(status string) bool {
+	_, ok := w.transitions[status]
+	return ok
+}
+
+// Allows reports whether a task may move from one status to another.
+// Staying in the same status is always allowed.
+func (w *Workflow) Allows(from, to string) bool {
+	if from == to {
+		return w.Valid(from)
+	}
+
+	return w.transitions[from][to]
+}
+
+// Closed reports whether status is one of finished tasks.
+func (w *Workflow) Closed(status string) bool {
+	return w.closed[status]
+}
+
+// Statuses returns every status, sorted.
+func (w *Workflow) Statuses() []string {
+	return w.statuses(func(string) bool { return true })
+}
+
+// Open returns the statuses that are not closed, sorted.
+func (w *Workflow) Open() []string {
+	return w.statuses(func(status string) bool { return !w.closed[status] })
+}
+
+func (w *Workflow) statuses(keep func(status string) bool) []string {
+	var statuses []string
+	for status := range w.transitions {
+		if keep(status) {
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Strings(statuses)
+
+	return statuses
+}
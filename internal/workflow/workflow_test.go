@@ -0,0 +1,75 @@
+package workflow_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/canyouhearthemusic/todo-list/internal/workflow"
+)
+
+func TestAllows(t *testing.T) {
+	w := workflow.Default()
+
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"active", "done", true},
+		{"done", "active", true},
+		{"done", "done", true},
+		{"active", "archived", false},
+		{"archived", "archived", false},
+	}
+
+	for _, tt := range tests {
+		if got := w.Allows(tt.from, tt.to); got != tt.want {
+			t.Errorf("Allows(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	w, err := workflow.New(workflow.Definition{
+		Initial: "todo",
+		Done:    "done",
+		Closed:  []string{"cancelled"},
+		Transitions: map[string][]string{
+			"todo":        {"in_progress", "cancelled"},
+			"in_progress": {"blocked", "review", "done"},
+			"blocked":     {"in_progress"},
+			"review":      {"in_progress", "done"},
+			"done":        {"todo"},
+			"cancelled":   nil,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(w.Open(), ","); got != "blocked,in_progress,review,todo" {
+		t.Errorf("Open() = %s", got)
+	}
+	if w.Allows("todo", "done") || !w.Allows("review", "done") || w.Allows("cancelled", "todo") {
+		t.Error("Allows does not follow the transitions")
+	}
+}
+
+func TestNewRejectsUnknownStatuses(t *testing.T) {
+	tests := map[string]workflow.Definition{
+		"transition": {Initial: "active", Done: "active", Transitions: map[string][]string{"active": {"done"}}},
+		"initial":    {Initial: "todo", Done: "active", Transitions: map[string][]string{"active": nil}},
+		"done":       {Initial: "active", Done: "finished", Transitions: map[string][]string{"active": nil}},
+		"closed":     {Initial: "active", Done: "done", Closed: []string{"gone"}, Transitions: map[string][]string{"active": nil, "done": nil}},
+	}
+
+	for name, d := range tests {
+		if _, err := workflow.New(d); err == nil {
+			t.Errorf("New accepted an unknown %s status", name)
+		}
+	}
+
+	d := workflow.Definition{Initial: "done", Done: "done", Transitions: map[string][]string{"done": nil}}
+	if _, err := workflow.New(d); err == nil {
+		t.Error("New accepted a closed initial status")
+	}
+}